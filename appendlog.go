@@ -0,0 +1,317 @@
+package burrowdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const appendLogFileName = ".log" // Name of the file in the type dir of an append log type holding its records.
+
+// WithAppendLog specifies that entities of the named type should be appended
+// as JSON lines to a single file in the type's directory rather than each
+// being stored in its own file. This suits append-only, event-like types with
+// many small records. The type must be stored with the JSON codec.
+//
+// An index of where the latest record of each ID starts is kept in memory,
+// rebuilt from the log by NewDB, so GetByID doesn't scan the log. Puts and
+// deletes only ever append to the log, so it grows with every write.
+func WithAppendLog(typeName string) newDBOption {
+	return func(db *BurrowDB) error {
+		if db.appendLogs == nil {
+			db.appendLogs = map[string]bool{}
+		}
+		db.appendLogs[typeName] = true
+		return nil
+	}
+}
+
+// logLine is a single line of an append log.
+type logLine struct {
+	Key     string          `json:"key"`               // Key of the entity.
+	Time    int64           `json:"time"`              // Time of the write in nanoseconds since the Unix epoch.
+	Value   json.RawMessage `json:"value,omitempty"`   // Encoded entity, for a put.
+	Deleted bool            `json:"deleted,omitempty"` // Whether the entity was deleted.
+}
+
+// logRecord locates the latest line put for an entity in an append log.
+type logRecord struct {
+	offset int64     // byte offset of the line.
+	length int64     // length of the line.
+	time   time.Time // time of the write.
+}
+
+// appendLog is the in-memory index of an append log.
+type appendLog struct {
+	mu      sync.Mutex           // guards the index, which is updated by readers holding the type's read lock.
+	size    int64                // number of bytes of the log which have been indexed.
+	records map[string]logRecord // latest record of each entity keyed by key.
+	file    os.FileInfo          // log which has been indexed, or nil.
+}
+
+// isAppendLog reports whether the named type is stored in an append log.
+func (db *BurrowDB) isAppendLog(typeName string) bool {
+	return db.appendLogs[typeName]
+}
+
+// loadAppendLogs indexes the append log of every type stored in one.
+func (db *BurrowDB) loadAppendLogs() error {
+	for typeName := range db.appendLogs {
+		mu := db.typeLock(typeName)
+		mu.RLock()
+		err := db.withAppendLog(typeName, func(*appendLog) error { return nil })
+		mu.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withAppendLog calls fn with the index of the append log of the named type,
+// bringing it up to date with the log first. The lock of the type must be held,
+// and must be held for writing if fn appends to the log.
+func (db *BurrowDB) withAppendLog(typeName string, fn func(l *appendLog) error) error {
+	l := db.locks.forAppendLog(db.typeDirName(typeName))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := db.indexAppendLog(typeName, l)
+	if err != nil {
+		return err
+	}
+
+	return fn(l)
+}
+
+// indexAppendLog adds the lines written to the append log of the named type
+// since it was last indexed to its index. The whole log is indexed again if it
+// has shrunk or been replaced, such as by Reset or Restore in this or another
+// process. An incomplete final line, left by a crash, is ignored.
+func (db *BurrowDB) indexAppendLog(typeName string, l *appendLog) error {
+	release, err := db.acquireFile()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f, err := os.Open(db.appendLogPath(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		l.size, l.records, l.file = 0, map[string]logRecord{}, nil
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to open append log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat append log: %w", err)
+	}
+
+	if info.Size() < l.size || l.records == nil || (l.file != nil && !os.SameFile(l.file, info)) {
+		l.size, l.records = 0, map[string]logRecord{}
+	}
+	l.file = info
+
+	_, err = f.Seek(l.size, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("unable to seek append log: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		data, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read append log: %w", err)
+		}
+
+		var line logLine
+		err = json.Unmarshal(data, &line)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s append log at byte offset %d: %w", typeName, l.size, err)
+		}
+
+		if line.Deleted {
+			delete(l.records, line.Key)
+		} else {
+			l.records[line.Key] = logRecord{offset: l.size, length: int64(len(data)), time: time.Unix(0, line.Time)}
+		}
+		l.size += int64(len(data))
+	}
+}
+
+// appendLine appends the passed line to the append log of the named type and
+// updates its index. The lock of the type must be held for writing.
+func (db *BurrowDB) appendLine(typeName string, line logLine) error {
+	err := db.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!db.jsonOpts.DisableHTMLEscape)
+	err = enc.Encode(line)
+	if err != nil {
+		return fmt.Errorf("unable to marshal append log line: %w", err)
+	}
+
+	err = db.mkdirAll(db.typeDir(typeName))
+	if err != nil {
+		return fmt.Errorf("unable to create type dir: %w", err)
+	}
+
+	return db.withAppendLog(typeName, func(l *appendLog) error {
+		release, err := db.acquireFile()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		f, err := os.OpenFile(db.appendLogPath(typeName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, db.filePerm())
+		if err != nil {
+			return fmt.Errorf("unable to open append log: %w", err)
+		}
+		defer f.Close()
+
+		// Drop an incomplete final line so the new line starts on its own.
+		err = f.Truncate(l.size)
+		if err != nil {
+			return fmt.Errorf("unable to truncate append log: %w", err)
+		}
+
+		_, err = f.Write(buf.Bytes())
+		if err == nil && db.wal {
+			err = f.Sync()
+		}
+		if err != nil {
+			f.Truncate(l.size)
+			return fmt.Errorf("unable to write append log: %w", noSpace(err))
+		}
+
+		if line.Deleted {
+			delete(l.records, line.Key)
+		} else {
+			l.records[line.Key] = logRecord{offset: l.size, length: int64(buf.Len()), time: time.Unix(0, line.Time)}
+		}
+		l.size += int64(buf.Len())
+
+		db.markWritten(db.appendLogPath(typeName))
+
+		return nil
+	})
+}
+
+// putLogEntity appends the encoded entity of the named type with the passed
+// key to the type's append log.
+func (db *BurrowDB) putLogEntity(typeName, key string, data []byte) error {
+	err := db.appendLine(typeName, logLine{Key: key, Time: time.Now().UnixNano(), Value: data})
+	if err != nil {
+		return err
+	}
+
+	return db.logChange(typeName, key, OpPut)
+}
+
+// deleteLogEntity records the deletion of the entity of the named type with the
+// passed key in the type's append log. ErrNoSuchEntity is returned if there is
+// no such entity.
+func (db *BurrowDB) deleteLogEntity(typeName, key string) error {
+	_, ok, err := db.logRecord(typeName, key)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrNoSuchEntity
+	}
+
+	err = db.appendLine(typeName, logLine{Key: key, Time: time.Now().UnixNano(), Deleted: true})
+	if err != nil {
+		return err
+	}
+
+	return db.logChange(typeName, key, OpDelete)
+}
+
+// readLogEntity returns the encoded entity of the named type with the passed
+// key from the type's append log. ErrNoSuchEntity is returned if there is no
+// such entity.
+func (db *BurrowDB) readLogEntity(typeName, key string) ([]byte, error) {
+	var data []byte
+	err := db.withAppendLog(typeName, func(l *appendLog) error {
+		rec, ok := l.records[key]
+		if !ok {
+			return ErrNoSuchEntity
+		}
+
+		release, err := db.acquireFile()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		f, err := os.Open(db.appendLogPath(typeName))
+		if err != nil {
+			return fmt.Errorf("unable to open append log: %w", err)
+		}
+		defer f.Close()
+
+		data = make([]byte, rec.length)
+		_, err = f.ReadAt(data, rec.offset)
+		if err != nil {
+			return fmt.Errorf("unable to read append log: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var line logLine
+	err = json.Unmarshal(data, &line)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse append log line of %s %q: %w", typeName, key, err)
+	}
+
+	return line.Value, nil
+}
+
+// logRecord returns the record of the entity of the named type with the passed
+// key in the type's append log. False is returned if there is no such entity.
+func (db *BurrowDB) logRecord(typeName, key string) (logRecord, bool, error) {
+	var rec logRecord
+	var ok bool
+	err := db.withAppendLog(typeName, func(l *appendLog) error {
+		rec, ok = l.records[key]
+		return nil
+	})
+	return rec, ok, err
+}
+
+// logKeys returns the key of every entity in the append log of the named type
+// along with the time each was last written.
+func (db *BurrowDB) logKeys(typeName string) ([]string, map[string]time.Time, error) {
+	var keys []string
+	modTimes := map[string]time.Time{}
+	err := db.withAppendLog(typeName, func(l *appendLog) error {
+		for key, rec := range l.records {
+			keys = append(keys, key)
+			modTimes[key] = rec.time
+		}
+		return nil
+	})
+	return keys, modTimes, err
+}
+
+// appendLogPath returns the path of the append log of the named type.
+func (db *BurrowDB) appendLogPath(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), appendLogFileName)
+}
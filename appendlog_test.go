@@ -0,0 +1,108 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type appendItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestAppendLog(t *testing.T) {
+	dir := t.TempDir()
+	opts := []newDBOption{WithDir(dir), WithAppendLog("appendItem"), WithSortOrder(Insertion)}
+	db, err := NewDB(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 20 {
+		err = db.Put(appendItem{ID: i, Status: fmt.Sprint(i % 3)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.Put(appendItem{ID: 5, Status: "x"})
+	if err == nil {
+		err = db.Delete(appendItem{}, 7)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every record is in the one file, so no entity files are written.
+	entries, err := os.ReadDir(filepath.Join(dir, "appendItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("got %s in the type dir", entry.Name())
+		}
+	}
+
+	var item appendItem
+	err = db.GetByID(&item, 5)
+	if err != nil || item.Status != "x" {
+		t.Fatalf("got %+v, %v, want the latest record", item, err)
+	}
+
+	// A partial line left by a crash is ignored when the log is read again.
+	f, err := os.OpenFile(filepath.Join(dir, "appendItem", appendLogFileName), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"key":"9`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDB(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []appendItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 19 || items[18].ID != 5 {
+		t.Fatalf("got %d items ending with %+v, want 19 ending with the rewritten 5", len(items), items[len(items)-1])
+	}
+
+	err = db.GetByID(&item, 7)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a deleted entity, want %v", err, ErrNoSuchEntity)
+	}
+
+	err = db.GetByField(&items, "Status", "x")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got %+v, %v by index", items, err)
+	}
+
+	err = db.Put(appendItem{ID: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendLogCodec(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithAppendLog("appendItem"), WithCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(appendItem{ID: 1})
+	if err == nil {
+		t.Fatal("got no error appending with the gob codec")
+	}
+}
@@ -0,0 +1,120 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const appliedFileName = ".applied" // Name of the file in the db dir holding the number of applied change log entries.
+
+// Apply applies a mutation received from the Tail of another store, making this
+// store a follower of it. A put writes the entry's Data as the stored bytes of
+// the entity and a delete removes the entity if it is stored. The number of
+// entries applied is recorded in the db dir, and entries with a lower sequence
+// number than it are skipped, so the same entries can be applied more than
+// once, such as after resuming with Tail(ctx, n) where n is given by Applied.
+//
+// The follower must use the same codecs and layout as the store being followed.
+// The Go types of the entities aren't known, so indexes are updated for deletes
+// but not puts, and RebuildIndexes should be used before querying the indexed
+// fields of a follower. A put without Data, such as for an entity deleted before
+// Tail sent the entry, is skipped.
+func (db *BurrowDB) Apply(entry ChangeEntry) (err error) {
+	defer db.handleError("Apply", &err)
+
+	if entry.Type == "" || isReservedType(entry.Type) || !filepath.IsLocal(entry.Type) {
+		return fmt.Errorf("invalid type name %q", entry.Type)
+	}
+
+	if !isSafeKey(entry.ID) {
+		return fmt.Errorf("invalid ID %q", entry.ID)
+	}
+
+	// Take the type's lock first, as lockAll does.
+	mu := db.typeLock(entry.Type)
+	mu.Lock()
+	defer mu.Unlock()
+
+	db.locks.applied.Lock()
+	defer db.locks.applied.Unlock()
+
+	applied, err := db.readApplied()
+	if err != nil {
+		return err
+	}
+
+	if entry.Seq < applied {
+		return nil
+	}
+
+	switch entry.Op {
+	case OpPut:
+		if entry.Data != nil {
+			err = db.writeEntity(entry.Type, entry.ID, entry.Data)
+		}
+	case OpDelete:
+		err = db.deleteEntity(entry.Type, entry.ID)
+		if err == nil {
+			err = db.removeFromRawIndexes(entry.Type, entry.ID)
+		} else if errors.Is(err, ErrNoSuchEntity) {
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unknown change op %q", entry.Op)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to apply change %d: %w", entry.Seq, err)
+	}
+
+	return db.writeApplied(entry.Seq + 1)
+}
+
+// Applied returns the number of change log entries of another store which have
+// been applied by Apply, which is the sequence number to resume tailing it
+// from.
+func (db *BurrowDB) Applied() (_ int, err error) {
+	defer db.handleError("Applied", &err)
+
+	db.locks.applied.Lock()
+	defer db.locks.applied.Unlock()
+
+	return db.readApplied()
+}
+
+// readApplied returns the number of change log entries applied, or 0 if none
+// have been. The applied lock must be held.
+func (db *BurrowDB) readApplied() (int, error) {
+	data, err := os.ReadFile(db.appliedPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("unable to read applied changes: %w", err)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse applied changes: %w", err)
+	}
+
+	return n, nil
+}
+
+// writeApplied records the passed number of change log entries as applied. The
+// applied lock must be held.
+func (db *BurrowDB) writeApplied(n int) error {
+	err := db.writeFileAtomic(db.appliedPath(), []byte(strconv.Itoa(n)))
+	if err != nil {
+		return fmt.Errorf("unable to write applied changes: %w", err)
+	}
+	return nil
+}
+
+// appliedPath returns the path of the file holding the number of change log
+// entries applied.
+func (db *BurrowDB) appliedPath() string {
+	return fmt.Sprintf("%s/%s", db.dir, appliedFileName)
+}
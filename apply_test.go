@@ -0,0 +1,141 @@
+package burrowdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type applyItem struct {
+	ID   int
+	Name string
+}
+
+func TestApply(t *testing.T) {
+	leader, err := NewDB(WithDir(t.TempDir()), WithChangeLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replicate applies the leader's entries to the follower until it has
+	// applied n of them, resuming from where it left off.
+	replicate := func(n int) {
+		t.Helper()
+
+		applied, err := follower.Applied()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		entries, err := leader.Tail(ctx, applied)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for applied < n {
+			entry, ok := <-entries
+			if !ok {
+				t.Fatalf("got %d entries applied before the tail ended, want %d", applied, n)
+			}
+
+			err = follower.Apply(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			applied = entry.Seq + 1
+		}
+	}
+
+	for i := range 10 {
+		err = leader.Put(applyItem{ID: i + 1, Name: "first"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	replicate(10)
+
+	err = leader.Put(applyItem{ID: 1, Name: "updated"})
+	if err == nil {
+		err = leader.Delete(applyItem{}, 2)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicate(12)
+
+	var got applyItem
+	err = follower.GetByID(&got, 1)
+	if err != nil || got.Name != "updated" {
+		t.Fatalf("got %+v and %v from the follower, want the update", got, err)
+	}
+
+	want, err := leader.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := follower.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint != want {
+		t.Fatalf("got follower fingerprint %s, want the leader's %s", fingerprint, want)
+	}
+
+	// Entries which have already been applied are skipped.
+	entries, err := leader.ReadChangeLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = follower.Apply(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = follower.GetByID(&got, 1)
+	if err != nil || got.Name != "updated" {
+		t.Fatalf("got %+v and %v after reapplying the first put, want the update kept", got, err)
+	}
+
+	applied, err := follower.Applied()
+	if err != nil || applied != 12 {
+		t.Fatalf("got %d applied and %v, want 12", applied, err)
+	}
+}
+
+func TestApplyInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(filepath.Join(dir, "store")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the type dir so that a traversing ID would resolve.
+	err = db.Put(applyItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"", "../../escaped", `..\escaped`, ".hidden"} {
+		for _, op := range []ChangeOp{OpPut, OpDelete} {
+			err = db.Apply(ChangeEntry{Op: op, Type: "applyItem", ID: id, Data: []byte("{}")})
+			if err == nil {
+				t.Fatalf("got no error applying a %s of ID %q", op, id)
+			}
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "escaped"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v statting the escaped file, want it never written", err)
+	}
+}
@@ -0,0 +1,572 @@
+package burrowdb
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// backupOption is an option which can be passed to Backup to change what is
+// archived.
+type backupOption func(*backupConfig)
+
+// backupConfig holds the settings of a single Backup.
+type backupConfig struct {
+	canonicalOnly bool // whether to leave out data which can be regenerated.
+}
+
+// WithBackupCanonicalOnly specifies that the backup should contain only the
+// entities and what is needed to decode them, leaving out indexes, ID
+// sequences and the change log. This makes the archive smaller, and the
+// indexes can be regenerated with RebuildIndexes after it is restored.
+func WithBackupCanonicalOnly() backupOption {
+	return func(c *backupConfig) {
+		c.canonicalOnly = true
+	}
+}
+
+// Backup writes every file in the db dir to w as a tar archive which can be
+// loaded with Restore. Each file is read under the lock of its type, so the
+// archive is consistent for each entity but not necessarily across types which
+// are written during the backup. Temp files and locks are never archived.
+func (db *BurrowDB) Backup(w io.Writer, opts ...backupOption) (err error) {
+	defer db.handleError("Backup", &err)
+
+	var cfg backupConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(db.dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(db.dir, filename)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && skipBackupDir(entry.Name(), cfg) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || skipBackupFile(entry.Name(), cfg) {
+			return nil
+		}
+
+		data, err := db.readForBackup(rel, filename)
+		if errors.Is(err, os.ErrNotExist) {
+			// The file was deleted since the dir was read.
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		err = tw.WriteHeader(&tar.Header{
+			Name: rel,
+			Mode: 0666,
+			Size: int64(len(data)),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to write header of %s: %w", rel, err)
+		}
+
+		_, err = tw.Write(data)
+		if err != nil {
+			return fmt.Errorf("unable to write %s: %w", rel, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to back up db: %w", err)
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return fmt.Errorf("unable to finish backup: %w", err)
+	}
+
+	return nil
+}
+
+// importOption is an option which can be passed to ImportAll or Restore to
+// change how entities are loaded.
+type importOption func(*importConfig)
+
+// importConfig holds the settings of a single ImportAll or Restore.
+type importConfig struct {
+	validate  bool               // whether to check the archive before writing any of it.
+	batchSize int                // number of entities in each batch, or 0 for one batch.
+	progress  func(ImportReport) // called as each batch is completed, or nil.
+
+	committed int // number of entities handled when the last batch was completed.
+}
+
+// WithValidateBeforeRestore specifies that the archive should be extracted into
+// a staging dir and every entity in it checked to decode, as by Verify, before
+// anything is written to the db dir. A truncated or corrupt archive then leaves
+// the store untouched. The staged files are then merged into the db dir, and
+// those already written are rolled back if one can't be. It has no effect on
+// ImportAll.
+func WithValidateBeforeRestore() importOption {
+	return func(c *importConfig) {
+		c.validate = true
+	}
+}
+
+// WithImportBatchSize specifies that ImportAll and Restore should complete the
+// entities they load in batches of n. Once each batch has been written, the
+// files written are synced, if the db syncs written files, and the function
+// given to WithImportProgress is called, so that a failed import only loses the
+// batch it failed in and can be resumed, such as by loading the same input
+// again with ImportSkip. Without it the whole input is a single batch.
+func WithImportBatchSize(n int) importOption {
+	return func(c *importConfig) {
+		c.batchSize = n
+	}
+}
+
+// WithImportProgress specifies a function which ImportAll and Restore call with
+// the running totals each time a batch set by WithImportBatchSize is
+// completed, including the final, possibly smaller, batch.
+func WithImportProgress(fn func(ImportReport)) importOption {
+	return func(c *importConfig) {
+		c.progress = fn
+	}
+}
+
+// newImportConfig returns the settings given by the passed options.
+func newImportConfig(opts []importOption) (*importConfig, error) {
+	cfg := &importConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.batchSize < 0 {
+		return nil, fmt.Errorf("invalid import batch size %d", cfg.batchSize)
+	}
+
+	return cfg, nil
+}
+
+// handled completes the current batch if it holds the batch size of entities,
+// given the totals of the entities handled so far.
+func (c *importConfig) handled(db *BurrowDB, report ImportReport) error {
+	if c.batchSize == 0 || report.Imported+report.Skipped-c.committed < c.batchSize {
+		return nil
+	}
+
+	return c.complete(db, report)
+}
+
+// finish completes the final batch, if it holds any entities or is the only
+// batch, given the totals of the entities handled.
+func (c *importConfig) finish(db *BurrowDB, report ImportReport) error {
+	if c.batchSize > 0 && c.committed > 0 && report.Imported+report.Skipped == c.committed {
+		return nil
+	}
+
+	return c.complete(db, report)
+}
+
+// complete syncs the files written by the current batch and reports progress.
+func (c *importConfig) complete(db *BurrowDB, report ImportReport) error {
+	err := db.syncWritten()
+	if err != nil {
+		return fmt.Errorf("unable to sync imported batch: %w", err)
+	}
+
+	c.committed = report.Imported + report.Skipped
+	if c.progress != nil {
+		c.progress(report)
+	}
+
+	return nil
+}
+
+// Restore writes every file in the tar archive read from r, as written by
+// Backup, into the db dir and returns the number of entity files written and
+// skipped. An entity file which already exists is handled as mode decides,
+// while other files, such as indexes, codecs and append logs, overwrite any
+// existing file with the same name. Each file is written atomically under the
+// lock of its type. Indexes aren't regenerated, so RebuildIndexes should be
+// called for each indexed type if the backup was made with
+// WithBackupCanonicalOnly or entities were skipped.
+//
+// Files are written as they are read unless WithValidateBeforeRestore is
+// passed, so a corrupt archive, or with ImportFail one holding an entity which
+// is already stored, may otherwise be partly restored. WithImportBatchSize
+// completes the entities in batches, as for ImportAll.
+func (db *BurrowDB) Restore(r io.Reader, mode ImportMode, opts ...importOption) (_ ImportReport, err error) {
+	defer db.handleError("Restore", &err)
+
+	err = db.checkWritable()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	cfg, err := newImportConfig(opts)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	// Restored files aren't accounted for as they're written, so the size of
+	// the store is measured again.
+	defer db.forgetStoreSize()
+
+	if cfg.validate {
+		return db.restoreValidated(r, mode, cfg)
+	}
+
+	var report ImportReport
+	err = readBackup(r, func(rel string, data []byte) error {
+		restored, err := db.restoreFile(rel, data, mode)
+		if err != nil {
+			return err
+		}
+
+		report.count(rel, restored)
+		return cfg.handled(db, report)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, cfg.finish(db, report)
+}
+
+// readBackup calls fn with the path, relative to the db dir, and contents of
+// every file in the tar archive read from r.
+func readBackup(r io.Reader, fn func(rel string, data []byte) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read backup: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("backup contains the invalid path %q", hdr.Name)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("unable to read %s from backup: %w", hdr.Name, err)
+		}
+
+		err = fn(hdr.Name, data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// restoreValidated restores the archive read from r, as described by
+// WithValidateBeforeRestore.
+func (db *BurrowDB) restoreValidated(r io.Reader, mode ImportMode, cfg *importConfig) (ImportReport, error) {
+	var report ImportReport
+	staging, err := os.MkdirTemp(db.tempDir, "burrowdb-restore-")
+	if err != nil {
+		return report, fmt.Errorf("unable to create staging dir: %w", noSpace(err))
+	}
+	defer os.RemoveAll(staging)
+
+	var rels []string
+	err = readBackup(r, func(rel string, data []byte) error {
+		filename := filepath.Join(staging, filepath.FromSlash(rel))
+		err := os.MkdirAll(filepath.Dir(filename), 0777)
+		if err == nil {
+			err = os.WriteFile(filename, data, 0666)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to stage %s: %w", rel, noSpace(err))
+		}
+
+		rels = append(rels, rel)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	err = db.validateStaged(staging)
+	if err != nil {
+		return report, err
+	}
+
+	// Check for stored entities before writing anything, so that the store is
+	// left untouched if there are any.
+	if mode == ImportFail {
+		for _, rel := range rels {
+			if isEntityFile(rel) && fileExists(filepath.Join(db.dir, filepath.FromSlash(rel))) {
+				return report, fmt.Errorf("%w: %s", ErrEntityExists, rel)
+			}
+		}
+	}
+
+	// Keep what each file held so that it can be put back if a later file
+	// can't be restored.
+	type undo struct {
+		rel     string
+		data    []byte
+		existed bool
+	}
+	var undos []undo
+	for _, rel := range rels {
+		data, err := os.ReadFile(filepath.Join(staging, filepath.FromSlash(rel)))
+		if err != nil {
+			return report, fmt.Errorf("unable to read staged %s: %w", rel, err)
+		}
+
+		u := undo{rel: rel}
+		u.data, err = os.ReadFile(filepath.Join(db.dir, filepath.FromSlash(rel)))
+		if err == nil {
+			u.existed = true
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return report, fmt.Errorf("unable to read %s: %w", rel, err)
+		}
+
+		restored, err := db.restoreFile(rel, data, mode)
+		if err != nil {
+			errs := []error{err}
+			for _, u := range slices.Backward(undos) {
+				if u.existed {
+					_, err = db.restoreFile(u.rel, u.data, ImportOverwrite)
+				} else {
+					err = db.unrestoreFile(u.rel)
+				}
+				errs = append(errs, err)
+			}
+			return ImportReport{}, errors.Join(errs...)
+		}
+
+		report.count(rel, restored)
+		if restored {
+			undos = append(undos, u)
+		}
+
+		err = cfg.handled(db, report)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, cfg.finish(db, report)
+}
+
+// validateStaged checks that every entity in the passed staging dir can be
+// decoded, opening it with the same settings as this db.
+func (db *BurrowDB) validateStaged(staging string) error {
+	staged, err := db.openDetached(staging)
+	if err != nil {
+		return fmt.Errorf("unable to open staging dir: %w", err)
+	}
+	defer staged.Close()
+
+	report, err := staged.Verify()
+	if err != nil {
+		return fmt.Errorf("unable to validate backup: %w", err)
+	}
+
+	if len(report.Corrupt) > 0 {
+		issue := report.Corrupt[0]
+		return fmt.Errorf("backup contains %d corrupt entities, such as %s %q: %s", len(report.Corrupt), issue.Type, issue.Key, issue.Detail)
+	}
+
+	return nil
+}
+
+// unrestoreFile removes the file at the passed path, relative to the db dir,
+// which was written by restoreFile, holding the lock which guards it.
+func (db *BurrowDB) unrestoreFile(rel string) error {
+	mu := db.fileLock(rel, true)
+	mu.Lock()
+	defer mu.Unlock()
+
+	err := os.Remove(filepath.Join(db.dir, filepath.FromSlash(rel)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to roll back %s: %w", rel, err)
+	}
+
+	return nil
+}
+
+// readForBackup returns the contents of the file at the passed path, relative
+// to the db dir, holding the lock which guards it.
+func (db *BurrowDB) readForBackup(rel, filename string) ([]byte, error) {
+	mu := db.fileLock(rel, false)
+	mu.Lock()
+	defer mu.Unlock()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", rel, err)
+	}
+
+	return data, nil
+}
+
+// restoreFile atomically writes data to the file at the passed path, relative
+// to the db dir, holding the lock which guards it. An entity file which
+// already exists is handled as mode decides, reporting false if it is skipped.
+func (db *BurrowDB) restoreFile(rel string, data []byte, mode ImportMode) (bool, error) {
+	mu := db.fileLock(rel, true)
+	mu.Lock()
+	defer mu.Unlock()
+
+	filename := filepath.Join(db.dir, filepath.FromSlash(rel))
+	if mode != ImportOverwrite && isEntityFile(rel) && fileExists(filename) {
+		if mode == ImportFail {
+			return false, fmt.Errorf("%w: %s", ErrEntityExists, rel)
+		}
+		return false, nil
+	}
+
+	err := db.mkdirAll(filepath.Dir(filename))
+	if err != nil {
+		return false, fmt.Errorf("unable to create dir for %s: %w", rel, err)
+	}
+
+	err = db.writeFileAtomic(filename, data)
+	if err != nil {
+		return false, fmt.Errorf("unable to restore %s: %w", rel, err)
+	}
+
+	if isEntityFile(rel) {
+		db.dropHeld(db.fileTypeDir(rel), strings.TrimSuffix(path.Base(rel), db.fileExt))
+	}
+
+	return true, nil
+}
+
+// count adds the file at the passed path, relative to the db dir, to the
+// report if it is an entity file, as imported if restored and otherwise as
+// skipped.
+func (r *ImportReport) count(rel string, restored bool) {
+	switch {
+	case !isEntityFile(rel):
+	case restored:
+		r.Imported++
+	default:
+		r.Skipped++
+	}
+}
+
+// isEntityFile reports whether the file at the passed slash separated path,
+// relative to the db dir, holds an entity rather than data kept alongside
+// entities, such as an index, or shared between types.
+func isEntityFile(rel string) bool {
+	parts := strings.Split(rel, "/")
+	if len(parts) < 2 {
+		return false
+	}
+
+	for _, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// fileExists reports whether a file exists at the passed path.
+func fileExists(filename string) bool {
+	_, err := os.Lstat(filename)
+	return err == nil
+}
+
+// fileLock returns the lock guarding the file at the passed slash separated
+// path relative to the db dir, for reading or writing. Files anywhere below a
+// type dir are guarded by the type's lock.
+func (db *BurrowDB) fileLock(rel string, write bool) sync.Locker {
+	dir := db.fileTypeDir(rel)
+	switch {
+	case dir == blobDirName:
+		return &db.locks.blobs
+	case dir == ".":
+		switch rel {
+		case changeLogFileName:
+			return &db.locks.changeLog
+		case appliedFileName:
+			return &db.locks.applied
+		}
+		return &sync.Mutex{}
+	}
+
+	mu := db.typeLock(dir)
+	if write {
+		return mu
+	}
+	return mu.RLocker()
+}
+
+// fileTypeDir returns the type dir, relative to the db dir, holding the file at
+// the passed slash separated path relative to the db dir, or the dir of the
+// file if it isn't below a type dir. Files in the hidden dirs of a type, such
+// as its indexes, history and metadata, and entities in the partitions of a
+// partitioned type or the sub-directories of the db's layout belong to the
+// type dir above them. Type names may themselves contain slashes.
+func (db *BurrowDB) fileTypeDir(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts)-1; i++ {
+		if strings.HasPrefix(parts[i], ".") {
+			return path.Join(parts[:i]...)
+		}
+	}
+
+	dir := path.Dir(rel)
+	if parent := path.Dir(dir); parent != "." && db.isPartitionDir(fmt.Sprintf("%s/%s", db.dir, parent)) {
+		return parent
+	}
+
+	if !db.isFlat() {
+		// Find the type dir above the sub-directories of the layout by its
+		// codec marker.
+		for d := dir; d != "."; d = path.Dir(d) {
+			if _, err := os.Stat(db.codecPath(d)); err == nil {
+				return d
+			}
+		}
+	}
+
+	return dir
+}
+
+// skipBackupDir reports whether the dir with the passed name should be left out
+// of a backup.
+func skipBackupDir(name string, cfg backupConfig) bool {
+	return name == pingTypeName || name == walDirName || (cfg.canonicalOnly && name == indexDirName)
+}
+
+// skipBackupFile reports whether the file with the passed name should be left
+// out of a backup.
+func skipBackupFile(name string, cfg backupConfig) bool {
+	switch {
+	case name == lockFileName, strings.HasPrefix(name, tempFilePrefix):
+		return true
+	case cfg.canonicalOnly:
+		return name == seqFileName || name == changeLogFileName || name == appliedFileName
+	}
+	return false
+}
@@ -0,0 +1,364 @@
+package burrowdb
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type backupItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+// backupNames returns the names of the files in the tar archive.
+func backupNames(t *testing.T, archive []byte) []string {
+	t.Helper()
+
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(backupItem{ID: 1, Status: "a"}, backupItem{ID: 2, Status: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = db.Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = restored.Restore(bytes.NewReader(buf.Bytes()), ImportOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The indexes are restored along with the entities.
+	var items []backupItem
+	err = restored.GetByField(&items, "Status", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("got %+v, want entity 2", items)
+	}
+}
+
+func TestBackupCanonicalOnly(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithChangeLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(backupItem{ID: 1, Status: "a"}, backupItem{ID: 2, Status: "b"})
+	if err == nil {
+		_, _, err = db.Insert(backupItem{})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = db.Backup(&buf, WithBackupCanonicalOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := backupNames(t, buf.Bytes())
+	for _, name := range names {
+		for _, derived := range []string{indexDirName, seqFileName, changeLogFileName} {
+			if strings.Contains(name, derived) {
+				t.Errorf("got %s in the archive", name)
+			}
+		}
+	}
+
+	restored, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = restored.Restore(bytes.NewReader(buf.Bytes()), ImportOverwrite)
+	if err == nil {
+		err = restored.RebuildIndexes(backupItem{})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []backupItem
+	err = restored.GetByField(&items, "Status", "b")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got %+v, %v after rebuilding the indexes", items, err)
+	}
+}
+
+func TestValidateBeforeRestore(t *testing.T) {
+	src, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = src.PutAll(backupItem{ID: 1, Status: "restored"}, backupItem{ID: 2, Status: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = src.Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	// Archive the same files with entity 2 corrupted.
+	var corrupt bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(valid))
+	tw := tar.NewWriter(&corrupt)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == "backupItem/2" {
+			data = []byte(`{"ID":2,"Status":`)
+			hdr.Size = int64(len(data))
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = live.Put(backupItem{ID: 1, Status: "live"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, archive := range map[string][]byte{
+		"corrupt":   corrupt.Bytes(),
+		"truncated": valid[:bytes.Index(valid, []byte(`"restored"`))+5],
+	} {
+		_, err = live.Restore(bytes.NewReader(archive), ImportOverwrite, WithValidateBeforeRestore())
+		if err == nil {
+			t.Fatalf("%s: got nil error", name)
+		}
+
+		var items []backupItem
+		err = live.GetAll(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].Status != "live" {
+			t.Fatalf("%s: got %+v, want the live store untouched", name, items)
+		}
+	}
+
+	_, err = live.Restore(bytes.NewReader(valid), ImportOverwrite, WithValidateBeforeRestore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []backupItem
+	err = live.GetByField(&items, "Status", "restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %+v, want both entities restored", items)
+	}
+}
+
+func TestRestoreModes(t *testing.T) {
+	source, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = source.PutAll(backupItem{ID: 1, Status: "restored"}, backupItem{ID: 2, Status: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = source.Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode     ImportMode
+		validate bool
+		want     map[int]string
+		report   ImportReport
+		wantErr  error
+	}{
+		{ImportOverwrite, false, map[int]string{1: "restored", 2: "restored"}, ImportReport{Imported: 2}, nil},
+		{ImportSkip, false, map[int]string{1: "restored", 2: "stored"}, ImportReport{Imported: 1, Skipped: 1}, nil},
+		{ImportSkip, true, map[int]string{1: "restored", 2: "stored"}, ImportReport{Imported: 1, Skipped: 1}, nil},
+		{ImportFail, true, map[int]string{2: "stored"}, ImportReport{}, ErrEntityExists},
+	}
+	for _, test := range tests {
+		db, err := NewDB(WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Put(backupItem{ID: 2, Status: "stored"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var opts []importOption
+		if test.validate {
+			opts = append(opts, WithValidateBeforeRestore())
+		}
+
+		report, err := db.Restore(bytes.NewReader(buf.Bytes()), test.mode, opts...)
+		if !errors.Is(err, test.wantErr) {
+			t.Fatalf("got %v restoring with mode %d, want %v", err, test.mode, test.wantErr)
+		}
+		if report != test.report {
+			t.Fatalf("got %+v restoring with mode %d, want %+v", report, test.mode, test.report)
+		}
+
+		var items []backupItem
+		err = db.GetAll(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := map[int]string{}
+		for _, item := range items {
+			got[item.ID] = item.Status
+		}
+		if !maps.Equal(got, test.want) {
+			t.Fatalf("got %v restoring with mode %d, want %v", got, test.mode, test.want)
+		}
+	}
+}
+
+func TestRestoreBatches(t *testing.T) {
+	src, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 6 {
+		err = src.Put(backupItem{ID: i + 1, Status: "restored"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = src.Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, validate := range []bool{false, true} {
+		db, err := NewDB(WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var progress []ImportReport
+		opts := []importOption{WithImportBatchSize(4), WithImportProgress(func(r ImportReport) {
+			progress = append(progress, r)
+		})}
+		if validate {
+			opts = append(opts, WithValidateBeforeRestore())
+		}
+
+		report, err := db.Restore(bytes.NewReader(buf.Bytes()), ImportOverwrite, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ImportReport{{Imported: 4}, {Imported: 6}}
+		if report.Imported != 6 || !slices.Equal(progress, want) {
+			t.Fatalf("got %+v with progress %v validating %t, want 6 imported with %v", report, progress, validate, want)
+		}
+	}
+}
+
+func TestFileTypeDir(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithTimePartition("events/Log", "At"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel, want string
+	}{
+		{"backupItem/1", "backupItem"},
+		{"backupItem/.codec", "backupItem"},
+		{"backupItem/.index/Status", "backupItem"},
+		{"backupItem/.history/1/2", "backupItem"},
+		{"backupItem/.meta/1", "backupItem"},
+		{"pkg/backupItem/1", "pkg/backupItem"},
+		{"pkg/backupItem/.codecs/1", "pkg/backupItem"},
+		{"events/Log/2024-01-15/1", "events/Log"},
+		{".blobs/abc", ".blobs"},
+		{".changelog", "."},
+	}
+	for _, test := range tests {
+		if got := db.fileTypeDir(test.rel); got != test.want {
+			t.Errorf("got %q for %s, want %q", got, test.rel, test.want)
+		}
+	}
+
+	// So writes to partitions and hidden dirs are serialised with the type's
+	// writes.
+	if db.fileLock("events/Log/2024-01-15/1", true) != db.typeLock("events/Log") {
+		t.Fatal("got a partition locked apart from its type")
+	}
+}
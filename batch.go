@@ -0,0 +1,459 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+)
+
+// PutAll puts each of the passed values into the db as Put would. Use PutBatch
+// to put them atomically.
+//
+// A failure to put one value doesn't prevent the others from being put. The
+// returned error joins the error of every value which failed, each wrapped with
+// the index of the value.
+func (db *BurrowDB) PutAll(vs ...any) error {
+	var errs []error
+	for i, v := range vs {
+		err := db.Put(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("element %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetMany gets the entity with each of the passed IDs and appends them, in the
+// order of ids, to the slice pointed to by dst.
+//
+// A failure to get one entity doesn't prevent the others from being got. The
+// returned error joins the error of every ID which failed, each wrapped with
+// the ID, so a missing entity can be detected with errors.Is(err,
+// ErrNoSuchEntity).
+func (db *BurrowDB) GetMany(dst any, ids []any) (err error) {
+	defer db.handleError("GetMany", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	typeName := db.typeName(elemType)
+	slice := reflect.ValueOf(dst).Elem()
+	var errs []error
+	for _, id := range ids {
+		v := reflect.New(elemType)
+		err := db.get(v.Interface(), typeName, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("id %v: %w", id, err))
+			continue
+		}
+		slice.Set(reflect.Append(slice, v.Elem()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// batchWrite is an entity file staged by PutBatch.
+type batchWrite struct {
+	enc      encoded
+	filename string // path of the entity file.
+	temp     string // temp file holding the new contents.
+	data     []byte // new contents of the entity file.
+	old      []byte // previous contents of the entity file.
+	existed  bool   // whether the entity file existed before the batch.
+	applied  bool   // whether the temp file has been renamed into place.
+}
+
+// PutBatch puts every passed struct value into the db, possibly of different
+// types, such that either all of their entity files are replaced or none are.
+// The new contents of every entity are written to temp files before any is
+// renamed into place, and renames already made are rolled back if one fails.
+// With WithWAL, a crash during the renames is completed by NewDB.
+//
+// As with Put, indexes are updated after the entities are written. If a value
+// is given the ID of an earlier value in the batch, the later value is kept.
+// Types stored with WithAppendLog can't be put in a batch.
+func (db *BurrowDB) PutBatch(vs ...any) (err error) {
+	defer db.handleError("PutBatch", &err)
+
+	typeNames := make([]string, 0, len(vs))
+	for i, v := range vs {
+		if reflect.TypeOf(v) == nil || reflect.TypeOf(v).Kind() != reflect.Struct {
+			return fmt.Errorf("element %d: %w", i, ErrInvalidValueType)
+		}
+		typeName := db.typeName(reflect.TypeOf(v))
+		if !slices.Contains(typeNames, typeName) {
+			typeNames = append(typeNames, typeName)
+		}
+	}
+
+	// Lock in a fixed order so concurrent batches can't deadlock.
+	slices.Sort(typeNames)
+	for _, typeName := range typeNames {
+		mu := db.typeLock(typeName)
+		mu.Lock()
+		defer mu.Unlock()
+	}
+
+	writes, indexes, err := db.stageBatch(vs)
+	if err != nil {
+		return err
+	}
+
+	err = db.reserveBatchEntities(writes)
+	if err != nil {
+		db.discardBatch(writes)
+		return err
+	}
+
+	err = db.reserveBatch(writes)
+	if err != nil {
+		db.forgetStoreSize()
+		db.discardBatch(writes)
+		return err
+	}
+
+	for _, w := range writes {
+		err = db.archiveVersion(w.enc.typeName, w.enc.key)
+		if err != nil {
+			db.forgetStoreSize()
+			db.discardBatch(writes)
+			return err
+		}
+	}
+
+	err = db.commitBatch(writes)
+	if err != nil {
+		db.forgetStoreSize()
+		return err
+	}
+
+	var errs []error
+	for _, w := range writes {
+		if db.contentAddressing {
+			errs = append(errs, db.releaseBlob(w.old))
+		}
+		errs = append(errs,
+			db.recordEntityCodec(w.enc.typeName, w.enc.key, nil),
+			db.logChange(w.enc.typeName, w.enc.key, OpPut),
+		)
+	}
+
+	for _, v := range vs {
+		_type := reflect.TypeOf(v)
+		if idx, ok := indexes[_type]; ok {
+			errs = append(errs, db.writeIndexes(_type, idx), db.applyRetention(_type))
+			delete(indexes, _type)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stageBatch encodes every passed value and writes its new contents to a temp
+// file, returning the staged writes and the updated indexes of each type. Every
+// temp file is removed if staging fails. The locks of the values' types must be
+// held.
+func (db *BurrowDB) stageBatch(vs []any) ([]*batchWrite, map[reflect.Type]map[string]index, error) {
+	var writes []*batchWrite
+	indexes := map[reflect.Type]map[string]index{}
+	for i, v := range vs {
+		w, err := db.stageWrite(v, indexes)
+		if err != nil {
+			db.discardBatch(writes)
+			return nil, nil, fmt.Errorf("element %d: %w", i, err)
+		}
+
+		// A later value with the same key replaces the earlier one.
+		j := slices.IndexFunc(writes, func(o *batchWrite) bool { return o.filename == w.filename })
+		if j >= 0 {
+			db.discardBatch(writes[j : j+1])
+			w.old, w.existed = writes[j].old, writes[j].existed
+			writes = slices.Delete(writes, j, j+1)
+		}
+		writes = append(writes, w)
+	}
+
+	return writes, indexes, nil
+}
+
+// stageWrite fills in and encodes the passed value and writes its new contents
+// to a temp file, updating the cached indexes of its type.
+func (db *BurrowDB) stageWrite(v any, indexes map[reflect.Type]map[string]index) (*batchWrite, error) {
+	v, err := db.applyDefaulter(v)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := db.encode(v, putConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	if db.isAppendLog(enc.typeName) {
+		return nil, fmt.Errorf("%s is stored in an append log which can't be written in a batch", enc.typeName)
+	}
+
+	if db.isPartitioned(enc.typeName) {
+		return nil, fmt.Errorf("%s is partitioned by time and can't be written in a batch", enc.typeName)
+	}
+
+	err = db.checkOverwriteWindow(enc.typeName, enc.key)
+	if err == nil {
+		err = db.checkSymlinks(db.entityPath(enc.typeName, enc.key))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_type := reflect.TypeOf(v)
+	idx, ok := indexes[_type]
+	if !ok {
+		idx, err = db.readIndexes(_type)
+		if err != nil {
+			return nil, err
+		}
+		indexes[_type] = idx
+	}
+
+	err = db.updateIndexes(_type, idx, enc.key, reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.recordCodec(enc.typeName, enc.codec)
+	if err == nil {
+		err = db.recordTypeSchema(_type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := &batchWrite{enc: enc, filename: db.entityPath(enc.typeName, enc.key), data: enc.data}
+	err = db.mkdirAll(filepath.Dir(w.filename))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create type dir: %w", err)
+	}
+
+	db.dropHeld(enc.typeName, enc.key)
+	err = db.recordKeyName(enc.typeName, enc.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if isDir(w.filename) {
+		return nil, fmt.Errorf("%w: %q conflicts with the entity file and must be removed", ErrPathIsDirectory, w.filename)
+	}
+
+	w.old, err = os.ReadFile(w.filename)
+	if err == nil {
+		w.existed = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read entity: %w", err)
+	}
+
+	if db.contentAddressing {
+		w.data, err = db.storeBlob(w.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.data = db.addHeader(w.data)
+	w.temp, err = db.writeTemp(w.filename, w.data)
+	if err != nil {
+		if db.contentAddressing {
+			db.releaseBlob(w.data)
+		}
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// commitBatch renames every staged temp file into place. If a rename fails, the
+// files already renamed are given back their previous contents and every
+// remaining temp file is removed.
+func (db *BurrowDB) commitBatch(writes []*batchWrite) error {
+	var intents []string
+	if db.wal {
+		// Every intent is logged before any rename so a crash part way through
+		// the batch is completed rather than left half applied.
+		for _, w := range writes {
+			intent, err := db.logIntent(OpPut, w.filename, w.data)
+			if err != nil {
+				db.clearIntents(intents)
+				db.discardBatch(writes)
+				return err
+			}
+			intents = append(intents, intent)
+		}
+	}
+
+	for _, w := range writes {
+		err := os.Rename(w.temp, w.filename)
+		if err != nil {
+			err = fmt.Errorf("unable to rename temp file: %w", noSpace(err))
+			db.rollbackBatch(writes)
+			db.clearIntents(intents)
+			return err
+		}
+		w.applied = true
+		db.markRenamed(w.filename)
+	}
+
+	err := db.clearIntents(intents)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range writes {
+		db.wroteRaw(w.enc.typeName, w.enc.key, w.data)
+	}
+
+	return db.countWrites(len(writes))
+}
+
+// rollbackBatch gives every applied write of a batch back its previous
+// contents, in reverse order, and discards the rest.
+func (db *BurrowDB) rollbackBatch(writes []*batchWrite) error {
+	var errs []error
+	for _, w := range slices.Backward(writes) {
+		if !w.applied {
+			continue
+		}
+
+		if w.existed {
+			errs = append(errs, db.replaceFile(w.filename, w.old))
+		} else {
+			errs = append(errs, os.Remove(w.filename))
+		}
+		w.applied = false
+	}
+
+	errs = append(errs, db.discardBatch(writes))
+	return errors.Join(errs...)
+}
+
+// discardBatch removes the temp files of the passed writes which haven't been
+// applied, releasing the blobs they refer to.
+func (db *BurrowDB) discardBatch(writes []*batchWrite) error {
+	var errs []error
+	for _, w := range writes {
+		if w.applied {
+			continue
+		}
+
+		err := os.Remove(w.temp)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		if db.contentAddressing {
+			errs = append(errs, db.releaseBlob(w.data))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// clearIntents removes every passed entry from the write-ahead log.
+func (db *BurrowDB) clearIntents(intents []string) error {
+	var errs []error
+	for _, intent := range intents {
+		errs = append(errs, db.clearIntent(intent))
+	}
+	return errors.Join(errs...)
+}
+
+// GetManyMap gets the entity with each of the passed IDs and inserts each one
+// into the map pointed to by dst keyed by its ID. IDs without an entity are
+// left out of the map. A nil map will be allocated.
+//
+// The dst must be a pointer to a map whose values are structs and whose key
+// kind matches the kind of each ID, although integer IDs may have any integer
+// kind. A failure to get one entity doesn't prevent the others from being got,
+// and the returned error joins the error of every ID which failed, each wrapped
+// with the ID.
+func (db *BurrowDB) GetManyMap(dst any, ids []any) (err error) {
+	defer db.handleError("GetManyMap", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	mapType := _type.Elem()
+	if mapType.Kind() != reflect.Map {
+		return ErrInvalidDstType
+	}
+
+	elemType := mapType.Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	mapKeys := make([]reflect.Value, len(ids))
+	for i, id := range ids {
+		// Integer IDs of any size are accepted, but strings aren't converted to
+		// and from numbers. Pointer IDs are keyed by the value they point to.
+		id, err := derefID(id)
+		if err != nil {
+			return fmt.Errorf("id %v: %w", ids[i], err)
+		}
+		v := reflect.ValueOf(id)
+		keyKind := mapType.Key().Kind()
+		if !v.IsValid() || !v.Type().ConvertibleTo(mapType.Key()) ||
+			(v.Kind() != keyKind && !(isIntKind(v.Kind()) && isIntKind(keyKind))) {
+			return fmt.Errorf("%w: id %v", ErrKeyTypeMismatch, id)
+		}
+		mapKeys[i] = v.Convert(mapType.Key())
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err != nil {
+		return err
+	}
+
+	m := reflect.ValueOf(dst).Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(mapType))
+	}
+
+	var errs []error
+	for i, id := range ids {
+		key, err := db.idKey(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("id %v: %w", id, err))
+			continue
+		}
+
+		v, err := db.load(codec, elemType, key)
+		if errors.Is(err, ErrNoSuchEntity) {
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("id %v: %w", id, err))
+			continue
+		}
+		m.SetMapIndex(mapKeys[i], v.Elem())
+	}
+
+	return errors.Join(errs...)
+}
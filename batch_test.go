@@ -0,0 +1,172 @@
+package burrowdb
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type batchItem struct {
+	ID   int
+	Name string
+}
+
+func TestPutAll(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The invalid value doesn't stop the values after it being put.
+	err = db.PutAll(batchItem{ID: 1}, 5, batchItem{ID: 2})
+	if !errors.Is(err, ErrInvalidValueType) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidValueType)
+	}
+
+	for _, id := range []int{1, 2} {
+		err = db.GetByID(&batchItem{}, id)
+		if err != nil {
+			t.Fatalf("got %v getting %d", err, id)
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	var ops []string
+	db, err := NewDB(WithDir(t.TempDir()), WithErrorHandler(func(op string, err error) error {
+		ops = append(ops, op)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(batchItem{ID: 1}, batchItem{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []batchItem
+	err = db.GetMany(&items, []any{2, 3, 1, 4})
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want %v", err, ErrNoSuchEntity)
+	}
+	if len(items) != 2 || items[0].ID != 2 || items[1].ID != 1 {
+		t.Fatalf("got %v, want the found entities in the order of the IDs", items)
+	}
+
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Fatalf("got %v, want an error for each missing ID", err)
+	}
+
+	// Errors are passed to the error handler once for each call, as
+	// GetMany's own.
+	err = db.GetMany(items, []any{1})
+	if !errors.Is(err, ErrNonPointerDst) {
+		t.Fatalf("got %v, want %v", err, ErrNonPointerDst)
+	}
+
+	err = db.GetMany(&items, []any{3, 4})
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want %v", err, ErrNoSuchEntity)
+	}
+
+	if !slices.Equal(ops, []string{"GetMany", "GetMany", "GetMany"}) {
+		t.Fatalf("got handled ops %q, want GetMany for each call", ops)
+	}
+}
+
+type batchUnique struct {
+	ID    int
+	Email string `burrowdb:"unique"`
+}
+
+func TestPutBatch(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts []newDBOption
+	}{
+		{"Plain", nil},
+		{"WALContentAddressing", []newDBOption{WithWAL(), WithContentAddressing()}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := NewDB(append([]newDBOption{WithDir(t.TempDir())}, test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The later value with the same ID is kept.
+			err = db.PutBatch(batchUnique{ID: 1, Email: "x"}, batchItem{ID: 2}, batchUnique{ID: 3, Email: "y"}, batchUnique{ID: 1, Email: "w"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var items []batchUnique
+			err = db.GetAll(&items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 || items[0].Email != "w" || items[1].Email != "y" {
+				t.Fatalf("got %+v", items)
+			}
+
+			err = db.GetByID(&batchItem{}, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A violation by one value leaves every entity as it was.
+			err = db.PutBatch(batchUnique{ID: 4, Email: "z"}, batchUnique{ID: 5, Email: "z"})
+			if !errors.Is(err, ErrUniqueViolation) {
+				t.Fatalf("got %v, want %v", err, ErrUniqueViolation)
+			}
+
+			err = db.PutBatch(batchUnique{ID: 3, Email: "v"}, batchUnique{ID: 6, Email: "w"})
+			if !errors.Is(err, ErrUniqueViolation) {
+				t.Fatalf("got %v, want %v", err, ErrUniqueViolation)
+			}
+
+			err = db.GetAll(&items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 || items[1].Email != "y" {
+				t.Fatalf("got %+v after a failed batch, want it unchanged", items)
+			}
+		})
+	}
+}
+
+func TestGetManyMap(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(batchItem{ID: 1, Name: "a"}, batchItem{ID: 3, Name: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing IDs are left out, and integer IDs may have any integer kind.
+	var items map[int]batchItem
+	err = db.GetManyMap(&items, []any{1, 2, int64(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Name != "a" || items[3].Name != "c" {
+		t.Fatalf("got %v, want entities 1 and 3", items)
+	}
+
+	err = db.GetManyMap(&items, []any{"x"})
+	if !errors.Is(err, ErrKeyTypeMismatch) {
+		t.Fatalf("got %v for a string ID, want %v", err, ErrKeyTypeMismatch)
+	}
+
+	var byName map[string]batchItem
+	err = db.GetManyMap(&byName, []any{1})
+	if !errors.Is(err, ErrKeyTypeMismatch) {
+		t.Fatalf("got %v for string keys, want %v", err, ErrKeyTypeMismatch)
+	}
+}
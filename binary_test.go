@@ -0,0 +1,72 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type binaryItem struct {
+	ID    int
+	Name  string
+	Data  []byte `burrowdb:"binary"`
+	Empty []byte `burrowdb:"binary"`
+	Plain []byte
+}
+
+func TestBinaryFields(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 64<<10)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+
+	item := binaryItem{ID: 1, Name: "large", Data: data, Plain: []byte("plain")}
+	err = db.Put(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got binaryItem
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != item.Name || !bytes.Equal(got.Data, data) || got.Empty != nil || string(got.Plain) != "plain" {
+		t.Fatalf("got %q with %d bytes of data, want the value put", got.Name, len(got.Data))
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "binaryItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= int64(len(encoded))*4/5 {
+		t.Fatalf("got %d bytes stored, want well under the %d of base64", info.Size(), len(encoded))
+	}
+
+	// Raw readers see the JSON the entity was encoded as.
+	raw, err := db.GetRawJSON("binaryItem", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded binaryItem
+	err = json.Unmarshal(raw, &decoded)
+	if err != nil || !bytes.Equal(decoded.Data, data) {
+		t.Fatalf("got %v decoding the raw JSON", err)
+	}
+}
@@ -0,0 +1,173 @@
+package burrowdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	blobDirName   = ".blobs"         // Name of the directory in the db dir holding content addressed data.
+	blobRefPrefix = "burrowdb:blob:" // Prefix of the entity files which refer to a blob.
+	blobRefSuffix = ".refs"          // Suffix of the files counting the references to each blob.
+)
+
+// WithContentAddressing specifies that the encoded data of each entity should
+// be stored once under the SHA-256 hash of its contents, with the entity file
+// holding only a reference to it. Entities with identical data share a single
+// file, which is removed once nothing refers to it.
+//
+// The option must be used every time a dir written with it is opened, as
+// references aren't followed without it.
+func WithContentAddressing() newDBOption {
+	return func(db *BurrowDB) error {
+		db.contentAddressing = true
+		return nil
+	}
+}
+
+// storeBlob stores data under its hash, if it isn't already, and counts a new
+// reference to it. The contents of the entity file referring to it are
+// returned.
+func (db *BurrowDB) storeBlob(data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	db.locks.blobs.Lock()
+	defer db.locks.blobs.Unlock()
+
+	refs, err := db.blobRefs(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if refs == 0 {
+		err = db.mkdirAll(db.blobDir())
+		if err != nil {
+			return nil, fmt.Errorf("unable to create blob dir: %w", err)
+		}
+
+		err = db.writeFileAtomic(db.blobPath(hash), data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write blob: %w", err)
+		}
+	}
+
+	err = db.writeBlobRefs(hash, refs+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(blobRefPrefix + hash), nil
+}
+
+// releaseBlob removes a reference to the blob referred to by the passed entity
+// file contents, removing the blob once nothing refers to it. Nothing is done
+// if the contents aren't a reference.
+func (db *BurrowDB) releaseBlob(ref []byte) error {
+	hash, ok := blobHash(ref)
+	if !ok {
+		return nil
+	}
+
+	db.locks.blobs.Lock()
+	defer db.locks.blobs.Unlock()
+
+	refs, err := db.blobRefs(hash)
+	if err != nil {
+		return err
+	}
+
+	if refs > 1 {
+		return db.writeBlobRefs(hash, refs-1)
+	}
+
+	err = os.Remove(db.blobPath(hash))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove blob: %w", err)
+	}
+
+	err = os.Remove(db.blobPath(hash) + blobRefSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove blob references: %w", err)
+	}
+
+	return nil
+}
+
+// resolveBlob returns the data of the blob referred to by the passed entity
+// file contents, or the contents themselves if they aren't a reference.
+func (db *BurrowDB) resolveBlob(ref []byte) ([]byte, error) {
+	hash, ok := blobHash(ref)
+	if !ok {
+		return ref, nil
+	}
+
+	db.locks.blobs.Lock()
+	defer db.locks.blobs.Unlock()
+
+	data, err := os.ReadFile(db.blobPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read blob %s: %w", hash, err)
+	}
+
+	return data, nil
+}
+
+// blobRefs returns the number of entities referring to the blob with the
+// passed hash. The blob lock must be held.
+func (db *BurrowDB) blobRefs(hash string) (int, error) {
+	data, err := os.ReadFile(db.blobPath(hash) + blobRefSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("unable to read blob references: %w", err)
+	}
+
+	refs, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse blob references: %w", err)
+	}
+
+	return refs, nil
+}
+
+// writeBlobRefs records the number of entities referring to the blob with the
+// passed hash. The blob lock must be held.
+func (db *BurrowDB) writeBlobRefs(hash string, refs int) error {
+	err := db.writeFileAtomic(db.blobPath(hash)+blobRefSuffix, []byte(strconv.Itoa(refs)))
+	if err != nil {
+		return fmt.Errorf("unable to write blob references: %w", err)
+	}
+	return nil
+}
+
+// blobHash returns the hash of the blob referred to by the passed entity file
+// contents, which may start with the header written by WithFileHeader. False is
+// returned if they aren't a reference.
+func blobHash(ref []byte) (string, bool) {
+	if rest, ok := bytes.CutPrefix(ref, fileMagic); ok && len(rest) > 0 {
+		ref = rest[1:]
+	}
+
+	hash, ok := bytes.CutPrefix(ref, []byte(blobRefPrefix))
+	if !ok || len(hash) != hex.EncodedLen(sha256.Size) {
+		return "", false
+	}
+	return string(hash), true
+}
+
+// blobDir returns the directory holding content addressed data.
+func (db *BurrowDB) blobDir() string {
+	return fmt.Sprintf("%s/%s", db.dir, blobDirName)
+}
+
+// blobPath returns the path of the blob with the passed hash.
+func (db *BurrowDB) blobPath(hash string) string {
+	return fmt.Sprintf("%s/%s", db.blobDir(), hash)
+}
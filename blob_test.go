@@ -0,0 +1,67 @@
+package burrowdb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type blobItem struct {
+	ID   int
+	Body string
+}
+
+type blobCopy blobItem
+
+func TestContentAddressing(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithContentAddressing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both entities encode to the same bytes, so share a blob.
+	err = db.PutAll(blobItem{ID: 1, Body: "same"}, blobCopy{ID: 1, Body: "same"}, blobItem{ID: 2, Body: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := filepath.Glob(filepath.Join(db.blobDir(), "*"+blobRefSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("got %d blobs, want 2", len(blobs))
+	}
+
+	var items []blobItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Body != "same" || items[1].Body != "other" {
+		t.Fatalf("got %+v", items)
+	}
+
+	// The shared blob is kept until nothing refers to it.
+	err = db.Delete(blobItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c blobCopy
+	err = db.GetByID(&c, 1)
+	if err != nil || c.Body != "same" {
+		t.Fatalf("got %+v, %v after deleting the other reference", c, err)
+	}
+
+	err = db.Delete(blobCopy{}, 1)
+	if err == nil {
+		err = db.Delete(blobItem{}, 2)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countFiles(t, db.blobDir()); n != 0 {
+		t.Fatalf("got %d blob files after deleting every entity, want 0", n)
+	}
+}
@@ -0,0 +1,111 @@
+package burrowdb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// bulkOption is an option which can be passed to BulkLoad to change how values
+// are loaded.
+type bulkOption func(*bulkConfig)
+
+// bulkConfig holds the settings of a single BulkLoad.
+type bulkConfig struct {
+	onCollision func(existing, incoming any) (any, error) // resolves values whose ID is already stored, or nil.
+}
+
+// WithOnCollision specifies that when BulkLoad loads a value whose ID is
+// already stored, whether before the load or by an earlier value in it, the
+// stored entity and the value should be passed to fn, and the value fn returns
+// stored instead. The returned value must have the same type as the loaded
+// ones. An error returned by fn stops the load. Without it the loaded value
+// replaces the stored entity.
+func WithOnCollision(fn func(existing, incoming any) (any, error)) bulkOption {
+	return func(c *bulkConfig) {
+		c.onCollision = fn
+	}
+}
+
+// BulkLoad puts each struct in the slice vs into the db as Put would, but
+// without updating the indexes of their type, which makes loading many entities
+// much faster. If rebuildIndexesAfter is true the indexes are rebuilt once every
+// entity is written, as RebuildIndexes would, otherwise they are left stale
+// until RebuildIndexes is called. Unique fields are only checked by the rebuild.
+//
+// The lock of the type is held throughout. The first value which fails stops
+// the load, leaving the values before it stored.
+func (db *BurrowDB) BulkLoad(vs any, rebuildIndexesAfter bool, opts ...bulkOption) (err error) {
+	defer db.handleError("BulkLoad", &err)
+
+	var cfg bulkConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	slice := reflect.ValueOf(vs)
+	if slice.Kind() != reflect.Slice {
+		return ErrInvalidValueType
+	}
+
+	_type := slice.Type().Elem()
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range slice.Len() {
+		v := slice.Index(i).Interface()
+		if cfg.onCollision != nil {
+			v, err = db.resolveCollision(v, cfg.onCollision)
+		}
+		if err == nil {
+			err = db.put(v, func(c *putConfig) {
+				c.skipIndexes = true
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+
+	if !rebuildIndexesAfter {
+		return nil
+	}
+
+	return db.rebuildIndexes(_type)
+}
+
+// resolveCollision returns the value to load in place of v, passing it and the
+// stored entity with the same type and ID to fn if there is one, as described
+// by WithOnCollision. The lock of the type must be held.
+func (db *BurrowDB) resolveCollision(v any, fn func(existing, incoming any) (any, error)) (any, error) {
+	_type := reflect.TypeOf(v)
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := db.idKey(reflect.ValueOf(v).FieldByIndex(idField.Index).Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := db.existing(_type, key)
+	if err != nil || existing == nil {
+		return v, err
+	}
+
+	result, err := fn(existing, v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve collision: %w", err)
+	}
+
+	if reflect.TypeOf(result) != _type {
+		return nil, fmt.Errorf("%w: collision callback returned %T, expected %s", ErrInvalidValueType, result, _type)
+	}
+
+	return result, nil
+}
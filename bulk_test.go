@@ -0,0 +1,148 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type bulkItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func bulkItems(n int) []bulkItem {
+	items := make([]bulkItem, n)
+	for i := range items {
+		items[i] = bulkItem{ID: i, Status: fmt.Sprint(i % 3)}
+	}
+	return items
+}
+
+func TestBulkLoad(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.BulkLoad(bulkItems(30), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []bulkItem
+	err = db.GetByField(&items, "Status", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 10 {
+		t.Fatalf("got %d items, want 10", len(items))
+	}
+	for _, item := range items {
+		if item.ID%3 != 1 {
+			t.Errorf("got %+v with status 1", item)
+		}
+	}
+
+	err = db.BulkLoad([]int{1}, false)
+	if err == nil {
+		t.Fatal("got no error loading a slice of non-structs")
+	}
+}
+
+func TestBulkLoadCollision(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(bulkItem{ID: 1, Status: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merge := WithOnCollision(func(existing, incoming any) (any, error) {
+		e, i := existing.(bulkItem), incoming.(bulkItem)
+		return bulkItem{ID: e.ID, Status: e.Status + i.Status}, nil
+	})
+	err = db.BulkLoad([]bulkItem{{ID: 1, Status: "b"}, {ID: 2, Status: "c"}, {ID: 1, Status: "d"}}, true, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item bulkItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != "abd" {
+		t.Fatalf("got status %q, want the colliding values merged into %q", item.Status, "abd")
+	}
+
+	err = db.GetByID(&item, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != "c" {
+		t.Fatalf("got status %q, want %q", item.Status, "c")
+	}
+
+	errAbort := errors.New("abort")
+	err = db.BulkLoad([]bulkItem{{ID: 2, Status: "e"}}, false, WithOnCollision(func(existing, incoming any) (any, error) {
+		return nil, errAbort
+	}))
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("got %v, want %v", err, errAbort)
+	}
+
+	// Without a callback the last value wins.
+	err = db.BulkLoad([]bulkItem{{ID: 2, Status: "f"}, {ID: 2, Status: "g"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&item, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != "g" {
+		t.Fatalf("got status %q, want %q", item.Status, "g")
+	}
+}
+
+// BenchmarkBulkLoad compares loading entities with BulkLoad to putting them one
+// at a time.
+func BenchmarkBulkLoad(b *testing.B) {
+	items := bulkItems(500)
+	for _, bench := range []struct {
+		name string
+		load func(db *BurrowDB) error
+	}{
+		{"Put", func(db *BurrowDB) error {
+			for _, item := range items {
+				err := db.Put(item)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"BulkLoad", func(db *BurrowDB) error {
+			return db.BulkLoad(items, true)
+		}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for b.Loop() {
+				db, err := NewDB(WithDir(b.TempDir()))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				err = bench.load(db)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Package burrowtest provides helpers for tests which use a burrowdb store.
+package burrowtest
+
+import (
+	"testing"
+
+	"github.com/davidsutts/burrowdb"
+)
+
+// NewTestDB returns a new db in a temp dir created by tb.TempDir, with the
+// passed options applied after the dir, closing it when the test and its
+// subtests complete. The test is failed immediately if the db can't be opened.
+//
+//	func TestThing(t *testing.T) {
+//		db := burrowtest.NewTestDB(t, burrowdb.WithCodec(burrowdb.GobCodec))
+//		...
+//	}
+func NewTestDB(tb testing.TB, opts ...func(*burrowdb.BurrowDB) error) *burrowdb.BurrowDB {
+	tb.Helper()
+
+	db, err := burrowdb.NewDB(burrowdb.WithDir(tb.TempDir()), func(db *burrowdb.BurrowDB) error {
+		for _, opt := range opts {
+			err := opt(db)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("unable to get new DB: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		err := db.Close()
+		if err != nil {
+			tb.Errorf("unable to close DB: %v", err)
+		}
+	})
+
+	return db
+}
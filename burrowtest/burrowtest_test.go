@@ -0,0 +1,66 @@
+package burrowtest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidsutts/burrowdb"
+	"github.com/davidsutts/burrowdb/burrowtest"
+)
+
+type item struct {
+	ID   int
+	Name string
+}
+
+func TestNewTestDB(t *testing.T) {
+	db := burrowtest.NewTestDB(t, burrowdb.WithCodec(burrowdb.GobCodec))
+
+	err := db.Put(item{ID: 1, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got item
+	err = db.GetByID(&got, 1)
+	if err != nil || got.Name != "a" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	if codec := db.Config().Codec; codec != "gob" {
+		t.Fatalf("got codec %s, want the options applied", codec)
+	}
+}
+
+func TestNewTestDBDirs(t *testing.T) {
+	a := burrowtest.NewTestDB(t)
+	b := burrowtest.NewTestDB(t)
+	if a.Config().Dir == b.Config().Dir {
+		t.Fatal("got the same dir for two dbs")
+	}
+
+	info, err := os.Stat(a.Config().Dir)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("got %v, want the dir created", err)
+	}
+}
+
+func TestNewTestDBCleanup(t *testing.T) {
+	var dir string
+	t.Run("Open", func(t *testing.T) {
+		db := burrowtest.NewTestDB(t, burrowdb.WithProcessLock())
+		dir = db.Config().Dir
+
+		err := db.Put(item{ID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// The db is closed, and its dir removed, once the subtest completes.
+	_, err := os.Stat(filepath.Join(dir, "item"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("got %v, want the dir removed", err)
+	}
+}
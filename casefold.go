@@ -0,0 +1,59 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// caseProbeName is the name of the file briefly created in the db dir to check
+// whether the filesystem it is on is case-insensitive.
+const caseProbeName = ".burrow-case-probe"
+
+// WithCaseFoldedTypes specifies that type dirs should be named with the lower
+// case form of their type name, so that types whose names differ only by case,
+// or a type renamed in case, map to the same dir and lock on every filesystem.
+//
+// This is done automatically if NewDB finds that the db dir is on a
+// case-insensitive filesystem, such as the macOS and Windows defaults, where
+// such names would otherwise share a dir without sharing a lock. It can be
+// used to make a store behave the same when moved between filesystems. Note
+// that type dirs created without it whose names aren't lower case won't be
+// found on a case-sensitive filesystem.
+func WithCaseFoldedTypes() newDBOption {
+	return func(db *BurrowDB) error {
+		db.foldTypeCase = true
+		return nil
+	}
+}
+
+// isCaseInsensitive reports whether the filesystem the passed dir is on treats
+// names differing only by case as the same file.
+func isCaseInsensitive(dir string) (bool, error) {
+	filename := filepath.Join(dir, caseProbeName)
+	err := os.WriteFile(filename, nil, 0666)
+	if err != nil {
+		return false, fmt.Errorf("unable to write case probe: %w", err)
+	}
+	defer os.Remove(filename)
+
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(caseProbeName)))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to stat case probe: %w", err)
+	}
+
+	return true, nil
+}
+
+// typeDirName returns the name of the dir holding the entities of the named
+// type, relative to the db dir.
+func (db *BurrowDB) typeDirName(typeName string) string {
+	if db.foldTypeCase {
+		return strings.ToLower(typeName)
+	}
+	return typeName
+}
@@ -0,0 +1,48 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type caseItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+// CaseItem differs from caseItem only by case.
+type CaseItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestCaseFoldedTypes(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithCaseFoldedTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(CaseItem{ID: 1, Status: "a"}, caseItem{ID: 2, Status: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"1", "2"} {
+		_, err = os.Stat(filepath.Join(dir, "caseitem", key))
+		if err != nil {
+			t.Fatalf("got %v, want the entity in the lower case dir", err)
+		}
+	}
+
+	if db.typeLock("CaseItem") != db.typeLock("caseItem") {
+		t.Fatal("got different locks for the same dir")
+	}
+
+	var items []CaseItem
+	err = db.GetByField(&items, "Status", "a")
+	if err != nil || len(items) != 2 {
+		t.Fatalf("got %+v, %v, want both entities indexed", items, err)
+	}
+}
@@ -0,0 +1,293 @@
+package burrowdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const changeLogFileName = ".changelog" // Name of the change log file in the db dir.
+
+// tailPollInterval is how often Tail checks the change log for entries logged
+// by other processes.
+const tailPollInterval = 100 * time.Millisecond
+
+// ChangeOp is the kind of mutation recorded by a ChangeEntry.
+type ChangeOp string
+
+const (
+	OpPut    ChangeOp = "put"
+	OpDelete ChangeOp = "delete"
+)
+
+// ChangeEntry is a single mutation recorded in the change log.
+type ChangeEntry struct {
+	ID   string    `json:"id"`   // Key of the mutated entity.
+	Type string    `json:"type"` // Type of the mutated entity.
+	Op   ChangeOp  `json:"op"`   // Kind of mutation.
+	Time time.Time `json:"time"` // When the mutation was made.
+
+	Seq  int    `json:"-"` // Position of the entry in the change log from 0, set when it is read.
+	Data []byte `json:"-"` // Stored bytes of the entity for puts sent by Tail, as read when sent.
+}
+
+// WithChangeLog specifies that every Put and Delete should be recorded, in
+// order, in an append-only change log in the db dir. The log can be read with
+// ReadChangeLog, or followed with Tail.
+func WithChangeLog() newDBOption {
+	return func(db *BurrowDB) error {
+		db.changeLog = true
+		return nil
+	}
+}
+
+// ReadChangeLog returns every entry in the change log, oldest first.
+func (db *BurrowDB) ReadChangeLog() (_ []ChangeEntry, err error) {
+	defer db.handleError("ReadChangeLog", &err)
+
+	db.locks.changeLog.Lock()
+	defer db.locks.changeLog.Unlock()
+
+	release, err := db.acquireFile()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	f, err := os.Open(db.changeLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open change log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []ChangeEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := ChangeEntry{Seq: len(entries)}
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal change log entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read change log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// logChange appends an entry for the passed mutation to the change log, if the
+// change log is enabled. Mutations of reserved types aren't recorded.
+func (db *BurrowDB) logChange(typeName, key string, op ChangeOp) error {
+	if !db.changeLog || isReservedType(typeName) {
+		return nil
+	}
+
+	line, err := json.Marshal(ChangeEntry{ID: key, Type: typeName, Op: op, Time: time.Now()})
+	if err != nil {
+		return fmt.Errorf("unable to marshal change log entry: %w", err)
+	}
+
+	db.locks.changeLog.Lock()
+	defer db.locks.changeLog.Unlock()
+
+	release, err := db.acquireFile()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f, err := os.OpenFile(db.changeLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, db.filePerm())
+	if err != nil {
+		return fmt.Errorf("unable to open change log: %w", err)
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write change log: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close change log: %w", err)
+	}
+
+	// Wake any tails waiting for the entry.
+	if db.locks.changed != nil {
+		close(db.locks.changed)
+		db.locks.changed = nil
+	}
+
+	return nil
+}
+
+// Tail returns a channel receiving the entries of the change log, oldest first,
+// starting with the entry with the passed sequence number. Entries are numbered
+// from 0 in the order they were logged, so they match the indexes of the slice
+// returned by ReadChangeLog and a replicator which has applied n entries can
+// resume with Tail(ctx, n). The Data of each put is the current stored bytes of
+// the entity, so the entries can be passed to the Apply of a follower.
+//
+// Once the existing entries have been sent, Tail blocks for new ones until ctx
+// is done, at which point the channel is closed. Entries logged by other
+// processes are picked up by polling the log every 100ms. If the log
+// can't be read, or has been truncated such as by Reset or Restore, the
+// channel is closed and the error is passed to the handler given to
+// WithErrorHandler, if any, with the operation "Tail".
+func (db *BurrowDB) Tail(ctx context.Context, from int) (_ <-chan ChangeEntry, err error) {
+	defer db.handleError("Tail", &err)
+
+	if from < 0 {
+		return nil, fmt.Errorf("invalid sequence number %d", from)
+	}
+
+	ch := make(chan ChangeEntry)
+	go db.tail(ctx, from, ch)
+
+	return ch, nil
+}
+
+// tail sends the entries of the change log from the passed sequence number to
+// ch until ctx is done, as described by Tail, closing ch when it returns.
+func (db *BurrowDB) tail(ctx context.Context, from int, ch chan<- ChangeEntry) {
+	defer close(ch)
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	var offset int64
+	var seq int
+	for {
+		entries, next, changed, err := db.readChangesFrom(offset)
+		if err != nil {
+			db.handleError("Tail", &err)
+			return
+		}
+		offset = next
+
+		for _, entry := range entries {
+			entry.Seq = seq
+			seq++
+			if entry.Seq < from {
+				continue
+			}
+
+			entry.Data, err = db.changeData(entry)
+			if err != nil {
+				db.handleError("Tail", &err)
+				return
+			}
+
+			select {
+			case ch <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// changeData returns the stored bytes of the entity put by the passed entry, or
+// nil if it is a delete or the entity has since been deleted.
+func (db *BurrowDB) changeData(entry ChangeEntry) ([]byte, error) {
+	if entry.Op != OpPut {
+		return nil, nil
+	}
+
+	mu := db.typeLock(entry.Type)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	data, err := db.readEntity(entry.Type, entry.ID)
+	if errors.Is(err, ErrNoSuchEntity) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s %q: %w", entry.Type, entry.ID, err)
+	}
+
+	return data, nil
+}
+
+// readChangesFrom returns the complete entries of the change log from the
+// passed byte offset, the offset following them and a channel which is closed
+// when another entry is added by this process.
+func (db *BurrowDB) readChangesFrom(offset int64) ([]ChangeEntry, int64, <-chan struct{}, error) {
+	db.locks.changeLog.Lock()
+	defer db.locks.changeLog.Unlock()
+
+	if db.locks.changed == nil {
+		db.locks.changed = make(chan struct{})
+	}
+	changed := db.locks.changed
+
+	release, err := db.acquireFile()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer release()
+
+	f, err := os.Open(db.changeLogPath())
+	if errors.Is(err, os.ErrNotExist) && offset == 0 {
+		return nil, 0, changed, nil
+	} else if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil, errors.New("change log has been removed")
+	} else if err != nil {
+		return nil, 0, nil, fmt.Errorf("unable to open change log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("unable to stat change log: %w", err)
+	} else if info.Size() < offset {
+		return nil, 0, nil, errors.New("change log has been truncated")
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("unable to seek change log: %w", err)
+	}
+
+	var entries []ChangeEntry
+	r := bufio.NewReader(f)
+	for {
+		// An incomplete final line is being written by another process.
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return entries, offset, changed, nil
+		} else if err != nil {
+			return nil, 0, nil, fmt.Errorf("unable to read change log: %w", err)
+		}
+
+		var entry ChangeEntry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("unable to unmarshal change log entry at byte offset %d: %w", offset, err)
+		}
+		entries = append(entries, entry)
+		offset += int64(len(line))
+	}
+}
+
+// changeLogPath returns the path of the change log file.
+func (db *BurrowDB) changeLogPath() string {
+	return fmt.Sprintf("%s/%s", db.dir, changeLogFileName)
+}
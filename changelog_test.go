@@ -0,0 +1,144 @@
+package burrowdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type changeItem struct {
+	ID int
+}
+
+func TestChangeLog(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithChangeLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(changeItem{ID: 1}, changeItem{ID: 2})
+	if err == nil {
+		err = db.Delete(changeItem{}, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ping writes to a reserved type which isn't recorded.
+	err = db.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := db.ReadChangeLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ChangeEntry{
+		{ID: "1", Type: "changeItem", Op: OpPut},
+		{ID: "2", Type: "changeItem", Op: OpPut},
+		{ID: "1", Type: "changeItem", Op: OpDelete},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		w := want[i]
+		if entry.ID != w.ID || entry.Type != w.Type || entry.Op != w.Op {
+			t.Errorf("entry %d: got %+v, want %+v", i, entry, w)
+		}
+		if entry.Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+	}
+}
+
+func TestChangeLogDisabled(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(changeItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := db.ReadChangeLog()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("got %v, %v without a change log", entries, err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithChangeLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 2 {
+		err = db.Put(changeItem{ID: i + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries, err := db.Tail(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for j := range 25 {
+				err := db.Put(changeItem{ID: 100 + i*25 + j})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+
+	var got []ChangeEntry
+	timeout := time.After(5 * time.Second)
+	for len(got) < 101 {
+		select {
+		case entry := <-entries:
+			got = append(got, entry)
+		case <-timeout:
+			t.Fatalf("got %d entries, want 101", len(got))
+		}
+	}
+	wg.Wait()
+
+	want, err := db.ReadChangeLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range got {
+		if entry.ID != want[i+1].ID || entry.Op != want[i+1].Op {
+			t.Fatalf("got entry %d %+v, want %+v", i+1, entry, want[i+1])
+		}
+	}
+
+	cancel()
+	select {
+	case entry, ok := <-entries:
+		if ok {
+			t.Fatalf("got %+v after cancelling, want the channel closed", entry)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancelling")
+	}
+
+	_, err = db.Tail(context.Background(), -1)
+	if err == nil {
+		t.Fatal("got no error for a negative sequence number")
+	}
+}
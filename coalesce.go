@@ -0,0 +1,209 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithCoalesce specifies that Put should hold each value for window before
+// writing it, so that when the same entity is put several times within the
+// window only the latest value is written. This reduces disk churn for chatty
+// updates at the cost of durability, as held values are lost if the process
+// exits before they're written. Flush and Close write every held value at once.
+//
+// Put still validates and encodes the value before returning, so errors such as
+// ErrNoIDField are returned by it, but errors met writing a held value are only
+// returned by the next Flush or Close, having been passed to the handler given
+// to WithErrorHandler with the operation "Coalesce". Reads don't see held
+// values until they're written. Deleting an entity, including by Truncate and
+// Reset, or writing it other than by Put, such as by PutWithID, UpdateFunc,
+// PutBatch or Restore, discards the value held for it.
+func WithCoalesce(window time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if window <= 0 {
+			return errors.New("coalesce window must be positive")
+		}
+
+		db.coalesceWindow = window
+		db.coalesce = &coalescer{}
+		return nil
+	}
+}
+
+// coalescer holds the values put within the coalesce window.
+type coalescer struct {
+	mu      sync.Mutex
+	pending map[string]*heldPut // values waiting to be written keyed by type then key.
+	errs    []error             // errors met writing values when their window ended.
+}
+
+// heldPut is a value waiting to be written by the coalescer.
+type heldPut struct {
+	typeName string
+	v        any
+	opts     []putOption
+	timer    *time.Timer
+}
+
+// holdPut holds the passed struct value to be written once the coalesce window
+// of its entity ends, replacing any value already held for it. The window starts
+// when the first held value is put, so an entity put continuously is still
+// written every window. The lock of the type must be held for writing, so that
+// no value is held while writeHeld writes the previous one.
+func (db *BurrowDB) holdPut(v any, opts ...putOption) error {
+	var cfg putConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	enc, err := db.encode(v, cfg)
+	if err != nil {
+		return err
+	}
+	id := db.heldID(enc.typeName, enc.key)
+
+	c := db.coalesce
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, ok := c.pending[id]; ok {
+		held.v, held.opts = v, opts
+		return nil
+	}
+
+	if c.pending == nil {
+		c.pending = map[string]*heldPut{}
+	}
+	c.pending[id] = &heldPut{
+		typeName: enc.typeName,
+		v:        v,
+		opts:     opts,
+		timer: time.AfterFunc(db.coalesceWindow, func() {
+			err := db.writeHeld(id)
+			db.handleError("Coalesce", &err)
+			if err != nil {
+				c.mu.Lock()
+				c.errs = append(c.errs, err)
+				c.mu.Unlock()
+			}
+		}),
+	}
+
+	return nil
+}
+
+// writeHeld writes the value held with the passed ID, if there still is one.
+// The value is taken from the coalescer under the lock of its type, so a
+// delete of the entity either discards it or happens after it is written.
+func (db *BurrowDB) writeHeld(id string) error {
+	c := db.coalesce
+	c.mu.Lock()
+	held, ok := c.pending[id]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	mu := db.typeLock(held.typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	c.mu.Lock()
+	held, ok = c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	held.timer.Stop()
+
+	return db.put(held.v, held.opts...)
+}
+
+// dropHeld discards the value held for the entity of the named type with the
+// passed key, so that a delete or other write isn't undone by a put made before
+// it being written afterwards. The lock of the type must be held for writing.
+func (db *BurrowDB) dropHeld(typeName, key string) {
+	c := db.coalesce
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := db.heldID(typeName, key)
+	if held, ok := c.pending[id]; ok {
+		held.timer.Stop()
+		delete(c.pending, id)
+	}
+}
+
+// heldID returns the ID under which the value of the entity of the named type
+// with the passed key is held, which is the same whether the type is named by
+// its name or its dir.
+func (db *BurrowDB) heldID(typeName, key string) string {
+	return db.typeDirName(typeName) + "/" + key
+}
+
+// dropAllHeld discards the values held for every entity of the named type, or
+// of every type if typeName is "", as dropHeld does. The lock of the type, or
+// every lock, must be held for writing.
+func (db *BurrowDB) dropAllHeld(typeName string) {
+	c := db.coalesce
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, held := range c.pending {
+		if typeName == "" || db.typeDirName(held.typeName) == db.typeDirName(typeName) {
+			held.timer.Stop()
+			delete(c.pending, id)
+		}
+	}
+}
+
+// Flush writes every value held by WithCoalesce at once, rather than waiting
+// for their windows to end, then syncs every file written since the last sync
+// if WithSyncEvery or WithSyncInterval is used. The errors met writing and
+// syncing, along with those met in the background since the last Flush, are
+// joined and returned.
+func (db *BurrowDB) Flush() (err error) {
+	defer db.handleError("Flush", &err)
+
+	return db.flush()
+}
+
+// flush writes every held value and syncs written files as described by Flush.
+func (db *BurrowDB) flush() error {
+	return errors.Join(db.writeAllHeld(), db.syncWritten())
+}
+
+// writeAllHeld writes every value held by WithCoalesce, as described by Flush.
+func (db *BurrowDB) writeAllHeld() error {
+	c := db.coalesce
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	ids, errs := slices.Sorted(maps.Keys(c.pending)), c.errs
+	c.errs = nil
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		err := db.writeHeld(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to write %s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
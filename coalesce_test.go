@@ -0,0 +1,163 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type coalesceItem struct {
+	ID    int
+	Count int
+}
+
+func TestCoalesce(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCoalesce(200*time.Millisecond), WithChangeLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		err = db.Put(coalesceItem{ID: 1, Count: i + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var item coalesceItem
+	err = db.GetByID(&item, 1)
+	if err == nil {
+		t.Fatal("got a held value before the window ended")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err = db.GetByID(&item, 1)
+		if err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("value wasn't written after the window: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if item.Count != 3 {
+		t.Fatalf("got %+v, want the last value put", item)
+	}
+
+	entries, err := db.ReadChangeLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d writes, want 1: %+v", len(entries), entries)
+	}
+
+	// Close writes held values at once.
+	err = db.Put(coalesceItem{ID: 2, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&item, 2)
+	if err != nil || item.Count != 1 {
+		t.Fatalf("got %+v, %v after Close", item, err)
+	}
+}
+
+func TestCoalesceDelete(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCoalesce(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(coalesceItem{ID: 1, Count: 1})
+	if err == nil {
+		err = db.Flush()
+	}
+	if err == nil {
+		err = db.Put(coalesceItem{ID: 1, Count: 2})
+	}
+	if err == nil {
+		err = db.Delete(&coalesceItem{}, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The held value would have been written by now.
+	time.Sleep(200 * time.Millisecond)
+
+	var item coalesceItem
+	err = db.GetByID(&item, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %+v, %v after the window, want the delete kept", item, err)
+	}
+
+	err = db.Put(coalesceItem{ID: 2, Count: 1})
+	if err == nil {
+		err = db.Truncate(coalesceItem{})
+	}
+	if err == nil {
+		err = db.Put(coalesceItem{ID: 3, Count: 1})
+	}
+	if err == nil {
+		err = db.Reset()
+	}
+	if err == nil {
+		err = db.Flush()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int{2, 3} {
+		err = db.GetByID(&item, id)
+		if !errors.Is(err, ErrNoSuchEntity) {
+			t.Fatalf("got %+v, %v after Flush, want the held value discarded", item, err)
+		}
+	}
+}
+
+func TestCoalesceOtherWrites(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCoalesce(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A value held by Put isn't written over a later write made another way.
+	err = db.Put(coalesceItem{ID: 1, Count: 1})
+	if err == nil {
+		err = db.PutWithID(coalesceItem{ID: 1, Count: 2}, 1)
+	}
+	if err == nil {
+		err = db.Flush()
+	}
+	if err == nil {
+		err = db.Put(coalesceItem{ID: 1, Count: 3})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item coalesceItem
+	err = db.UpdateFunc(&item, 1, func() error {
+		item.Count += 10
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The held value would have been written by now.
+	time.Sleep(200 * time.Millisecond)
+
+	err = db.GetByID(&item, 1)
+	if err != nil || item.Count != 12 {
+		t.Fatalf("got %+v, %v after the window, want the count of 12 set by UpdateFunc", item, err)
+	}
+}
@@ -0,0 +1,327 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	codecFileName = ".codec"  // Name of the file in each type dir recording its codec.
+	codecsDirName = ".codecs" // Name of the directory in each type dir recording the codecs of individual entities.
+)
+
+// Codec encodes entities into the bytes stored on disk and decodes them back.
+type Codec interface {
+	Name() string                         // Name identifying the codec.
+	Marshal(v any) ([]byte, error)        // Encodes v.
+	Unmarshal(data []byte, dst any) error // Decodes data into the value pointed to by dst.
+}
+
+var (
+	JSONCodec      Codec = jsonCodec{}      // Encodes entities as JSON. This is the default codec.
+	GobCodec       Codec = gobCodec{}       // Encodes entities with encoding/gob.
+	MarshalerCodec Codec = marshalerCodec{} // Encodes entities with their own BurrowMarshaler methods.
+)
+
+// jsonCodec encodes entities as JSON using the options passed to WithJSONOptions.
+// A nil pointer field is stored as null and got as nil, while a pointer to a
+// zero value is got as a pointer to a zero value, so pointer fields can mark
+// optional values.
+type jsonCodec struct {
+	opts            JSONOptions
+	disallowUnknown bool // whether members not matching a field are rejected.
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (c jsonCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!c.opts.DisableHTMLEscape)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode terminates each value with a newline which json.Marshal doesn't.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (c jsonCodec) Unmarshal(data []byte, dst any) error {
+	if !c.opts.UseNumber && !c.disallowUnknown {
+		return json.Unmarshal(data, dst)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if c.opts.UseNumber {
+		dec.UseNumber()
+	}
+	if c.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(dst)
+}
+
+// gobCodec encodes entities with encoding/gob. Concrete types held in interface
+// fields must be registered with WithGobTypes.
+//
+// Unlike JSON, gob doesn't transmit zero values, so a pointer field pointing to
+// a zero value is got as nil. Types using nil pointers to mark absent fields
+// should be stored as JSON, which keeps a pointer to a zero value distinct.
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, dst any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(dst)
+}
+
+// BurrowMarshaler is implemented by types which encode themselves for storage,
+// bypassing the reflection based encoding of codecs such as JSONCodec for
+// performance critical types. Put stores values implementing it with
+// MarshalerCodec, under the ID returned by Key, whatever the type's codec is
+// and whether or not it has an ID field, ignoring the tags of its fields. Their
+// destinations must implement BurrowUnmarshaler to be got.
+type BurrowMarshaler interface {
+	MarshalBurrow() ([]byte, error) // Encodes the value.
+	Key() string                    // Returns the ID of the value.
+}
+
+// BurrowUnmarshaler is implemented by pointers to types which decode
+// themselves from the data encoded by their BurrowMarshaler.
+type BurrowUnmarshaler interface {
+	UnmarshalBurrow(data []byte) error // Decodes data into the value.
+}
+
+// marshalerCodec encodes entities with their BurrowMarshaler and
+// BurrowUnmarshaler methods.
+type marshalerCodec struct{}
+
+func (marshalerCodec) Name() string {
+	return "burrow"
+}
+
+func (marshalerCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(BurrowMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%T does not implement BurrowMarshaler", v)
+	}
+	return m.MarshalBurrow()
+}
+
+func (marshalerCodec) Unmarshal(data []byte, dst any) error {
+	u, ok := dst.(BurrowUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T does not implement BurrowUnmarshaler", dst)
+	}
+	return u.UnmarshalBurrow(data)
+}
+
+// WithCodecForType specifies the codec used to encode entities of the named
+// type, overriding the codec set by WithCodec.
+func WithCodecForType(typeName string, codec Codec) newDBOption {
+	return func(db *BurrowDB) error {
+		if db.typeCodecs == nil {
+			db.typeCodecs = map[string]Codec{}
+		}
+
+		db.typeCodecs[typeName] = codec
+		return nil
+	}
+}
+
+// WithKnownCodecs specifies codecs which entities may have been stored with,
+// other than JSONCodec, GobCodec, MarshalerCodec and those passed to WithCodec
+// or WithCodecForType, so that they can be decoded. Codecs passed to
+// WithCodecOption must be known.
+func WithKnownCodecs(codecs ...Codec) newDBOption {
+	return func(db *BurrowDB) error {
+		db.knownCodecs = append(db.knownCodecs, codecs...)
+		return nil
+	}
+}
+
+// WithCodecOption specifies the codec used to encode the entity being put,
+// overriding the codec of its type. The choice is recorded so that the entity
+// is decoded with the same codec when read. The codec must be known, as
+// described by WithKnownCodecs.
+func WithCodecOption(codec Codec) putOption {
+	return func(c *putConfig) {
+		c.codec = codec
+	}
+}
+
+// applyJSONOptions returns the passed codec with the db's JSON options applied
+// if it is the JSON codec.
+func (db *BurrowDB) applyJSONOptions(codec Codec) Codec {
+	if c, ok := codec.(jsonCodec); ok {
+		c.opts = db.jsonOpts
+		c.disallowUnknown = db.disallowUnknownFields
+		return c
+	}
+	return codec
+}
+
+// codecFor returns the codec used to encode entities of the named type.
+func (db *BurrowDB) codecFor(typeName string) Codec {
+	if codec, ok := db.typeCodecs[typeName]; ok {
+		return codec
+	}
+	return db.codec
+}
+
+// knownCodec returns the codec known to the db with the passed name.
+func (db *BurrowDB) knownCodec(name string) (Codec, bool) {
+	if db.codec.Name() == name {
+		return db.codec, true
+	}
+
+	for _, codec := range db.typeCodecs {
+		if codec.Name() == name {
+			return codec, true
+		}
+	}
+
+	for _, codec := range db.knownCodecs {
+		if codec.Name() == name {
+			return codec, true
+		}
+	}
+
+	switch name {
+	case JSONCodec.Name():
+		return db.applyJSONOptions(JSONCodec), true
+	case GobCodec.Name():
+		return GobCodec, true
+	case MarshalerCodec.Name():
+		return MarshalerCodec, true
+	}
+
+	return nil, false
+}
+
+// storedCodec returns the codec the entities of the named type were written
+// with, as recorded by recordCodec. The type's configured codec is returned if
+// nothing has been recorded, and ErrCodecMismatch if the recorded codec isn't
+// known to the db.
+func (db *BurrowDB) storedCodec(typeName string) (Codec, error) {
+	data, err := os.ReadFile(db.codecPath(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return db.codecFor(typeName), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read codec marker: %w", err)
+	}
+
+	name := string(data)
+	codec, ok := db.knownCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is stored with the unknown codec %q rather than %s", ErrCodecMismatch, typeName, name, db.codecFor(typeName).Name())
+	}
+
+	return codec, nil
+}
+
+// recordCodec records that the entities of the named type are written with the
+// passed codec so that they are decoded with it. ErrCodecMismatch is returned if
+// the type's existing entities were written with a different codec.
+func (db *BurrowDB) recordCodec(typeName string, codec Codec) error {
+	data, err := os.ReadFile(db.codecPath(typeName))
+	if err == nil {
+		if string(data) != codec.Name() {
+			return fmt.Errorf("%w: %s is stored with the %s codec and can't be written with %s", ErrCodecMismatch, typeName, data, codec.Name())
+		}
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read codec marker: %w", err)
+	}
+
+	err = db.mkdirAll(db.typeDir(typeName))
+	if err != nil {
+		return fmt.Errorf("unable to create type dir: %w", err)
+	}
+
+	err = db.writeFileAtomic(db.codecPath(typeName), []byte(codec.Name()))
+	if err != nil {
+		return fmt.Errorf("unable to write codec marker: %w", err)
+	}
+
+	return nil
+}
+
+// codecPath returns the path of the file recording the codec of the named type.
+func (db *BurrowDB) codecPath(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), codecFileName)
+}
+
+// entityCodec returns the codec the entity of the named type with the passed
+// key was written with, if it was overridden with WithCodecOption, and
+// otherwise the passed codec of its type.
+func (db *BurrowDB) entityCodec(typeName, key string, typeCodec Codec) (Codec, error) {
+	data, err := os.ReadFile(db.entityCodecPath(typeName, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return typeCodec, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read codec marker: %w", err)
+	}
+
+	name := string(data)
+	codec, ok := db.knownCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: entity %s of %s is stored with the unknown codec %q rather than %s", ErrCodecMismatch, key, typeName, name, typeCodec.Name())
+	}
+
+	return codec, nil
+}
+
+// recordEntityCodec records that the entity of the named type with the passed
+// key is written with the passed codec, overriding the codec of its type. The
+// record is removed if the codec is nil.
+func (db *BurrowDB) recordEntityCodec(typeName, key string, codec Codec) error {
+	filename := db.entityCodecPath(typeName, key)
+	if codec == nil {
+		err := os.Remove(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove codec marker: %w", err)
+		}
+		return nil
+	}
+
+	if _, ok := db.knownCodec(codec.Name()); !ok {
+		return fmt.Errorf("codec %q must be passed to WithKnownCodecs to be used with WithCodecOption", codec.Name())
+	}
+
+	err := db.mkdirAll(fmt.Sprintf("%s/%s", db.typeDir(typeName), codecsDirName))
+	if err != nil {
+		return fmt.Errorf("unable to create codec marker dir: %w", err)
+	}
+
+	err = db.writeFileAtomic(filename, []byte(codec.Name()))
+	if err != nil {
+		return fmt.Errorf("unable to write codec marker: %w", err)
+	}
+
+	return nil
+}
+
+// entityCodecPath returns the path of the file recording the codec of the
+// entity of the named type with the passed key.
+func (db *BurrowDB) entityCodecPath(typeName, key string) string {
+	return fmt.Sprintf("%s/%s/%s", db.typeDir(typeName), codecsDirName, key)
+}
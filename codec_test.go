@@ -0,0 +1,435 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type codecItem struct {
+	ID   int
+	Name string
+}
+
+type codecShape interface {
+	Area() float64
+}
+
+type codecSquare struct {
+	Side float64
+}
+
+func (s codecSquare) Area() float64 {
+	return s.Side * s.Side
+}
+
+type codecDrawing struct {
+	ID    int
+	Shape codecShape
+}
+
+func TestGobCodec(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCodec(GobCodec), WithGobTypes(codecSquare{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(codecDrawing{ID: 1, Shape: codecSquare{Side: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var drawing codecDrawing
+	err = db.GetByID(&drawing, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drawing.Shape == nil || drawing.Shape.Area() != 4 {
+		t.Fatalf("got %+v, want the interface field decoded", drawing)
+	}
+}
+
+func TestCodecForType(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithCodecForType("codecDrawing", GobCodec), WithGobTypes(codecSquare{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(codecDrawing{ID: 1, Shape: codecSquare{Side: 3}})
+	if err == nil {
+		err = db.Put(codecItem{ID: 1, Name: "a"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "codecItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ID":1,"Name":"a"}`; string(data) != want {
+		t.Fatalf("got %q for the other type, want it stored as JSON", data)
+	}
+
+	var drawing codecDrawing
+	err = db.GetByID(&drawing, 1)
+	if err != nil || drawing.Shape.Area() != 9 {
+		t.Fatalf("got %+v, %v", drawing, err)
+	}
+}
+
+// indentCodec is a JSON codec which indents what it marshals.
+type indentCodec struct{}
+
+func (indentCodec) Name() string {
+	return "json-indent"
+}
+
+func (indentCodec) Marshal(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (indentCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestCodecOption(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithKnownCodecs(indentCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(codecItem{ID: 1, Name: "plain"})
+	if err == nil {
+		err = db.Put(codecItem{ID: 2, Name: "indented"}, WithCodecOption(indentCodec{}))
+	}
+	if err == nil {
+		err = db.Put(codecItem{ID: 3, Name: "gob"}, WithCodecOption(GobCodec))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "codecItem", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"ID\": 2,\n  \"Name\": \"indented\"\n}"; string(data) != want {
+		t.Fatalf("got %q, want %q", data, want)
+	}
+
+	// Each entity is decoded with the codec it was written with.
+	var items []codecItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[1].Name != "indented" || items[2].Name != "gob" {
+		t.Fatalf("got %+v", items)
+	}
+
+	err = db.Put(codecItem{ID: 3, Name: "json again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item codecItem
+	err = db.GetByID(&item, 3)
+	if err != nil || item.Name != "json again" {
+		t.Fatalf("got %+v, %v", item, err)
+	}
+
+	// A codec must be known to the db for it to be read back.
+	plain, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = plain.Put(codecItem{ID: 1}, WithCodecOption(indentCodec{}))
+	if err == nil {
+		t.Fatal("got no error putting with an unknown codec")
+	}
+}
+
+// codecPair encodes itself as its fields separated by a bar.
+type codecPair struct {
+	A, B string
+}
+
+func (p codecPair) MarshalBurrow() ([]byte, error) {
+	return []byte(p.A + "|" + p.B), nil
+}
+
+func (p codecPair) Key() string {
+	return p.A
+}
+
+func (p *codecPair) UnmarshalBurrow(data []byte) error {
+	var ok bool
+	p.A, p.B, ok = strings.Cut(string(data), "|")
+	if !ok {
+		return errors.New("missing separator")
+	}
+	return nil
+}
+
+func TestMarshalerCodec(t *testing.T) {
+	// The type's own methods are used whatever the db's codec is.
+	db, err := NewDB(WithDir(t.TempDir()), WithCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(codecPair{A: "k", B: "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(db.entityPath("codecPair", "k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "k|v" {
+		t.Fatalf("got %q stored, want k|v", data)
+	}
+
+	var pair codecPair
+	err = db.GetByID(&pair, "k")
+	if err != nil || pair.B != "v" {
+		t.Fatalf("got %+v, %v", pair, err)
+	}
+
+	var pairs []codecPair
+	err = db.GetAll(&pairs)
+	if err != nil || len(pairs) != 1 {
+		t.Fatalf("got %+v, %v", pairs, err)
+	}
+	// The tags of its fields aren't looked at.
+	strict, err := NewDB(WithDir(t.TempDir()), WithStrictTags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = strict.Put(codecTagged{ID: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// codecTagged encodes itself despite having an invalid tag.
+type codecTagged struct {
+	ID string `burrowdb:"idnex"`
+}
+
+func (c codecTagged) MarshalBurrow() ([]byte, error) {
+	return []byte(c.ID), nil
+}
+
+func (c codecTagged) Key() string {
+	return c.ID
+}
+
+// codecRecord is encoded with reflection, and codecFastRecord, which has the
+// same fields, with its own methods.
+type codecRecord struct {
+	ID    string
+	Name  string
+	Count int
+}
+
+type codecFastRecord codecRecord
+
+func (r codecFastRecord) MarshalBurrow() ([]byte, error) {
+	return []byte(r.ID + "|" + r.Name + "|" + strconv.Itoa(r.Count)), nil
+}
+
+func (r codecFastRecord) Key() string {
+	return r.ID
+}
+
+func (r *codecFastRecord) UnmarshalBurrow(data []byte) error {
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 3 {
+		return errors.New("wrong number of fields")
+	}
+
+	count, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return err
+	}
+
+	r.ID, r.Name, r.Count = parts[0], parts[1], count
+	return nil
+}
+
+// BenchmarkMarshalerCodec compares putting and getting a struct with JSONCodec
+// to doing so with its BurrowMarshaler.
+func BenchmarkMarshalerCodec(b *testing.B) {
+	for _, bench := range []struct {
+		name  string
+		value any
+		dst   func() any
+	}{
+		{"JSON", codecRecord{ID: "r", Name: "record", Count: 7}, func() any { return &codecRecord{} }},
+		{"Marshaler", codecFastRecord{ID: "r", Name: "record", Count: 7}, func() any { return &codecFastRecord{} }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			db, err := NewDB(WithDir(b.TempDir()))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for b.Loop() {
+				err = db.Put(bench.value)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				err = db.GetByID(bench.dst(), "r")
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type codecOptional struct {
+	ID int
+	N  *int
+}
+
+func TestCodecPointers(t *testing.T) {
+	zero, five := 0, 5
+	tests := []struct {
+		codec Codec
+		want  []*int // values got back for nil, a pointer to zero and to five.
+	}{
+		{JSONCodec, []*int{nil, &zero, &five}},
+		{GobCodec, []*int{nil, nil, &five}},
+	}
+
+	for _, test := range tests {
+		db, err := NewDB(WithDir(t.TempDir()), WithCodec(test.codec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.PutAll(codecOptional{ID: 0}, codecOptional{ID: 1, N: &zero}, codecOptional{ID: 2, N: &five})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for id, want := range test.want {
+			var got codecOptional
+			err = db.GetByID(&got, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (got.N == nil) != (want == nil) || got.N != nil && *got.N != *want {
+				t.Errorf("%s %d: got %v, want %v", test.codec.Name(), id, got.N, want)
+			}
+		}
+	}
+}
+
+func TestCodecMismatch(t *testing.T) {
+	dir := t.TempDir()
+	gobDB, err := NewDB(WithDir(dir), WithCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gobDB.Put(codecItem{ID: 1, Name: "gob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonDB, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Gob is always known, so the entity is decoded with the recorded codec,
+	// but it can't be written with another.
+	var item codecItem
+	err = jsonDB.GetByID(&item, 1)
+	if err != nil || item.Name != "gob" {
+		t.Fatalf("got %+v, %v", item, err)
+	}
+
+	err = jsonDB.Put(codecItem{ID: 2, Name: "json"})
+	if !errors.Is(err, ErrCodecMismatch) {
+		t.Fatalf("got %v writing with JSON, want %v", err, ErrCodecMismatch)
+	}
+
+	// A codec unknown to the db can't be decoded.
+	dir = t.TempDir()
+	indentDB, err := NewDB(WithDir(dir), WithCodec(indentCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = indentDB.Put(codecItem{ID: 1, Name: "indented"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonDB, err = NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = jsonDB.GetByID(&item, 1)
+	if !errors.Is(err, ErrCodecMismatch) {
+		t.Fatalf("got %v reading an unknown codec, want %v", err, ErrCodecMismatch)
+	}
+	if !strings.Contains(err.Error(), indentCodec{}.Name()) || !strings.Contains(err.Error(), JSONCodec.Name()) {
+		t.Fatalf("got %q, want both codecs named", err)
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "codecItem"), 0777)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "codecItem", "1"), []byte(`{"ID":1,"Name":"a","Colour":"red"}`), 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lenient, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item codecItem
+	err = lenient.GetByID(&item, 1)
+	if err != nil || item.Name != "a" {
+		t.Fatalf("got %+v, %v", item, err)
+	}
+
+	strict, err := NewDB(WithDir(dir), WithDisallowUnknownFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = strict.GetByID(&item, 1)
+	if err == nil || !strings.Contains(err.Error(), `"Colour"`) {
+		t.Fatalf("got %v, want an error naming the unknown field", err)
+	}
+
+	var items []codecItem
+	err = strict.GetAll(&items)
+	if err == nil {
+		t.Fatal("got nil error getting every entity")
+	}
+}
@@ -0,0 +1,373 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Compact removes what the store accumulates as entities are written and
+// deleted. For each type, in turn:
+//   - entities exceeding the retention limits set by WithRetention are deleted;
+//   - index entries of entities which don't exist are removed, and every index
+//     is rewritten;
+//   - codecs and metadata recorded for entities which don't exist are removed,
+//     as are their IDs recorded by WithEncryptedKeys;
+//   - temp files left in the type dir by interrupted writes are removed.
+//
+// Each type is locked while it is compacted, so other types can be used
+// meanwhile. With WithContentAddressing the references to every blob are then
+// counted again, under the read lock of every type, and blobs which nothing
+// refers to are removed. Temp files written to the dir set by WithTempDir are
+// left alone, and another process using the same dir must not be writing to
+// it.
+func (db *BurrowDB) Compact() (err error) {
+	defer db.handleError("Compact", &err)
+
+	err = db.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, typeName := range typeNames {
+		err = db.compactType(typeName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to compact %s: %w", typeName, err))
+		}
+	}
+
+	if db.contentAddressing {
+		errs = append(errs, db.compactBlobs(typeNames))
+	}
+
+	return errors.Join(errs...)
+}
+
+// compactType compacts the named type, as described by Compact.
+func (db *BurrowDB) compactType(typeName string) error {
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	expired, err := db.expiredKeys(typeName)
+	if err != nil {
+		return err
+	}
+
+	// The Go type isn't known, so the indexes are updated from disk.
+	for _, key := range expired {
+		err = db.deleteEntity(typeName, key)
+		if err == nil {
+			err = db.removeFromRawIndexes(typeName, key)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to delete expired %q: %w", key, err)
+		}
+	}
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	live := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		live[key] = true
+	}
+
+	err = db.compactIndexes(typeName, live)
+	if err != nil {
+		return err
+	}
+
+	err = db.pruneKeyNames(typeName, live)
+	if err != nil {
+		return err
+	}
+
+	err = db.removeOrphans(fmt.Sprintf("%s/%s", db.typeDir(typeName), codecsDirName), live)
+	if err != nil {
+		return err
+	}
+
+	err = db.removeOrphans(fmt.Sprintf("%s/%s", db.typeDir(typeName), metaDirName), live)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.removeTempFiles(db.typeDir(typeName))
+	return err
+}
+
+// compactIndexes rewrites every index stored for the named type without the
+// entries of entities whose keys aren't in live. The lock of the type must be
+// held.
+func (db *BurrowDB) compactIndexes(typeName string, live map[string]bool) error {
+	indexes, err := db.readRawIndexes(typeName)
+	if err != nil {
+		return err
+	}
+
+	for field, idx := range indexes {
+		for value, keys := range idx {
+			keys = slices.DeleteFunc(keys, func(key string) bool {
+				return !live[key]
+			})
+
+			if len(keys) == 0 {
+				delete(idx, value)
+			} else {
+				idx[value] = keys
+			}
+		}
+
+		if isDir(db.indexPath(typeName, field)) {
+			err = db.writeEnumIndex(typeName, field, idx)
+		} else {
+			err = db.writeIndexFile(db.indexPath(typeName, field), idx)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write index %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// removeOrphans removes every file or dir in the passed dir, kept alongside
+// the entity named by it, whose name isn't in live.
+func (db *BurrowDB) removeOrphans(dir string, live map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read %s: %w", filepath.Base(dir), err)
+	}
+
+	for _, entry := range entries {
+		if live[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		err = os.RemoveAll(fmt.Sprintf("%s/%s", dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove orphaned %s: %w", filepath.Base(dir), err)
+		}
+	}
+
+	return nil
+}
+
+// removeTempFiles removes every temp file in the passed dir and those below
+// it, returning how many were removed.
+func (db *BurrowDB) removeTempFiles(dir string) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(dir, func(filename string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		err = os.Remove(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to remove temp file: %w", err)
+		}
+		removed++
+		return nil
+	})
+
+	return removed, err
+}
+
+// CleanTemp removes the temp files left in the db dir, and in the dir set by
+// WithTempDir, by writes which were interrupted by a crash, returning how many
+// were removed. Every type is locked meanwhile so that the temp files of
+// writes in progress are kept, but another process using the same dir must not
+// be writing to it.
+func (db *BurrowDB) CleanTemp() (_ int, err error) {
+	defer db.handleError("CleanTemp", &err)
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return 0, err
+	}
+
+	// Make sure every stored type has a lock for lockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	unlock := db.locks.lockAll()
+	defer unlock()
+
+	removed, err := db.removeTempFiles(db.dir)
+	if err != nil {
+		return removed, err
+	}
+
+	if db.tempDir == "" {
+		return removed, nil
+	}
+
+	n, err := db.removeTempFiles(db.tempDir)
+	return removed + n, err
+}
+
+// compactBlobs counts the references to every blob from the entities of the
+// named types, rewriting the reference counts which are wrong and removing
+// the blobs which nothing refers to.
+func (db *BurrowDB) compactBlobs(typeNames []string) error {
+	// Make sure every stored type has a lock for rLockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	unlock := db.locks.rLockAll()
+	defer unlock()
+
+	refs := map[string]int{}
+	for _, typeName := range typeNames {
+		keys, err := db.keys(typeName)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			ref, err := os.ReadFile(db.entityPath(typeName, key))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("unable to read %s %q: %w", typeName, key, err)
+			}
+
+			if hash, ok := blobHash(ref); ok {
+				refs[hash]++
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(db.blobDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read blob dir: %w", err)
+	}
+
+	stored := map[string]bool{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			stored[strings.TrimSuffix(entry.Name(), blobRefSuffix)] = true
+		}
+	}
+
+	for _, hash := range slices.Sorted(maps.Keys(stored)) {
+		if refs[hash] > 0 {
+			counted, err := db.blobRefs(hash)
+			if err == nil && counted != refs[hash] {
+				err = db.writeBlobRefs(hash, refs[hash])
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, filename := range []string{db.blobPath(hash), db.blobPath(hash) + blobRefSuffix} {
+			err = os.Remove(filename)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("unable to remove unused blob: %w", err)
+			}
+		}
+	}
+
+	_, err = db.removeTempFiles(db.blobDir())
+	return err
+}
+
+// CompactJSON rewrites every entity with the type of dst which is stored as
+// indented JSON, such as by an external tool, without the insignificant
+// whitespace to save space. Entities which are already compact are left
+// alone. The type is locked while it is rewritten, and an error is returned if
+// it isn't stored with the JSON codec. Entities stored with another codec by
+// WithCodecOption are skipped.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) CompactJSON(dst any) (err error) {
+	defer db.handleError("CompactJSON", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err != nil {
+		return err
+	}
+
+	if codec.Name() != JSONCodec.Name() {
+		return fmt.Errorf("%s is stored with the %s codec rather than JSON", typeName, codec.Name())
+	}
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		entityCodec, err := db.entityCodec(typeName, key, codec)
+		if err != nil {
+			return err
+		}
+
+		if entityCodec.Name() != JSONCodec.Name() {
+			continue
+		}
+
+		data, err := db.readEntity(typeName, key)
+		if err != nil {
+			return fmt.Errorf("unable to read %q: %w", key, err)
+		}
+
+		var buf bytes.Buffer
+		err = json.Compact(&buf, data)
+		if err != nil {
+			return fmt.Errorf("unable to compact %q: %w", key, err)
+		}
+
+		if buf.Len() == len(data) {
+			continue
+		}
+
+		err = db.writeEntity(typeName, key, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("unable to write %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
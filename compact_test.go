@@ -0,0 +1,244 @@
+package burrowdb
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type compactItem struct {
+	ID   int
+	Tag  string `burrowdb:"index"`
+	Kind string `burrowdb:"index,enum"`
+}
+
+// countFiles returns the number of files below dir.
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+
+	n := 0
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		err = db.Put(compactItem{ID: i, Tag: "t", Kind: "k"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Remove entity files behind the db's back, as an interrupted delete
+	// would, leaving their index entries.
+	for i := 1; i <= 5; i++ {
+		err = os.Remove(db.entityPath("compactItem", db.entityKey(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile(filepath.Join(db.typeDir("compactItem"), tempFilePrefix+"x"), nil, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := countFiles(t, dir)
+	err = db.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after := countFiles(t, dir); after != before-1 {
+		t.Errorf("got %d files after compacting, want %d", after, before-1)
+	}
+
+	for field, value := range map[string]string{"Tag": "t", "Kind": "k"} {
+		var got []compactItem
+		err = db.GetByField(&got, field, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 5 {
+			t.Fatalf("got %d entities by %s, want 5", len(got), field)
+		}
+		for _, item := range got {
+			if item.ID <= 5 {
+				t.Errorf("got %+v by %s", item, field)
+			}
+		}
+	}
+}
+
+func TestCompactBlobs(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithContentAddressing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		err = db.Put(compactItem{ID: i, Tag: "same"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Put(compactItem{ID: 4, Tag: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose two entities referring to the shared blob and the only one
+	// referring to the other.
+	for _, id := range []int{1, 2, 4} {
+		err = os.Remove(db.entityPath("compactItem", db.entityKey(id)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := filepath.Glob(filepath.Join(db.blobDir(), "*"+blobRefSuffix))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(blobs) != 1 {
+		t.Fatalf("got %d blobs, want 1", len(blobs))
+	}
+
+	var item compactItem
+	err = db.GetByID(&item, 3)
+	if err != nil || item.Tag != "same" {
+		t.Fatalf("got %+v, %v", item, err)
+	}
+
+	// The last reference is counted once, so deleting it removes the blob.
+	err = db.Delete(&item, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countFiles(t, db.blobDir()); n != 0 {
+		t.Errorf("got %d blob files after deleting, want 0", n)
+	}
+}
+
+func TestCompactJSON(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "compactItem"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretty := "{\n  \"ID\": 1,\n  \"Tag\": \"a b\",\n  \"Kind\": \"x\"\n}\n"
+	err = os.WriteFile(filepath.Join(dir, "compactItem", "1"), []byte(pretty), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(compactItem{ID: 2, Tag: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CompactJSON(compactItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "compactItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ID":1,"Tag":"a b","Kind":"x"}`; string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+
+	var item compactItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item != (compactItem{ID: 1, Tag: "a b", Kind: "x"}) {
+		t.Fatalf("got %+v", item)
+	}
+
+	gobDB, err := NewDB(WithDir(t.TempDir()), WithCodec(GobCodec))
+	if err == nil {
+		err = gobDB.Put(compactItem{ID: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gobDB.CompactJSON(compactItem{})
+	if err == nil {
+		t.Fatal("got nil error compacting a gob type")
+	}
+}
+
+func TestCleanTemp(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "tmp")
+	db, err := NewDB(WithDir(dir), WithTempDir(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(compactItem{ID: 1, Tag: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{
+		filepath.Join(dir, "compactItem", tempFilePrefix+"1"),
+		filepath.Join(dir, "compactItem", tempFilePrefix+"2"),
+		filepath.Join(dir, tempFilePrefix+"3"),
+		filepath.Join(tempDir, tempFilePrefix+"4"),
+	} {
+		err = os.WriteFile(filename, []byte("partial"), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := db.CleanTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 4 {
+		t.Fatalf("got %d removed, want 4", removed)
+	}
+
+	removed, err = db.CleanTemp()
+	if err != nil || removed != 0 {
+		t.Fatalf("got %d, %v cleaning again, want 0", removed, err)
+	}
+
+	var item compactItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
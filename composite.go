@@ -0,0 +1,123 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const compositeSeparator = "+" // Separator of the field names in the name of a composite index.
+
+// WithCompositeIndex specifies that entities of the named type should be indexed
+// by the combination of the values of the passed fields, so that they can be
+// found by all of them at once with GetByComposite. The index is kept up to date
+// by Put and Delete like the indexes of fields tagged `burrowdb:"index"`, and
+// is stored under the field names joined by "+", such as Status+Region.
+//
+// At least two fields must be given, and each must be a field of the type.
+// Entities with a nil pointer in any of the fields aren't indexed.
+func WithCompositeIndex(typeName string, fields ...string) newDBOption {
+	return func(db *BurrowDB) error {
+		if len(fields) < 2 {
+			return fmt.Errorf("composite index of %s must have at least two fields", typeName)
+		}
+
+		if db.compositeIndexes == nil {
+			db.compositeIndexes = map[string][][]string{}
+		}
+		db.compositeIndexes[typeName] = append(db.compositeIndexes[typeName], slices.Clone(fields))
+
+		return nil
+	}
+}
+
+// compositeSpecs returns the composite indexes of the passed struct type.
+// Indexes naming fields the type doesn't have are skipped.
+func (db *BurrowDB) compositeSpecs(_type reflect.Type) []indexSpec {
+	var specs []indexSpec
+	for _, names := range db.compositeIndexes[db.typeName(_type)] {
+		spec := indexSpec{name: strings.Join(names, compositeSeparator)}
+		for _, name := range names {
+			field, ok := _type.FieldByName(name)
+			if !ok {
+				spec.fields = nil
+				break
+			}
+			spec.fields = append(spec.fields, field)
+		}
+
+		if len(spec.fields) > 0 {
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs
+}
+
+// compositeValue returns the key in a composite index of the passed keys of the
+// values of its fields. The keys are encoded as a JSON array so that values
+// containing any separator can't be confused.
+func compositeValue(values []string) string {
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// GetByComposite gets every entity with the element type of the slice pointed
+// to by dst whose fields have the passed values, in the order of the fields of
+// the type's composite index with as many fields, replacing the slice's
+// contents. ErrNotIndexed is returned if the type has no such composite index,
+// or has more than one.
+func (db *BurrowDB) GetByComposite(dst any, values ...any) (err error) {
+	defer db.handleError("GetByComposite", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	specs := slices.DeleteFunc(db.compositeSpecs(elemType), func(spec indexSpec) bool {
+		return len(spec.fields) != len(values)
+	})
+	if len(specs) != 1 {
+		return fmt.Errorf("%w: %s has %d composite indexes of %d fields", ErrNotIndexed, elemType.Name(), len(specs), len(values))
+	}
+
+	keys := make([]string, len(values))
+	for i, value := range values {
+		keys[i] = keyFor(value)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err = db.readIndexValue(typeName, specs[0], compositeValue(keys))
+	if err != nil {
+		return err
+	}
+
+	loaded, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return fmt.Errorf("unable to load entities indexed by %s: %w", specs[0].name, err)
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(loaded))
+	for _, v := range loaded {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
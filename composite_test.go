@@ -0,0 +1,60 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+)
+
+type compositeItem struct {
+	ID     int
+	Status string
+	Region string
+}
+
+func TestCompositeIndex(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCompositeIndex("compositeItem", "Status", "Region"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		compositeItem{ID: 1, Status: "open", Region: "eu"},
+		compositeItem{ID: 2, Status: "open", Region: "us"},
+		compositeItem{ID: 3, Status: "closed", Region: "eu"},
+		// Values which would collide if simply concatenated.
+		compositeItem{ID: 4, Status: "open+eu", Region: ""},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []compositeItem
+	err = db.GetByComposite(&items, "open", "eu")
+	if err != nil || len(items) != 1 || items[0].ID != 1 {
+		t.Fatalf("got %+v, %v, want entity 1", items, err)
+	}
+
+	// Updating and deleting entities reindexes them.
+	err = db.Put(compositeItem{ID: 2, Status: "open", Region: "eu"})
+	if err == nil {
+		err = db.Delete(compositeItem{}, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByComposite(&items, "open", "eu")
+	if err != nil || len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("got %+v, %v, want entity 2", items, err)
+	}
+
+	err = db.GetByComposite(&items, "open", "us")
+	if err != nil || len(items) != 0 {
+		t.Fatalf("got %+v, %v, want none", items, err)
+	}
+
+	err = db.GetByComposite(&items, "open")
+	if !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("got %v, want %v", err, ErrNotIndexed)
+	}
+}
@@ -0,0 +1,177 @@
+package burrowdb
+
+import (
+	"cmp"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config is a snapshot of the settings a db was opened with, for diagnosing
+// misconfiguration. Secrets such as the encryption key aren't included.
+type Config struct {
+	Dir                string              // Directory where entities are stored.
+	Codec              string              // Name of the codec used to encode entities.
+	TypeCodecs         map[string]string   // Names of the codecs overriding Codec keyed by type.
+	KnownCodecs        []string            // Names of the codecs passed to WithKnownCodecs.
+	JSONOptions        JSONOptions         // Settings used when encoding and decoding JSON.
+	DisallowUnknown    bool                // Whether JSON members not matching a field fail to decode.
+	SortOrder          SortOrder           // Order in which scans visit entities.
+	FieldDefaults      []string            // Types with field defaults, in ascending order.
+	Defaulters         []string            // Types with defaulters, in ascending order.
+	Schemas            []string            // Types with schemas, in ascending order.
+	ChangeLog          bool                // Whether mutations are recorded in the change log.
+	TempDir            string              // Directory for temp files, or "" for the target's directory.
+	KeyFilename        bool                // Whether IDs are formatted as filenames by WithKeyFilename.
+	IDFieldNames       []string            // Names of the fields which may hold IDs.
+	KeyNormalizer      bool                // Whether a key normalizer was given.
+	KeyWidth           int                 // Width integer keys are zero-padded to, or 0 for none.
+	FileExtension      string              // Extension of entity files, or "" for none.
+	Parallelism        int                 // Maximum number of entities scans load concurrently.
+	DirReadBatch       int                 // Number of entries type dirs are read at a time, or 0 for all at once.
+	Seed               bool                // Whether a seed function was given.
+	Encryption         bool                // Whether an encryption key was given.
+	EncryptedKeys      bool                // Whether entity filenames are derived from IDs with an HMAC.
+	NoCreate           bool                // Whether directories must already exist.
+	WAL                bool                // Whether mutations are recorded in a write-ahead log.
+	Mmap               bool                // Whether large entities are memory mapped when decoded.
+	MmapMinSize        int64               // Size from which entities are memory mapped.
+	ContentAddressing  bool                // Whether entity data is stored under its hash.
+	FileHeader         bool                // Whether entity files start with a magic header.
+	QualifiedTypeNames bool                // Whether types are stored under their package path.
+	Layout             Layout              // Decides where entity files are stored.
+	ProcessLock        bool                // Whether other processes are prevented from using Dir.
+	StrictTags         bool                // Whether Put rejects unknown struct tag options.
+	RejectZeroID       bool                // Whether Put rejects values with a zero ID field.
+	NoFollowSymlinks   bool                // Whether entities reached through symlinks are refused.
+	Retention          []string            // Types with retention policies, in ascending order.
+	AppendLogs         []string            // Types stored in an append log, in ascending order.
+	TimePartitions     []string            // Types partitioned by date, in ascending order.
+	VersionHistory     map[string]int      // Number of versions of entities kept keyed by type.
+	DeleteHistory      []string            // Types whose deleted entities are archived, in ascending order.
+	CompositeIndexes   map[string][]string // Names of the composite indexes of each type keyed by type.
+	WarmIndexes        []string            // Types whose indexes are warmed by NewDB, in ascending order.
+	IDGenerator        bool                // Whether an ID generator was given.
+	Loader             bool                // Whether a loader was given.
+	RawWriteHook       bool                // Whether a raw write hook was given.
+	RawReadHook        bool                // Whether a raw read hook was given.
+	FileMode           os.FileMode         // Permissions files are created with.
+	DirMode            os.FileMode         // Permissions directories are created with.
+	OverwriteWindow    time.Duration       // Time after an entity is written during which it can't be overwritten.
+	ErrorHandler       bool                // Whether an error handler was given.
+	CaseFoldedTypes    bool                // Whether type dirs are named in lower case.
+	MaxStoreSize       int64               // Maximum total size of the entities in bytes, or 0 for no limit.
+	MaxEntities        map[string]int      // Maximum number of entities keyed by type.
+	SweepInterval      time.Duration       // Time between sweeps of expired entities, or 0 for none.
+	SweepBatchSize     int                 // Maximum number of entities deleted from each type per sweep.
+	ReplicaRefresh     time.Duration       // Time between refreshes of a read-only replica, or 0 if it isn't one.
+	CoalesceWindow     time.Duration       // Time puts are held for before being written, or 0 for none.
+	SyncEvery          int                 // Number of writes after which files are synced, or 0 for no limit.
+	SyncInterval       time.Duration       // Time after a write by which files are synced, or 0 for no limit.
+	NoDirSync          bool                // Whether the directories of synced files are left unsynced.
+	MaxOpenFiles       int                 // Maximum number of files open at once, or 0 for no limit.
+	OpenFilesPolicy    OpenFilesPolicy     // What operations do when MaxOpenFiles files are open.
+}
+
+// Config returns the settings the db was opened with.
+func (db *BurrowDB) Config() Config {
+	typeCodecs := make(map[string]string, len(db.typeCodecs))
+	for typeName, codec := range db.typeCodecs {
+		typeCodecs[typeName] = codec.Name()
+	}
+
+	knownCodecs := make([]string, len(db.knownCodecs))
+	for i, codec := range db.knownCodecs {
+		knownCodecs[i] = codec.Name()
+	}
+
+	compositeIndexes := make(map[string][]string, len(db.compositeIndexes))
+	for typeName, indexes := range db.compositeIndexes {
+		for _, fields := range indexes {
+			compositeIndexes[typeName] = append(compositeIndexes[typeName], strings.Join(fields, compositeSeparator))
+		}
+	}
+
+	var fieldDefaults []string
+	for _type := range db.fieldDefaults {
+		fieldDefaults = append(fieldDefaults, db.typeName(_type))
+	}
+	slices.Sort(fieldDefaults)
+
+	var warmIndexes []string
+	for _, _type := range db.warmTypes {
+		warmIndexes = append(warmIndexes, db.typeName(_type))
+	}
+	slices.Sort(warmIndexes)
+
+	layout := db.layout
+	if layout == nil {
+		layout = Flat{}
+	}
+
+	return Config{
+		Dir:                db.dir,
+		Codec:              db.codec.Name(),
+		TypeCodecs:         typeCodecs,
+		KnownCodecs:        knownCodecs,
+		JSONOptions:        db.jsonOpts,
+		DisallowUnknown:    db.disallowUnknownFields,
+		SortOrder:          db.sortOrder,
+		FieldDefaults:      fieldDefaults,
+		Defaulters:         slices.Sorted(maps.Keys(db.defaulters)),
+		Schemas:            slices.Sorted(maps.Keys(db.schemas)),
+		ChangeLog:          db.changeLog,
+		TempDir:            db.tempDir,
+		KeyFilename:        db.keyFormat != nil,
+		IDFieldNames:       slices.Clone(db.idNames()),
+		KeyNormalizer:      db.keyNormalizer != nil,
+		KeyWidth:           db.keyWidth,
+		FileExtension:      db.fileExt,
+		Parallelism:        max(db.parallelism, 1),
+		DirReadBatch:       db.dirReadBatch,
+		Seed:               db.seed != nil,
+		Encryption:         db.aead != nil,
+		EncryptedKeys:      db.encryptKeys,
+		NoCreate:           db.noCreate,
+		WAL:                db.wal,
+		Mmap:               db.mmap,
+		MmapMinSize:        db.mmapMinSize,
+		ContentAddressing:  db.contentAddressing,
+		FileHeader:         db.fileHeader,
+		QualifiedTypeNames: db.qualifiedTypeNames,
+		Layout:             layout,
+		ProcessLock:        db.processLock,
+		StrictTags:         db.strictTags,
+		RejectZeroID:       db.rejectZeroID,
+		NoFollowSymlinks:   db.noFollowSymlinks,
+		Retention:          slices.Sorted(maps.Keys(db.retention)),
+		AppendLogs:         slices.Sorted(maps.Keys(db.appendLogs)),
+		TimePartitions:     slices.Sorted(maps.Keys(db.partitions)),
+		VersionHistory:     maps.Clone(db.history),
+		DeleteHistory:      slices.Sorted(maps.Keys(db.deleteHistory)),
+		CompositeIndexes:   compositeIndexes,
+		WarmIndexes:        warmIndexes,
+		IDGenerator:        db.idGenerator != nil,
+		Loader:             db.loader != nil,
+		RawWriteHook:       db.rawWriteHook != nil,
+		RawReadHook:        db.rawReadHook != nil,
+		FileMode:           db.filePerm(),
+		DirMode:            db.dirPerm(),
+		OverwriteWindow:    db.overwriteWindow,
+		ErrorHandler:       db.errorHandler != nil,
+		CaseFoldedTypes:    db.foldTypeCase,
+		MaxStoreSize:       db.maxStoreSize,
+		MaxEntities:        maps.Clone(db.maxEntities),
+		SweepInterval:      db.sweepInterval,
+		SweepBatchSize:     cmp.Or(db.sweepBatchSize, defaultSweepBatchSize),
+		ReplicaRefresh:     db.replicaRefresh,
+		CoalesceWindow:     db.coalesceWindow,
+		SyncEvery:          db.syncEvery,
+		SyncInterval:       db.syncInterval,
+		NoDirSync:          db.noDirSync,
+		MaxOpenFiles:       cap(db.openFiles),
+		OpenFilesPolicy:    db.openFilesPolicy,
+	}
+}
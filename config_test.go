@@ -0,0 +1,46 @@
+package burrowdb
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(
+		WithDir(dir),
+		WithCodec(GobCodec),
+		WithWAL(),
+		WithParallelism(4),
+		WithEncryption(make([]byte, 16)),
+		WithSortOrder(Descending),
+		WithSchema("b", []byte(`{}`)),
+		WithSchema("a", []byte(`{}`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := db.Config()
+	if c.Dir != dir || c.Codec != "gob" || !c.WAL || c.Parallelism != 4 || !c.Encryption || c.SortOrder != Descending {
+		t.Fatalf("got %+v, want the options given", c)
+	}
+	if !slices.Equal(c.Schemas, []string{"a", "b"}) {
+		t.Fatalf("got schemas %v, want [a b]", c.Schemas)
+	}
+	if c.ChangeLog || c.ContentAddressing || c.TempDir != "" {
+		t.Fatalf("got %+v, want the options not given left unset", c)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := db.Config()
+	if c.Codec != "json" || c.Parallelism != 1 || c.SortOrder != Ascending {
+		t.Fatalf("got %+v, want the defaults", c)
+	}
+}
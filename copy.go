@@ -0,0 +1,166 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+// CopyTo copies every file in the db dir to the passed dir, which must not
+// exist or be empty, and returns a db using the copy with the same settings as
+// this one. Every type is read locked for the duration of the copy so that it
+// is consistent across types. Temp files, locks and the write-ahead log aren't
+// copied, and the two stores are independent afterwards.
+func (db *BurrowDB) CopyTo(dir string) (_ *BurrowDB, err error) {
+	defer db.handleError("CopyTo", &err)
+
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("directory (%q) is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read directory (%q): %w", dir, err)
+	}
+
+	src, err := filepath.Abs(db.dir)
+	if err == nil {
+		var dst string
+		dst, err = filepath.Abs(dir)
+		if err == nil {
+			dst, err = filepath.Rel(src, dst)
+		}
+		if err == nil && filepath.IsLocal(dst) {
+			return nil, fmt.Errorf("directory (%q) is inside the db dir", dir)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path: %w", err)
+	}
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure every stored type has a lock for rLockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	unlock := db.locks.rLockAll()
+	err = db.copyFiles(dir, false)
+	unlock()
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy db: %w", err)
+	}
+
+	return NewDB(func(clone *BurrowDB) error {
+		*clone = *db
+		clone.dir = dir
+		clone.typeCodecs = maps.Clone(db.typeCodecs)
+		clone.locks = nil
+		clone.lockFile = nil
+		clone.sweepStop, clone.sweepDone = nil, nil
+		clone.refreshStop, clone.refreshDone = nil, nil
+		if db.coalesce != nil {
+			clone.coalesce = &coalescer{}
+		}
+		if db.syncer != nil {
+			clone.syncer = &syncer{}
+		}
+		clone.isNew = false
+		return nil
+	})
+}
+
+// openDetached returns a db using the passed dir, holding a copy of the store,
+// with the same settings as this one but without a process lock, seed, sweeps,
+// replica refreshes, coalescing, syncing or error handler, so that it can be
+// read without side effects.
+func (db *BurrowDB) openDetached(dir string) (*BurrowDB, error) {
+	return NewDB(func(clone *BurrowDB) error {
+		*clone = *db
+		clone.dir = dir
+		clone.typeCodecs = maps.Clone(db.typeCodecs)
+		clone.locks = nil
+		clone.lockFile = nil
+		clone.processLock = false
+		clone.tempDir = ""
+		clone.noCreate = false
+		clone.seed = nil
+		clone.sweepInterval = 0
+		clone.sweepStop, clone.sweepDone = nil, nil
+		clone.replicaRefresh = 0
+		clone.refreshStop, clone.refreshDone = nil, nil
+		clone.coalesce, clone.coalesceWindow = nil, 0
+		clone.syncer, clone.syncEvery, clone.syncInterval = nil, 0, 0
+		clone.errorHandler = nil
+		clone.isNew = false
+		return nil
+	})
+}
+
+// copyFiles copies every file in the db dir which would be backed up to the
+// passed dir, keeping modification times. If link is true, files which are only
+// ever replaced rather than modified in place are hard linked rather than
+// copied where possible. Every lock must be held.
+func (db *BurrowDB) copyFiles(dir string, link bool) error {
+	return filepath.WalkDir(db.dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(db.dir, filename)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+
+		if entry.IsDir() {
+			if rel != "." && (skipBackupDir(entry.Name(), backupConfig{}) || filename == filepath.Clean(db.tempDir)) {
+				return filepath.SkipDir
+			}
+
+			err = os.MkdirAll(target, db.dirPerm())
+			if err != nil {
+				return fmt.Errorf("unable to create %s: %w", rel, noSpace(err))
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || skipBackupFile(entry.Name(), backupConfig{}) {
+			return nil
+		}
+
+		// Append logs, key names files and the change log are appended to in
+		// place, so a link would see later writes.
+		name := entry.Name()
+		if link && name != appendLogFileName && name != keyNamesFileName && name != changeLogFileName {
+			if os.Link(filename, target) == nil {
+				return nil
+			}
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", rel, err)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to stat %s: %w", rel, err)
+		}
+
+		err = os.WriteFile(target, data, db.filePerm())
+		if err == nil {
+			err = os.Chtimes(target, info.ModTime(), info.ModTime())
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write %s: %w", rel, noSpace(err))
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type copyItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestCopyTo(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithProcessLock(), WithCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = db.Put(copyItem{ID: 1, Status: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := db.CopyTo(filepath.Join(t.TempDir(), "copy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if codec := c.Config().Codec; codec != "gob" {
+		t.Fatalf("got codec %s, want the settings copied", codec)
+	}
+
+	// The stores are independent after the copy.
+	err = c.Put(copyItem{ID: 2, Status: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []copyItem
+	err = db.GetAll(&items)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got %+v, %v in the original", items, err)
+	}
+
+	err = c.GetByField(&items, "Status", "a")
+	if err != nil || len(items) != 2 {
+		t.Fatalf("got %+v, %v in the copy, want both indexed", items, err)
+	}
+}
+
+func TestCopyToInvalidDir(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.CopyTo(filepath.Join(db.dir, "inside"))
+	if err == nil {
+		t.Fatal("got no error copying into the db dir")
+	}
+
+	full := t.TempDir()
+	err = os.WriteFile(filepath.Join(full, "file"), nil, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.CopyTo(full)
+	if err == nil {
+		t.Fatal("got no error copying into a dir which isn't empty")
+	}
+}
@@ -1,11 +1,20 @@
 package burrowdb
 
 import (
+	"crypto/cipher"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -14,16 +23,142 @@ var (
 	ErrMultipleIDFields = errors.New("value has multiple ID fields")
 	ErrNoSuchEntity     = errors.New("no such entity exists")
 	ErrNonPointerDst    = errors.New("dst is not a pointer")
+	ErrInvalidDstType   = errors.New("invalid dst type")
+	ErrKeyTypeMismatch  = errors.New("map key type does not match ID field")
+	ErrPathIsDirectory  = errors.New("entity path is a directory")
+	ErrNonIntegerKey    = errors.New("key is not an integer")
+	ErrStoreLocked      = errors.New("store is locked by another process")
+	ErrNotJSON          = errors.New("entity is not stored as JSON")
+	ErrTempDirDevice    = errors.New("temp dir is on a different device")
+	ErrNotIndexed       = errors.New("field is not indexed")
+	ErrUniqueViolation  = errors.New("unique field value already in use")
+	ErrSchemaViolation  = errors.New("value does not satisfy schema")
+	ErrNonIntegerID     = errors.New("ID field is not an integer")
+	ErrNoEncryptionKey  = errors.New("no encryption key has been given")
+	ErrMissingDir       = errors.New("directory does not exist")
+	ErrNoSpace          = errors.New("no space left on device")
+	ErrInvalidTag       = errors.New("invalid struct tag")
+	ErrTooSoon          = errors.New("entity was overwritten too soon")
+	ErrQuotaExceeded    = errors.New("store size quota exceeded")
+	ErrNilID            = errors.New("ID is a nil pointer")
+	ErrInvalidCursor    = errors.New("invalid scan cursor")
+	ErrUnchanged        = errors.New("value is unchanged")
+	ErrBadMagic         = errors.New("entity file has no valid header")
+	ErrCodecMismatch    = errors.New("entity is stored with a different codec")
+	ErrEntityLimit      = errors.New("type holds its maximum number of entities")
+	ErrTooManyOpenFiles = errors.New("too many files are open")
+	ErrZeroID           = errors.New("ID field is the zero value")
+	ErrSymlink          = errors.New("path is a symlink")
+	ErrReadOnly         = errors.New("db is read-only")
+	ErrEntityExists     = errors.New("entity already exists")
 )
 
 const (
 	structTagName = "burrowdb" // Struct tag key.
 	idFieldName   = "ID"       // Name required for a field or struct tag to specify ID field.
+	lockFileName  = ".lock"    // Name of the file in the db dir used for process locks.
+	pingTypeName  = ".ping"    // Reserved type used by Ping.
 )
 
 // BurrowDB is a database built for golang in golang.
 type BurrowDB struct {
-	dir string // directory where files will be stored.
+	dir         string           // directory where files will be stored.
+	codec       Codec            // codec used to encode entities.
+	typeCodecs  map[string]Codec // codecs overriding codec keyed by type.
+	knownCodecs []Codec          // codecs entities may have been stored with by WithCodecOption.
+	jsonOpts    JSONOptions      // settings used when encoding and decoding JSON.
+	locks       *lockSet         // locks shared by every BurrowDB using dir.
+
+	sortOrder     SortOrder                  // order in which scans visit entities.
+	defaulters    map[string]func(any) error // functions filling in values before they are stored keyed by type.
+	changeLog     bool                       // whether to record mutations in the change log.
+	tempDir       string                     // directory for temp files, or "" to use the target's directory.
+	keyFormat     func(id any) string        // formats IDs as filenames, or nil to use keyFor.
+	keyParse      func(name string) string   // recovers the formatted ID from a filename.
+	keyWidth      int                        // width integer keys are zero-padded to, or 0 for none.
+	fileExt       string                     // extension of entity files, or "" for none.
+	idFieldNames  []string                   // names of the fields which may hold IDs, or nil for idFieldName.
+	keyNormalizer func(string) string        // normalizes string IDs before they are used as keys, or nil.
+	isNew         bool                       // whether dir was created by NewDB.
+	schemas       map[string]*jsonSchema     // schemas which values must satisfy keyed by type.
+	parallelism   int                        // maximum number of entities scans load concurrently.
+	dirReadBatch  int                        // number of entries type dirs are read at a time, or 0 for all at once.
+	seed          func(*BurrowDB) error      // populates the store the first time it is opened, or nil.
+	aead          cipher.AEAD                // encrypts fields tagged for encryption, or nil.
+	filenameKey   []byte                     // key of the HMAC entity filenames are derived with by WithEncryptedKeys.
+	noCreate      bool                       // whether directories must already exist rather than be created.
+	wal           bool                       // whether to record mutations in a write-ahead log before making them.
+	mmap          bool                       // whether to memory map large entities when decoding them.
+	mmapMinSize   int64                      // size from which entities are memory mapped.
+
+	contentAddressing     bool // whether to store entity data under its hash.
+	fileHeader            bool // whether entity files start with a magic header.
+	qualifiedTypeNames    bool // whether to store types under their package path.
+	strictTags            bool // whether Put rejects unknown struct tag options.
+	disallowUnknownFields bool // whether JSON members not matching a field fail to decode.
+	rejectZeroID          bool // whether values with a zero ID field can't be put.
+	noFollowSymlinks      bool // whether entities reached through symlinks are refused.
+	encryptKeys           bool // whether entity filenames are derived from IDs with an HMAC.
+
+	fileMode os.FileMode // permissions of created files, or 0 for 0666.
+	dirMode  os.FileMode // permissions of created directories, or 0 for 0777.
+
+	retention  map[string]retention // limits on the entities kept keyed by type.
+	appendLogs map[string]bool      // types stored in an append log.
+	partitions map[string]string    // names of the time fields partitioning types keyed by type.
+
+	fieldDefaults map[reflect.Type]map[string]reflect.Value // defaults for missing fields keyed by type then field name.
+
+	history       map[string]int  // number of versions of entities kept keyed by type.
+	deleteHistory map[string]bool // types whose deleted entities are archived.
+
+	compositeIndexes map[string][][]string // fields of each composite index keyed by type.
+	warmTypes        []reflect.Type        // types whose indexes are warmed by NewDB.
+
+	idGenerator IDGenerator // generates the IDs given by Insert, or nil to use each type's sequence.
+
+	loader func(typeName string, id any) (any, bool, error) // loads entities missing from GetByID, or nil.
+
+	rawWriteHook func(typeName, key string, data []byte) // called with the stored bytes of each entity written, or nil.
+	rawReadHook  func(typeName, key string, data []byte) // called with the stored bytes of each entity read, or nil.
+
+	overwriteWindow time.Duration                    // time after an entity is written during which it can't be overwritten.
+	errorHandler    func(op string, err error) error // transforms the errors returned by methods, or nil.
+	foldTypeCase    bool                             // whether type dirs are named in lower case.
+	maxStoreSize    int64                            // maximum total size of the entities, or 0 for no limit.
+	maxEntities     map[string]int                   // maximum number of entities keyed by type.
+
+	syncEvery    int           // number of writes after which files are synced, or 0 for no limit.
+	syncInterval time.Duration // time after a write by which files are synced, or 0 for no limit.
+	syncer       *syncer       // files written since they were last synced, or nil if they aren't synced.
+	noDirSync    bool          // whether the directories of synced files are left for the OS to flush.
+
+	coalesceWindow time.Duration // time puts are held for before being written, or 0 to write at once.
+	coalesce       *coalescer    // values held by puts within the coalesce window, or nil.
+
+	openFiles       chan struct{}   // holds a value for each open file, or nil if they aren't limited.
+	openFilesPolicy OpenFilesPolicy // what operations do when openFiles is full.
+
+	sweepInterval  time.Duration // time between sweeps of expired entities, or 0 for none.
+	sweepBatchSize int           // maximum number of entities deleted from each type per sweep.
+	sweepStop      chan struct{} // closed to stop the sweeper, or nil if it isn't running.
+	sweepDone      chan struct{} // closed once the sweeper has stopped.
+
+	replicaRefresh time.Duration // time between refreshes of a read-only replica, or 0 if it isn't one.
+	refreshStop    chan struct{} // closed to stop the refresher, or nil if it isn't running.
+	refreshDone    chan struct{} // closed once the refresher has stopped.
+
+	layout Layout // decides where entity files are stored, or nil for Flat.
+
+	processLock bool     // whether to hold a lock preventing other processes using dir.
+	lockFile    *os.File // file holding the process lock, if any.
+}
+
+// JSONOptions changes how values are encoded to and decoded from JSON. The
+// zero value matches the behaviour of json.Marshal and json.Unmarshal.
+type JSONOptions struct {
+	DisableHTMLEscape bool // Store <, > and & as-is rather than as \u escapes.
+	UseNumber         bool // Decode numbers into interface values as json.Number rather than float64.
 }
 
 // newDBOption is an option which can be passed to NewDB to change the behaviour
@@ -38,12 +173,70 @@ func WithDir(dir string) newDBOption {
 	}
 }
 
+// WithCodec specifies the codec used to encode entities. By default entities
+// are encoded as JSON.
+func WithCodec(codec Codec) newDBOption {
+	return func(db *BurrowDB) error {
+		db.codec = codec
+		return nil
+	}
+}
+
+// WithGobTypes registers the concrete types of the passed values with
+// encoding/gob so that GobCodec can encode and decode them when they are held
+// in interface fields.
+func WithGobTypes(vs ...any) newDBOption {
+	return func(db *BurrowDB) (err error) {
+		// gob.Register panics when a name is registered to more than one type.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("unable to register gob type: %v", r)
+			}
+		}()
+
+		for _, v := range vs {
+			gob.Register(v)
+		}
+		return nil
+	}
+}
+
+// WithJSONOptions specifies the settings used when encoding and decoding
+// entities as JSON.
+func WithJSONOptions(opts JSONOptions) newDBOption {
+	return func(db *BurrowDB) error {
+		db.jsonOpts = opts
+		return nil
+	}
+}
+
+// WithDisallowUnknownFields specifies that entities stored as JSON should fail
+// to decode if they have a member which doesn't match a field of their struct,
+// such as one which has been renamed or removed, rather than the member being
+// ignored. The error names the unknown member.
+func WithDisallowUnknownFields() newDBOption {
+	return func(db *BurrowDB) error {
+		db.disallowUnknownFields = true
+		return nil
+	}
+}
+
+// WithProcessLock specifies that the db should take an advisory lock on the
+// directory, held until Close, so that only one process can use the store at a
+// time. NewDB will return ErrStoreLocked if another process holds the lock.
+func WithProcessLock() newDBOption {
+	return func(db *BurrowDB) error {
+		db.processLock = true
+		return nil
+	}
+}
+
 // NewDB returns a new BurrowDB instance with the passed options.
 //
 // If no directory or target is passed, the db will default to using
 // a localstore at ./burrow.
 func NewDB(opts ...newDBOption) (*BurrowDB, error) {
-	db := &BurrowDB{}
+	db := &BurrowDB{codec: JSONCodec}
 	for _, opt := range opts {
 		err := opt(db)
 		if err != nil {
@@ -51,99 +244,1595 @@ func NewDB(opts ...newDBOption) (*BurrowDB, error) {
 		}
 	}
 
+	if db.encryptKeys && db.aead == nil {
+		return nil, fmt.Errorf("%w: keys can't be encrypted", ErrNoEncryptionKey)
+	}
+
+	db.codec = db.applyJSONOptions(db.codec)
+	for typeName, codec := range db.typeCodecs {
+		db.typeCodecs[typeName] = db.applyJSONOptions(codec)
+	}
+
 	if db.dir == "" {
 		db.dir = "burrow"
 	}
 
-	err := os.MkdirAll(db.dir, 0777)
+	_, err := os.Stat(db.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		db.isNew = true
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to stat directory (%q): %v", db.dir, err)
+	}
+
+	err = db.mkdirAll(db.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create directory (%q): %w", db.dir, err)
+	}
+
+	err = db.checkTempDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// The case probe is a write, so replicas keep the configured folding.
+	if !db.foldTypeCase && !db.isReplica() {
+		db.foldTypeCase, err = isCaseInsensitive(db.dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db.locks, err = locksFor(db.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get locks for directory (%q): %v", db.dir, err)
+	}
+
+	if db.processLock {
+		db.lockFile, err = os.OpenFile(fmt.Sprintf("%s/%s", db.dir, lockFileName), os.O_CREATE|os.O_RDWR, db.filePerm())
+		if err != nil {
+			return nil, fmt.Errorf("unable to open lock file: %w", err)
+		}
+
+		err = lockFile(db.lockFile)
+		if err != nil {
+			db.lockFile.Close()
+			return nil, fmt.Errorf("unable to acquire process lock: %w", err)
+		}
+	}
+
+	if db.wal && !db.isReplica() {
+		err = db.replayWAL()
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("unable to recover from write-ahead log: %w", err)
+		}
+	}
+
+	err = db.loadAppendLogs()
 	if err != nil {
-		return nil, fmt.Errorf("unable to create directory (%q): %v", db.dir, err)
+		db.Close()
+		return nil, err
+	}
+
+	if db.seed != nil && !db.isReplica() {
+		err = db.runSeed()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	for _, _type := range db.warmTypes {
+		err = db.warmIndexes(_type)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("unable to warm indexes of %s: %w", _type, err)
+		}
 	}
 
+	db.startSweeper()
+	db.startRefresher()
+
 	return db, nil
 }
 
+// IsNew reports whether the db dir was created when this db was opened, rather
+// than already existing. This can be used to seed a brand new store.
+func (db *BurrowDB) IsNew() bool {
+	return db.isNew
+}
+
+// Close releases any resources held by the db. The db should not be used after
+// it has been closed.
+func (db *BurrowDB) Close() (err error) {
+	defer db.handleError("Close", &err)
+
+	db.stopSweeper()
+	db.stopRefresher()
+	err = db.flush()
+	if err != nil {
+		return err
+	}
+
+	if db.lockFile == nil {
+		return nil
+	}
+
+	err = unlockFile(db.lockFile)
+	if err != nil {
+		return fmt.Errorf("unable to release process lock: %w", err)
+	}
+
+	err = db.lockFile.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close lock file: %w", err)
+	}
+	db.lockFile = nil
+
+	return nil
+}
+
+// putOption is an option which can be passed to Put to change how a single
+// entity is stored.
+type putOption func(*putConfig)
+
+// putConfig holds the settings of a single Put.
+type putConfig struct {
+	codec Codec // codec overriding the type's codec, or nil.
+	id    any   // ID overriding the value of the ID field, if hasID.
+	hasID bool
+
+	skipIndexes bool // Whether to leave the type's indexes unchanged.
+}
+
 // Put takes a value and puts it into the db. This will overwrite any existing
 // object with the same ID.
 //
 // The value must be a struct type. To specify the ID field for the object, the
-// field should either be called ID or the struct tag should be `burrowdb: "ID"`
-func (db *BurrowDB) Put(v any) error {
+// field should either be called ID, or a name passed to WithIDFieldNames, or the
+// struct tag should be `burrowdb: "ID"`
+//
+// Fields tagged `burrowdb:"index"` or `burrowdb:"unique"` are indexed so that
+// entities can be found by their value with GetByField. Fields tagged
+// `burrowdb:"encrypt"` are stored encrypted with the key passed to
+// WithEncryption. Byte slice fields tagged `burrowdb:"binary"` are stored as raw
+// bytes after the JSON rather than as base64 within it, and are restored when
+// the entity is read. Fields tagged `burrowdb:"name=..."` are stored under the
+// given name rather than their JSON name, so the names used on disk can differ
+// from those used by json tags elsewhere.
+//
+// Options such as WithCodecOption change how this entity alone is stored.
+func (db *BurrowDB) Put(v any, opts ...putOption) (err error) {
+	defer db.handleError("Put", &err)
+
 	_type := reflect.TypeOf(v)
 	if _type.Kind() != reflect.Struct {
 		return ErrInvalidValueType
 	}
 
-	fields := reflect.VisibleFields(_type)
-	var idField *reflect.StructField
-	for _, field := range fields {
-		if field.Name == idFieldName {
-			if idField != nil {
-				return ErrMultipleIDFields
-			}
-			idField = &field
-			continue
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	if db.coalesceWindow > 0 {
+		return db.holdPut(v, opts...)
+	}
+
+	return db.put(v, opts...)
+}
+
+// PutWithID puts the passed struct value into the db under the passed ID,
+// whatever the value of its ID field, so that it can be got by GetByID with
+// that ID. The value doesn't need an ID field. This will overwrite any
+// existing object with the same ID.
+func (db *BurrowDB) PutWithID(v any, id any) (err error) {
+	defer db.handleError("PutWithID", &err)
+
+	_type := reflect.TypeOf(v)
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	return db.put(v, func(c *putConfig) {
+		c.id = id
+		c.hasID = true
+	})
+}
+
+// put puts the passed struct value into the db. The lock of the value's type
+// must be held.
+func (db *BurrowDB) put(v any, opts ...putOption) error {
+	var cfg putConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	v, err := db.applyDefaulter(v)
+	if err != nil {
+		return err
+	}
+
+	enc, err := db.encode(v, cfg)
+	if err != nil {
+		return err
+	}
+
+	if db.isAppendLog(enc.typeName) && enc.codec.Name() != JSONCodec.Name() {
+		return fmt.Errorf("%s is stored in an append log and can't be written with %s", enc.typeName, enc.codec.Name())
+	}
+
+	err = db.checkOverwriteWindow(enc.typeName, enc.key)
+	if err == nil {
+		err = db.checkSymlinks(db.entityPath(enc.typeName, enc.key))
+	}
+	if err != nil {
+		return err
+	}
+
+	placed, err := db.placeEntity(reflect.ValueOf(v), enc.key)
+	if err != nil {
+		return err
+	}
+
+	_type := reflect.TypeOf(v)
+	var indexes map[string]index
+	if !cfg.skipIndexes {
+		indexes, err = db.indexUpdates(_type, enc.key, reflect.ValueOf(v))
+		if err != nil {
+			return err
 		}
+	}
 
-		if field.Tag.Get(structTagName) == idFieldName {
-			if idField != nil {
-				return ErrMultipleIDFields
-			}
-			idField = &field
+	err = db.archiveVersion(enc.typeName, enc.key)
+	if err == nil && enc.override == nil {
+		err = db.recordCodec(enc.typeName, enc.codec)
+	}
+	if err == nil {
+		err = db.recordEntityCodec(enc.typeName, enc.key, enc.override)
+	}
+	if err == nil {
+		err = db.recordTypeSchema(_type)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = db.writeEntity(enc.typeName, enc.key, enc.data)
+	err = errors.Join(err, placed(err == nil))
+	if err != nil {
+		return err
+	}
+
+	err = db.writeIndexes(_type, indexes)
+	if err != nil {
+		return err
+	}
+
+	return db.applyRetention(_type)
+}
+
+// encoded is a struct value encoded by encode, ready to be written.
+type encoded struct {
+	typeName string
+	key      string
+	codec    Codec  // codec the data was encoded with.
+	override Codec  // codec overriding the type's codec, or nil.
+	data     []byte // encoded value.
+}
+
+// encode encodes the passed struct value for storage with the passed settings,
+// validating it against its type's schema, encrypting its encrypted fields,
+// renaming its renamed fields and packing its binary fields. Values encoded by
+// their BurrowMarshaler skip every step relying on the tags of their fields.
+func (db *BurrowDB) encode(v any, cfg putConfig) (encoded, error) {
+	_type := reflect.TypeOf(v)
+	m, isMarshaler := v.(BurrowMarshaler)
+	if !isMarshaler {
+		err := db.checkTags(_type)
+		if err != nil {
+			return encoded{}, err
 		}
 	}
 
-	if idField == nil {
-		return ErrNoIDField
+	if isMarshaler && !cfg.hasID {
+		cfg.id = m.Key()
+	} else if !cfg.hasID {
+		idField, err := db.findIDField(_type)
+		if err != nil {
+			return encoded{}, err
+		}
+		id := reflect.ValueOf(v).FieldByIndex(idField.Index)
+		if db.rejectZeroID && id.IsZero() {
+			return encoded{}, fmt.Errorf("%w: %s of %s isn't set", ErrZeroID, idField.Name, _type)
+		}
+		cfg.id = id.Interface()
 	}
 
-	// Marshal into JSON.
-	data, err := json.Marshal(v)
+	typeName := db.typeName(_type)
+	key, err := db.putKey(typeName, cfg.id)
 	if err != nil {
-		return fmt.Errorf("unable to marshal value: %v", err)
+		return encoded{}, err
+	}
+
+	// Marshal using the type's codec unless it is overridden.
+	codec := db.codecFor(typeName)
+	if isMarshaler {
+		codec = MarshalerCodec
 	}
 
-	_v := reflect.ValueOf(v)
+	if cfg.codec != nil && cfg.codec.Name() == codec.Name() {
+		cfg.codec = nil
+	} else if cfg.codec != nil {
+		codec = cfg.codec
+	}
 
-	// Write File.
-	typeDir := fmt.Sprintf("%s/%s", db.dir, _type.Name())
-	err = os.MkdirAll(typeDir, 0777)
+	data, err := codec.Marshal(v)
 	if err != nil {
-		return fmt.Errorf("unable to create type dir: %w", err)
+		return encoded{}, fmt.Errorf("unable to marshal value: %v", err)
 	}
 
-	filename := fmt.Sprintf("%s/%v", typeDir, _v.FieldByName(idField.Name))
-	err = os.WriteFile(filename, data, 0666)
+	err = db.validateSchema(typeName, codec, v, data)
 	if err != nil {
-		return fmt.Errorf("unable to write file: %w", err)
+		return encoded{}, err
 	}
 
-	return nil
+	if codec.Name() != MarshalerCodec.Name() {
+		data, err = db.encryptFields(_type, codec, data)
+		if err != nil {
+			return encoded{}, err
+		}
+
+		data, err = db.renameFields(_type, codec, data)
+		if err != nil {
+			return encoded{}, err
+		}
+
+		data, err = db.packBinaryFields(_type, typeName, codec, data)
+		if err != nil {
+			return encoded{}, err
+		}
+	}
+
+	return encoded{
+		typeName: typeName,
+		key:      key,
+		codec:    codec,
+		override: cfg.codec,
+		data:     data,
+	}, nil
 }
 
 // GetByID gets the entity with the type of the passed destination with the
-// passed ID.
-func (db *BurrowDB) GetByID(dst any, id any) error {
+// passed ID. The dst must be a pointer to a struct. If there is no such entity
+// and the db uses WithLoader, it is loaded and stored first.
+func (db *BurrowDB) GetByID(dst any, id any) (err error) {
+	defer db.handleError("GetByID", &err)
+
 	// Make sure that the dst is a pointer type.
 	_type := reflect.TypeOf(dst)
 	if _type.Kind() != reflect.Pointer {
 		return ErrNonPointerDst
 	}
 
-	filename := fmt.Sprintf("%s/%s/%v", db.dir, _type.Elem().Name(), id)
+	// Only structs are stored, so any other type would be looked for under a
+	// meaningless type name.
+	if _type.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: dst must point to a struct, not %s", ErrInvalidDstType, _type.Elem())
+	}
 
-	data, err := os.ReadFile(filename)
-	if errors.Is(err, os.ErrNotExist) {
-		return ErrNoSuchEntity
-	} else if err != nil {
-		return fmt.Errorf("unable to get entity: %w", err)
+	return db.get(dst, db.typeName(_type.Elem()), id)
+}
+
+// get decodes the entity of the named type with the passed ID into dst, loading
+// it first if the db uses WithLoader, as GetByID does.
+func (db *BurrowDB) get(dst any, typeName string, id any) error {
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	err = db.getByID(dst, typeName, key)
+	if errors.Is(err, ErrNoSuchEntity) && db.loader != nil {
+		return db.readThrough(dst, typeName, id, key)
+	}
+
+	return err
+}
+
+// getByID decodes the entity of the named type with the passed key into dst,
+// holding the type's read lock.
+func (db *BurrowDB) getByID(dst any, typeName, key string) error {
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
 	}
+	if err != nil {
+		return err
+	}
+
+	return db.withEntity(typeName, key, func(data []byte) error {
+		return db.decode(codec, typeName, key, data, dst)
+	})
+}
 
-	err = json.Unmarshal(data, dst)
+// Get returns the entity of type T with the passed ID. It behaves like GetByID
+// but returns the entity rather than decoding it into a destination.
+func Get[T any](db *BurrowDB, id any) (T, error) {
+	var v T
+	err := db.GetByID(&v, id)
 	if err != nil {
-		return fmt.Errorf("unable to unmarshal data: %w", err)
+		var zero T
+		return zero, err
 	}
 
-	return nil
+	return v, nil
+}
+
+// Delete removes the entity with the type of dst and the passed ID. The dst may
+// be a struct or a pointer to one and is only used for its type.
+// ErrNoSuchEntity is returned if there is no such entity.
+func (db *BurrowDB) Delete(dst any, id any) (err error) {
+	defer db.handleError("Delete", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	return db.delete(_type, key)
+}
+
+// Delete removes the entity of type T with the passed ID. It behaves like the
+// Delete method but doesn't need a value of the type to be passed.
+// ErrNoSuchEntity is returned if there is no such entity.
+func Delete[T any](db *BurrowDB, id any) error {
+	return db.Delete((*T)(nil), id)
+}
+
+// DeleteAndGet decodes the entity with the type of the passed destination and
+// the passed ID into dst, then removes it. Nothing else can write the entity in
+// between. ErrNoSuchEntity is returned if there is no such entity.
+func (db *BurrowDB) DeleteAndGet(dst any, id any) (err error) {
+	defer db.handleError("DeleteAndGet", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(_type.Elem())
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	err = db.decode(codec, typeName, key, data, dst)
+	if err != nil {
+		return err
+	}
+
+	return db.delete(_type.Elem(), key)
+}
+
+// delete removes the entity of the passed type with the passed key and its
+// index entries. The lock of the type must be held.
+func (db *BurrowDB) delete(_type reflect.Type, key string) error {
+	typeName := db.typeName(_type)
+	if db.deleteHistory[typeName] {
+		err := db.archiveVersion(typeName, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := db.deleteEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	return db.removeFromIndexes(_type, key)
+}
+
+// Ping checks that the store is healthy by writing a sentinel record to a
+// reserved type, reading it back and deleting it.
+func (db *BurrowDB) Ping() (err error) {
+	defer db.handleError("Ping", &err)
+
+	mu := db.typeLock(pingTypeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	sent := time.Now().UnixNano()
+	data, err := db.codec.Marshal(sent)
+	if err != nil {
+		return fmt.Errorf("unable to marshal sentinel: %w", err)
+	}
+
+	err = db.writeEntity(pingTypeName, "sentinel", data)
+	if err != nil {
+		return err
+	}
+
+	data, err = db.readEntity(pingTypeName, "sentinel")
+	if err != nil {
+		return err
+	}
+
+	var got int64
+	err = db.codec.Unmarshal(data, &got)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal sentinel: %w", err)
+	}
+
+	if got != sent {
+		return fmt.Errorf("sentinel read back as %d, expected %d", got, sent)
+	}
+
+	return db.deleteEntity(pingTypeName, "sentinel")
+}
+
+// GetAll gets every entity with the element type of the slice pointed to by
+// dst, replacing the slice's contents. Entities are visited in the order set by
+// WithSortOrder.
+func (db *BurrowDB) GetAll(dst any) (err error) {
+	defer db.handleError("GetAll", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// GetAll returns every entity of type T. It behaves like the GetAll method but
+// returns the entities rather than decoding them into a destination.
+func GetAll[T any](db *BurrowDB) ([]T, error) {
+	var all []T
+	err := db.GetAll(&all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// GetAllExcept gets every entity with the element type of the slice pointed to
+// by dst, except those with the passed IDs, replacing the slice's contents as
+// GetAll does. The IDs are matched by the keys they are stored under, so 7 and
+// int64(7) exclude the same entity. IDs which aren't stored are ignored.
+func (db *BurrowDB) GetAllExcept(dst any, excludeIDs []any) (err error) {
+	defer db.handleError("GetAllExcept", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	excluded := make(map[string]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		key, err := db.idKey(id)
+		if err != nil {
+			return err
+		}
+		excluded[key] = true
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return excluded[key]
+	})
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// GetLast gets the n entities with the highest IDs and the element type of the
+// slice pointed to by dst, replacing the slice's contents. Entities are visited
+// from the highest ID down, comparing integer IDs numerically, whatever order is
+// set by WithSortOrder. Every entity is got if there are fewer than n.
+func (db *BurrowDB) GetLast(dst any, n int) (err error) {
+	defer db.handleError("GetLast", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	if n < 0 {
+		return fmt.Errorf("invalid number of entities %d", n)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+	sortKeys(keys, Descending, nil, db.compareKeys(typeName))
+
+	values, err := db.loadAll(elemType, keys[:min(n, len(keys))])
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// GetAllMap gets every entity with the value type of the passed destination
+// map and inserts each one keyed by its ID.
+//
+// The dst must be a pointer to a map whose values are structs and whose key
+// kind matches the kind of the struct's ID field. A nil map will be allocated.
+func (db *BurrowDB) GetAllMap(dst any) (err error) {
+	defer db.handleError("GetAllMap", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	mapType := _type.Elem()
+	if mapType.Kind() != reflect.Map {
+		return ErrInvalidDstType
+	}
+
+	elemType := mapType.Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	idField, err := db.findIDField(elemType)
+	if err != nil {
+		return err
+	}
+
+	if mapType.Key().Kind() != idField.Type.Kind() {
+		return ErrKeyTypeMismatch
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return err
+	}
+
+	m := reflect.ValueOf(dst).Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(mapType))
+	}
+
+	for _, v := range values {
+		id := v.Elem().FieldByIndex(idField.Index)
+		m.SetMapIndex(id.Convert(mapType.Key()), v.Elem())
+	}
+
+	return nil
+}
+
+// idNames returns the names of the fields which may hold the ID of an entity.
+func (db *BurrowDB) idNames() []string {
+	if len(db.idFieldNames) == 0 {
+		return []string{idFieldName}
+	}
+	return db.idFieldNames
+}
+
+// findIDField returns the field of the passed struct type which should be used
+// as the entity's ID. This is either the field named ID, or one of the names
+// passed to WithIDFieldNames, or the field with the struct tag `burrowdb:"ID"`.
+func (db *BurrowDB) findIDField(_type reflect.Type) (*reflect.StructField, error) {
+	names := db.idNames()
+	fields := reflect.VisibleFields(_type)
+	var idField *reflect.StructField
+	for _, field := range fields {
+		if slices.Contains(names, field.Name) {
+			if idField != nil {
+				return nil, ErrMultipleIDFields
+			}
+			idField = &field
+			continue
+		}
+
+		if opts, _ := parseTag(field); opts.id {
+			if idField != nil {
+				return nil, ErrMultipleIDFields
+			}
+			idField = &field
+		}
+	}
+
+	if idField == nil {
+		return nil, ErrNoIDField
+	}
+
+	return idField, nil
+}
+
+// IntKeys returns the keys of every entity with the type of dst, parsed as
+// integers and sorted in ascending order. An error is returned if any key is
+// not an integer.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) IntKeys(dst any) (_ []int64, err error) {
+	defer db.handleError("IntKeys", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return nil, ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		n, err := strconv.ParseInt(db.idText(typeName, key), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrNonIntegerKey, key)
+		}
+		ints = append(ints, n)
+	}
+	slices.Sort(ints)
+
+	return ints, nil
+}
+
+// KeySizes returns the size in bytes of the file of every entity with the type
+// of dst, keyed by the entity's key, without reading their contents. With
+// WithContentAddressing the size of the data an entity refers to is returned.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) KeySizes(dst any) (_ map[string]int64, err error) {
+	defer db.handleError("KeySizes", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return nil, ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		sizes[key], err = db.entitySize(typeName, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sizes, nil
+}
+
+// TypeSize returns the total size in bytes of the entities with the type of
+// dst, as reported by KeySizes. Derived data such as indexes and codec markers
+// isn't included.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) TypeSize(dst any) (_ int64, err error) {
+	defer db.handleError("TypeSize", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return 0, ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, key := range keys {
+		size, err := db.entitySize(typeName, key)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+
+	return total, nil
+}
+
+// entitySize returns the size in bytes of the entity of the named type with the
+// passed key, as described by KeySizes.
+func (db *BurrowDB) entitySize(typeName, key string) (int64, error) {
+	if db.isAppendLog(typeName) {
+		data, err := db.readLogEntity(typeName, key)
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(data)), nil
+	}
+
+	filename := db.entityPath(typeName, key)
+	if db.contentAddressing {
+		ref, err := os.ReadFile(filename)
+		if err != nil {
+			return 0, fmt.Errorf("unable to read entity: %w", err)
+		}
+
+		if hash, ok := blobHash(ref); ok {
+			filename = db.blobPath(hash)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, fmt.Errorf("unable to stat entity: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// PutList stores the whole of the passed slice as a single document of the
+// named type with the passed ID. This will overwrite any existing list with the
+// same ID.
+//
+// This suits small lists which are rewritten wholesale. Lists which are
+// updated an element at a time should store each element with Put.
+func (db *BurrowDB) PutList(typeName string, id any, slice any) (err error) {
+	defer db.handleError("PutList", &err)
+
+	if reflect.TypeOf(slice).Kind() != reflect.Slice {
+		return ErrInvalidValueType
+	}
+
+	codec := db.codecFor(typeName)
+	data, err := codec.Marshal(slice)
+	if err != nil {
+		return fmt.Errorf("unable to marshal value: %v", err)
+	}
+
+	err = db.validateSchema(typeName, codec, slice, data)
+	if err != nil {
+		return err
+	}
+
+	key, err := db.putKey(typeName, id)
+	if err != nil {
+		return err
+	}
+
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	err = db.recordCodec(typeName, codec)
+	if err != nil {
+		return err
+	}
+
+	return db.writeEntity(typeName, key, data)
+}
+
+// GetList gets the list of the named type with the passed ID, which was stored
+// with PutList, into the slice pointed to by dstSlice.
+func (db *BurrowDB) GetList(typeName string, id any, dstSlice any) (err error) {
+	defer db.handleError("GetList", &err)
+
+	_type := reflect.TypeOf(dstSlice)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	err = codec.Unmarshal(data, dstSlice)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal data: %w", err)
+	}
+
+	return nil
+}
+
+// WithQualifiedTypeNames specifies that entities should be stored under the
+// package path as well as the name of their type, in nested directories such
+// as github.com/user/project/User. This prevents types with the same name from
+// different packages sharing a directory.
+//
+// Options and methods which take a type name expect the qualified name when
+// this is used.
+func WithQualifiedTypeNames() newDBOption {
+	return func(db *BurrowDB) error {
+		db.qualifiedTypeNames = true
+		return nil
+	}
+}
+
+// typeName returns the name under which entities of the passed type are
+// stored.
+func (db *BurrowDB) typeName(_type reflect.Type) string {
+	if db.qualifiedTypeNames && _type.PkgPath() != "" {
+		return _type.PkgPath() + "/" + _type.Name()
+	}
+	return _type.Name()
+}
+
+// typeLock returns the lock guarding the entities of the named type.
+func (db *BurrowDB) typeLock(typeName string) *sync.RWMutex {
+	return db.locks.forType(db.typeDirName(typeName))
+}
+
+// typeDir returns the directory where entities of the named type are stored.
+func (db *BurrowDB) typeDir(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.dir, db.typeDirName(typeName))
+}
+
+// entityPath returns the path of the file storing the entity of the named
+// type with the passed key.
+func (db *BurrowDB) entityPath(typeName, key string) string {
+	if db.isPartitioned(typeName) {
+		return db.partitionedPath(typeName, key)
+	}
+
+	if db.layout != nil {
+		return db.layout.PathFor(db.dir, db.typeDirName(typeName), key+db.fileExt)
+	}
+	return fmt.Sprintf("%s/%s%s", db.typeDir(typeName), key, db.fileExt)
+}
+
+// keys returns the key of every entity of the named type in the db's sort
+// order. No keys are returned if nothing of the type has been stored.
+func (db *BurrowDB) keys(typeName string) ([]string, error) {
+	err := db.loadKeyNames(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.isAppendLog(typeName) {
+		keys, modTimes, err := db.logKeys(typeName)
+		if err != nil {
+			return nil, err
+		}
+		sortKeys(keys, db.sortOrder, modTimes, db.compareKeys(typeName))
+		return keys, nil
+	}
+
+	var keys []string
+	modTimes := map[string]time.Time{}
+	err = db.eachEntityFile(typeName, func(entry fs.DirEntry) error {
+		key, ok := db.fileKey(entry)
+		if !ok {
+			return nil
+		}
+
+		if db.sortOrder == Insertion {
+			info, err := entry.Info()
+			if err != nil {
+				return fmt.Errorf("unable to stat entity: %w", err)
+			}
+			modTimes[key] = info.ModTime()
+		}
+
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortKeys(keys, db.sortOrder, modTimes, db.compareKeys(typeName))
+
+	return keys, nil
+}
+
+// fileKey returns the key of the entity stored in the file with the passed
+// entry, or false if it isn't an entity file.
+func (db *BurrowDB) fileKey(entry fs.DirEntry) (string, bool) {
+	// Skip sub-directories and hidden files such as in-flight temp files.
+	if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+		return "", false
+	}
+
+	return strings.CutSuffix(entry.Name(), db.fileExt)
+}
+
+// eachEntityFile calls fn with the entry of each file in the dir of the named
+// type, and in its sub-directories if the db's layout uses them. Hidden
+// sub-directories are skipped.
+func (db *BurrowDB) eachEntityFile(typeName string, fn func(entry fs.DirEntry) error) error {
+	if db.isFlat() && !db.isPartitioned(typeName) {
+		if db.dirReadBatch > 0 {
+			return db.readDirBatched(db.typeDir(typeName), fn)
+		}
+
+		entries, err := os.ReadDir(db.typeDir(typeName))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read type dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			err = fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := filepath.WalkDir(db.typeDir(typeName), func(filename string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && filename == db.typeDir(typeName) {
+			return filepath.SkipAll
+		} else if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return fn(entry)
+		} else if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read type dir: %w", err)
+	}
+
+	return nil
+}
+
+// typeNames returns the name of every type with stored entities in ascending
+// order. Reserved types aren't included.
+func (db *BurrowDB) typeNames() ([]string, error) {
+	var names []string
+	addTypeDir := func(dir string) error {
+		rel, err := filepath.Rel(db.dir, dir)
+		if err != nil {
+			return err
+		}
+
+		typeName := filepath.ToSlash(rel)
+		if typeName != "." && !slices.Contains(names, typeName) {
+			names = append(names, typeName)
+		}
+		return nil
+	}
+
+	err := filepath.WalkDir(db.dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip hidden dirs such as indexes and reserved types.
+		if entry.IsDir() && filename != db.dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		// Entities of partitioned types are in sub-directories of the type dir.
+		if entry.IsDir() && db.isPartitionDir(filename) {
+			if err := addTypeDir(filename); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+
+		// Only type dirs hold codec markers. Entities in the sub-directories of
+		// other layouts belong to the type dir above them.
+		if entry.Name() == codecFileName && !db.isFlat() {
+			if err := addTypeDir(filepath.Dir(filename)); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+
+		// Types stored in an append log hold only hidden files.
+		if entry.Name() == appendLogFileName && entry.Type().IsRegular() {
+			return addTypeDir(filepath.Dir(filename))
+		}
+
+		if entry.IsDir() || !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !db.isFlat() {
+			return nil
+		}
+		return addTypeDir(filepath.Dir(filename))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read db dir: %w", err)
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// writeEntity writes data to the file of the entity of the named type with the
+// passed key, creating the type dir if it doesn't already exist. Entities of
+// types stored in an append log are appended to it instead. ErrQuotaExceeded is
+// returned if the write would take the store over the size set by
+// WithMaxStoreSize, and ErrEntityLimit if it would take the type over the
+// number of entities set by WithMaxEntities.
+func (db *BurrowDB) writeEntity(typeName, key string, data []byte) error {
+	err := db.reserveEntity(typeName, key, false)
+	if err != nil {
+		return err
+	}
+
+	err = db.reserveSize(typeName, key, int64(len(data)))
+	if err != nil {
+		db.forgetStoreSize()
+		return err
+	}
+
+	err = db.storeEntity(typeName, key, data)
+	if err != nil {
+		// The write may have partly happened, so the size is measured again.
+		db.forgetStoreSize()
+		return err
+	}
+
+	return db.countWrites(1)
+}
+
+// storeEntity writes data for the entity of the named type with the passed key,
+// as described by writeEntity, without accounting for the size of the store.
+func (db *BurrowDB) storeEntity(typeName, key string, data []byte) error {
+	db.dropHeld(typeName, key)
+
+	err := db.recordKeyName(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	if db.isAppendLog(typeName) {
+		err = db.putLogEntity(typeName, key, data)
+		if err == nil {
+			db.wroteRaw(typeName, key, data)
+		}
+		return err
+	}
+
+	filename := db.entityPath(typeName, key)
+	err = db.checkSymlinks(filename)
+	if err != nil {
+		return err
+	}
+
+	err = db.mkdirAll(filepath.Dir(filename))
+	if err != nil {
+		return fmt.Errorf("unable to create type dir: %w", err)
+	}
+
+	var old []byte
+	if db.contentAddressing {
+		old, err = os.ReadFile(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) && !isDir(filename) {
+			return fmt.Errorf("unable to read entity: %w", err)
+		}
+
+		data, err = db.storeBlob(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	data = db.addHeader(data)
+	err = db.writeFileAtomic(filename, data)
+	if err != nil {
+		if db.contentAddressing {
+			db.releaseBlob(data)
+		}
+		if isDir(filename) {
+			return fmt.Errorf("%w: %q conflicts with the entity file and must be removed", ErrPathIsDirectory, filename)
+		}
+		return err
+	}
+
+	if db.contentAddressing {
+		err = db.releaseBlob(old)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.logChange(typeName, key, OpPut)
+	if err != nil {
+		return err
+	}
+
+	db.wroteRaw(typeName, key, data)
+	return nil
+}
+
+// readEntity returns the contents of the file of the entity of the named type
+// with the passed key. ErrNoSuchEntity is returned if there is no such file.
+func (db *BurrowDB) readEntity(typeName, key string) ([]byte, error) {
+	if db.isAppendLog(typeName) {
+		data, err := db.readLogEntity(typeName, key)
+		if err == nil {
+			db.readRaw(typeName, key, data)
+		}
+		return data, err
+	}
+
+	filename := db.entityPath(typeName, key)
+	err := db.checkSymlinks(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := db.acquireFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filename)
+	release()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSuchEntity
+	} else if err != nil {
+		if isDir(filename) {
+			return nil, fmt.Errorf("%w: %q conflicts with the entity file and must be removed", ErrPathIsDirectory, filename)
+		}
+		return nil, fmt.Errorf("unable to get entity: %w", err)
+	}
+	db.readRaw(typeName, key, data)
+
+	data, err = db.stripHeader(typeName, key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.contentAddressing {
+		data, err = db.resolveBlob(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db.unpackBinaryFields(typeName, key, data)
+}
+
+// withEntity calls fn with the contents of the file of the entity of the named
+// type with the passed key, memory mapping it for the duration of the call if
+// the db uses WithMmap. ErrNoSuchEntity is returned if there is no such file.
+func (db *BurrowDB) withEntity(typeName, key string, fn func(data []byte) error) error {
+	if db.mmap && !db.contentAddressing && !db.isAppendLog(typeName) {
+		err := db.checkSymlinks(db.entityPath(typeName, key))
+		if err != nil {
+			return err
+		}
+
+		release, err := db.acquireFile()
+		if err != nil {
+			return err
+		}
+
+		data, unmap, ok := mmapFile(db.entityPath(typeName, key), db.mmapMinSize)
+		release()
+		if ok {
+			defer unmap()
+			db.readRaw(typeName, key, data)
+
+			data, err := db.stripHeader(typeName, key, data)
+			if err == nil {
+				data, err = db.unpackBinaryFields(typeName, key, data)
+			}
+			if err != nil {
+				return err
+			}
+			return fn(data)
+		}
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	return fn(data)
+}
+
+// isReservedType reports whether the named type is reserved for internal use.
+func isReservedType(typeName string) bool {
+	return strings.HasPrefix(typeName, ".")
+}
+
+// indirectType returns the type pointed to by the passed type, following any
+// number of pointers.
+func indirectType(_type reflect.Type) reflect.Type {
+	for _type.Kind() == reflect.Pointer {
+		_type = _type.Elem()
+	}
+	return _type
+}
+
+// decode decodes the data of the entity of the named type with the passed key
+// into dst with the passed codec, restoring the names of renamed fields,
+// decrypting encrypted fields and applying any field defaults registered for
+// the type.
+func (db *BurrowDB) decode(codec Codec, typeName, key string, data []byte, dst any) error {
+	// Values decoded by their BurrowUnmarshaler have no tagged fields to undo.
+	if codec.Name() != MarshalerCodec.Name() {
+		var err error
+		data, err = db.restoreFieldNames(reflect.TypeOf(dst).Elem(), codec, data)
+		if err != nil {
+			return fmt.Errorf("unable to rename fields of %s %q: %w", typeName, key, err)
+		}
+
+		data, err = db.decryptFields(reflect.TypeOf(dst).Elem(), codec, data)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt %s %q: %w", typeName, key, err)
+		}
+	}
+
+	err := codec.Unmarshal(data, dst)
+	if err != nil {
+		return unmarshalError(typeName, key, err)
+	}
+
+	err = db.applyFieldDefaults(codec, data, dst)
+	if err != nil {
+		return fmt.Errorf("unable to apply field defaults: %w", err)
+	}
+
+	return nil
+}
+
+// unmarshalError wraps the error of unmarshalling the entity of the named type
+// with the passed key, including the byte offset of the problem if the codec
+// reported one.
+func unmarshalError(typeName, key string, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("unable to unmarshal %s %q at byte offset %d: %w", typeName, key, syntaxErr.Offset, err)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("unable to unmarshal %s %q at byte offset %d: %w", typeName, key, typeErr.Offset, err)
+	}
+	return fmt.Errorf("unable to unmarshal %s %q: %w", typeName, key, err)
+}
+
+// deleteEntity removes the file of the entity of the named type with the passed
+// key, along with its metadata. ErrNoSuchEntity is returned if there is no such
+// file.
+func (db *BurrowDB) deleteEntity(typeName, key string) error {
+	err := db.checkWritable()
+	if err != nil {
+		return err
+	}
+	db.dropHeld(typeName, key)
+
+	err = db.reserveEntity(typeName, key, true)
+	if err != nil {
+		return err
+	}
+
+	err = db.reserveSize(typeName, key, 0)
+	if err != nil {
+		db.forgetStoreSize()
+		return err
+	}
+
+	err = db.removeEntity(typeName, key)
+	if err != nil {
+		db.forgetStoreSize()
+		return err
+	}
+
+	return db.removeMeta(typeName, key)
+}
+
+// removeEntity removes the entity of the named type with the passed key, as
+// described by deleteEntity, without accounting for the size of the store.
+func (db *BurrowDB) removeEntity(typeName, key string) error {
+	if db.isAppendLog(typeName) {
+		return db.deleteLogEntity(typeName, key)
+	}
+
+	filename := db.entityPath(typeName, key)
+	var ref []byte
+	if db.contentAddressing {
+		var err error
+		ref, err = os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoSuchEntity
+		} else if err != nil {
+			return fmt.Errorf("unable to read entity: %w", err)
+		}
+	}
+
+	var intent string
+	if db.wal {
+		var err error
+		intent, err = db.logIntent(OpDelete, filename, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := os.Remove(filename)
+	if intent != "" {
+		db.clearIntent(intent)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoSuchEntity
+	} else if err != nil {
+		return fmt.Errorf("unable to delete entity: %w", err)
+	}
+
+	err = db.releaseBlob(ref)
+	if err != nil {
+		return err
+	}
+
+	err = db.recordEntityCodec(typeName, key, nil)
+	if err != nil {
+		return err
+	}
+
+	return db.logChange(typeName, key, OpDelete)
+}
 
+// isDir reports whether a directory exists at the passed path.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
@@ -0,0 +1,754 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type dbItem struct {
+	Name string
+	Num  int64 `burrowdb:"ID"`
+}
+
+func TestGetAllMap(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		err = db.Put(dbItem{Name: "item", Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var items map[int64]dbItem
+	err = db.GetAllMap(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[2].Num != 2 {
+		t.Fatalf("got %v, want 3 items keyed by ID", items)
+	}
+
+	var badKeys map[string]dbItem
+	err = db.GetAllMap(&badKeys)
+	if !errors.Is(err, ErrKeyTypeMismatch) {
+		t.Fatalf("got %v, want %v", err, ErrKeyTypeMismatch)
+	}
+}
+
+func TestPathIsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.MkdirAll(filepath.Join(dir, "dbItem", "5"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbItem{Num: 5})
+	if !errors.Is(err, ErrPathIsDirectory) {
+		t.Fatalf("got %v putting, want %v", err, ErrPathIsDirectory)
+	}
+
+	err = db.GetByID(&dbItem{}, 5)
+	if !errors.Is(err, ErrPathIsDirectory) {
+		t.Fatalf("got %v getting, want %v", err, ErrPathIsDirectory)
+	}
+}
+
+type jsonItem struct {
+	ID   int
+	HTML string
+	Any  any
+}
+
+func TestJSONOptions(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithJSONOptions(JSONOptions{DisableHTMLEscape: true, UseNumber: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(jsonItem{ID: 1, HTML: "<a>&</a>", Any: int64(9007199254740993)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "jsonItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ID":1,"HTML":"<a>&</a>","Any":9007199254740993}`
+	if string(data) != want {
+		t.Fatalf("got %s stored, want %s", data, want)
+	}
+
+	var item jsonItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := item.Any.(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Fatalf("got %#v, want the number decoded exactly", item.Any)
+	}
+}
+
+func TestPutList(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutList("tags", 1, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+	err = db.GetList("tags", 1, &tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutList("tags", 1, append(tags, "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = db.GetList("tags", 1, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	err = db.GetList("tags", 2, &got)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing list, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestIntKeys(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, num := range []int64{10, 9, 100, 1} {
+		err = db.Put(dbItem{Num: num})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, dst := range []any{dbItem{}, (*dbItem)(nil)} {
+		keys, err := db.IntKeys(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int64{1, 9, 10, 100}; !slices.Equal(keys, want) {
+			t.Fatalf("got %v for %T, want %v", keys, dst, want)
+		}
+	}
+
+	err = db.PutList("dbItem", "x", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.IntKeys(dbItem{})
+	if !errors.Is(err, ErrNonIntegerKey) {
+		t.Fatalf("got %v, want %v", err, ErrNonIntegerKey)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbItem{Num: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Delete(dbItem{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&dbItem{}, 3)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v getting a deleted entity, want %v", err, ErrNoSuchEntity)
+	}
+
+	err = db.Delete(&dbItem{}, 3)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v deleting twice, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestPing(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the dir with a file so that nothing can be written to it.
+	err = os.RemoveAll(dir)
+	if err == nil {
+		err = os.WriteFile(dir, nil, 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Ping()
+	if err == nil {
+		t.Fatal("got no error pinging an unwritable store")
+	}
+}
+
+type dbUserID int
+
+func (id *dbUserID) String() string {
+	return fmt.Sprintf("user-%d", int(*id))
+}
+
+type dbUser struct {
+	ID   dbUserID
+	Name string
+}
+
+func TestStringerKeys(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbUser{ID: 4, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "dbUser", "user-4"))
+	if err != nil {
+		t.Fatalf("got %v, want the entity keyed by String", err)
+	}
+
+	var u dbUser
+	err = db.GetByID(&u, dbUserID(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "a" {
+		t.Fatalf("got %+v", u)
+	}
+
+	err = db.Delete(u, dbUserID(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	a, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.IsNew() {
+		t.Error("got a store which was just created not new")
+	}
+
+	b, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.IsNew() {
+		t.Error("got an existing store new")
+	}
+}
+
+func TestQualifiedTypeNames(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithQualifiedTypeNames())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbItem{Num: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "github.com", "davidsutts", "burrowdb", "dbItem", "1"))
+	if err != nil {
+		t.Fatalf("got %v, want the entity stored under its package path", err)
+	}
+
+	var items []dbItem
+	err = db.GetAll(&items)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got %v, %v", items, err)
+	}
+}
+
+func TestDeleteAndGet(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbItem{Name: "x", Num: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item dbItem
+	err = db.DeleteAndGet(&item, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "x" {
+		t.Fatalf("got %+v, want the deleted entity", item)
+	}
+
+	err = db.DeleteAndGet(&item, 4)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v deleting twice, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestGet(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dbItem{Name: "a", Num: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := Get[dbItem](db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "a" {
+		t.Fatalf("got %+v", item)
+	}
+
+	_, err = Get[dbItem](db, 2)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestDeleteGeneric(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(dbItem{Name: "a", Num: 1}, dbItem{Name: "b", Num: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Delete[dbItem](db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Get[dbItem](db, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v after deleting, want %v", err, ErrNoSuchEntity)
+	}
+
+	_, err = Get[dbItem](db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Delete[dbItem](db, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v deleting again, want %v", err, ErrNoSuchEntity)
+	}
+
+	err = Delete[*dbItem](db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAllGeneric(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(dbItem{Name: "ten", Num: 10}, dbItem{Name: "two", Num: 2}, dbItem{Name: "one", Num: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetAll[dbItem](db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []dbItem{{Name: "one", Num: 1}, {Name: "two", Num: 2}, {Name: "ten", Num: 10}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	other, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty, err := GetAll[dbItem](other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("got %v from an empty db, want none", empty)
+	}
+}
+
+func TestGetAllExcept(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 5 {
+		err = db.Put(dbItem{Name: fmt.Sprint(i + 1), Num: int64(i + 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// IDs of other integer types and pointers match the same keys.
+	four := 4
+	var got []dbItem
+	err = db.GetAllExcept(&got, []any{2, int32(3), &four, 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []dbItem{{Name: "1", Num: 1}, {Name: "5", Num: 5}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	err = db.GetAllExcept(&got, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("got %d entities excluding none, want 5", len(got))
+	}
+}
+
+func TestGetByIDNonStructDst(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	err = db.GetByID(&n, 1)
+	if !errors.Is(err, ErrInvalidDstType) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidDstType)
+	}
+	if !strings.Contains(err.Error(), "int") {
+		t.Fatalf("got %q, want the error to name the dst type", err)
+	}
+
+	var p *dbItem
+	err = db.GetByID(&p, 1)
+	if !errors.Is(err, ErrInvalidDstType) {
+		t.Fatalf("got %v for a pointer to a pointer, want %v", err, ErrInvalidDstType)
+	}
+}
+
+func TestKeySizes(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(dbItem{Name: "abc", Num: 1}, dbItem{Name: "abcdef", Num: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, err := db.KeySizes(dbItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 {
+		t.Fatalf("got %v, want a size for each entity", sizes)
+	}
+
+	for key, size := range sizes {
+		info, err := os.Stat(filepath.Join(dir, "dbItem", key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != info.Size() {
+			t.Errorf("%s: got %d bytes, want %d", key, size, info.Size())
+		}
+	}
+}
+
+func TestTypeSize(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := db.TypeSize(dbItem{})
+	if err != nil || size != 0 {
+		t.Fatalf("got %d, %v for an empty type, want 0", size, err)
+	}
+
+	items := []dbItem{{Name: "abc", Num: 1}, {Name: "abcdef", Num: 2}, {Name: "a", Num: 3}}
+	var want int64
+	for _, item := range items {
+		err = db.Put(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want += int64(len(data))
+	}
+
+	size, err = db.TypeSize(&dbItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want {
+		t.Fatalf("got %d bytes, want %d", size, want)
+	}
+}
+
+type dbNoID struct {
+	A int
+}
+
+func TestPutWithID(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutWithID(dbItem{Name: "x", Num: 1}, "derived")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item dbItem
+	err = db.GetByID(&item, "derived")
+	if err != nil || item.Num != 1 {
+		t.Fatalf("got %+v, %v", item, err)
+	}
+
+	err = db.GetByID(&item, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v getting by the ID field, want %v", err, ErrNoSuchEntity)
+	}
+
+	// Values don't need an ID field.
+	err = db.PutWithID(dbNoID{A: 1}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var noID dbNoID
+	err = db.GetByID(&noID, 5)
+	if err != nil || noID.A != 1 {
+		t.Fatalf("got %+v, %v", noID, err)
+	}
+}
+
+func TestGetLast(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSortOrder(Insertion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(1); i <= 12; i++ {
+		err = db.Put(dbItem{Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// IDs are compared numerically whatever the sort order.
+	var items []dbItem
+	err = db.GetLast(&items, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Num != 12 || items[1].Num != 11 || items[2].Num != 10 {
+		t.Fatalf("got %v, want 12, 11 and 10", items)
+	}
+
+	err = db.GetLast(&items, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 12 {
+		t.Fatalf("got %d items, want every one", len(items))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.MkdirAll(filepath.Join(dir, "dbItem"), 0777)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "dbItem", "1"), []byte(`{"Name":"a",}`), 0666)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "dbItem", "2"), []byte(`{"Name":5}`), 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&dbItem{}, 1)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), `dbItem "1" at byte offset 13`) {
+		t.Errorf("got %v, want a syntax error naming the entity and offset", err)
+	}
+
+	err = db.GetByID(&dbItem{}, 2)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || !strings.Contains(err.Error(), `dbItem "2" at byte offset`) {
+		t.Errorf("got %v, want a type error naming the entity and offset", err)
+	}
+
+	var items []dbItem
+	err = db.GetAll(&items)
+	if err == nil || !strings.Contains(err.Error(), `dbItem "1"`) {
+		t.Errorf("got %v scanning, want the entity named", err)
+	}
+}
+
+func TestExternalWrites(t *testing.T) {
+	// Nothing read is kept between calls other than the index of each append
+	// log, so writes made by another process are seen by the next read.
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithAppendLog("appendItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(dbItem{Num: 1, Name: "a"}, appendItem{ID: 1, Status: "a"}, appendItem{ID: 2, Status: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item dbItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(db.entityPath("dbItem", "1"), []byte(`{"Name":"b","Num":1}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&item, 1)
+	if err != nil || item.Name != "b" {
+		t.Fatalf("got %+v, %v after the entity file was rewritten, want b", item, err)
+	}
+
+	getStatus := func(id int) string {
+		t.Helper()
+		var item appendItem
+		err := db.GetByID(&item, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return item.Status
+	}
+
+	if status := getStatus(1); status != "a" {
+		t.Fatalf("got %q, want a", status)
+	}
+
+	// A line appended to the log.
+	logPath := db.appendLogPath("appendItem")
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"key":"1","time":1,"value":{"ID":1,"Status":"b"}}` + "\n")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := getStatus(1); status != "b" {
+		t.Fatalf("got %q after a line was appended, want b", status)
+	}
+
+	// The log replaced by a longer one with the records at other offsets.
+	replaced := strings.Join([]string{
+		`{"key":"2","time":1,"value":{"ID":2,"Status":"replaced"}}`,
+		`{"key":"1","time":1,"value":{"ID":1,"Status":"replaced"}}`,
+		`{"key":"3","time":1,"value":{"ID":3,"Status":"replaced"}}`,
+		`{"key":"4","time":1,"value":{"ID":4,"Status":"replaced"}}`,
+	}, "\n") + "\n"
+	err = os.WriteFile(logPath+".new", []byte(replaced), 0666)
+	if err == nil {
+		err = os.Rename(logPath+".new", logPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id := 1; id <= 4; id++ {
+		if status := getStatus(id); status != "replaced" {
+			t.Fatalf("got %q for %d after the log was replaced, want replaced", status, id)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// WithOverwriteWindow specifies that Put should return ErrTooSoon rather than
+// overwrite an entity which was last written less than window ago, guarding
+// against accidental repeated writes such as a double submitted form. The time
+// of the last write is taken from the modification time of the entity's file.
+func WithOverwriteWindow(window time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if window < 0 {
+			return errors.New("overwrite window must not be negative")
+		}
+
+		db.overwriteWindow = window
+		return nil
+	}
+}
+
+// checkOverwriteWindow returns ErrTooSoon if the entity of the named type with
+// the passed key exists and was written within the db's overwrite window. The
+// type's lock must be held.
+func (db *BurrowDB) checkOverwriteWindow(typeName, key string) error {
+	if db.overwriteWindow == 0 {
+		return nil
+	}
+
+	modTime, err := db.entityModTime(typeName, key)
+	if errors.Is(err, ErrNoSuchEntity) || errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if age := time.Since(modTime); age < db.overwriteWindow {
+		return fmt.Errorf("%w: %s %q was written %s ago", ErrTooSoon, typeName, key, age.Round(time.Millisecond))
+	}
+
+	return nil
+}
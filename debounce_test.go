@@ -0,0 +1,47 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+type debounceItem struct {
+	ID int
+}
+
+func TestOverwriteWindow(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithOverwriteWindow(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(debounceItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(debounceItem{ID: 1})
+	if !errors.Is(err, ErrTooSoon) {
+		t.Fatalf("got %v overwriting at once, want %v", err, ErrTooSoon)
+	}
+
+	// Other entities aren't affected.
+	err = db.Put(debounceItem{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The window is measured from the modification time of the file.
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(db.entityPath("debounceItem", "1"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(debounceItem{ID: 1})
+	if err != nil {
+		t.Fatalf("got %v once the window has passed", err)
+	}
+}
@@ -0,0 +1,200 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WithFieldDefault specifies a value given to the named field of the type of v
+// when an entity is read without the field, so that records stored before the
+// field was added can be given a sensible default. A field stored with its zero
+// value, or null, is kept. Each entity is given its own copy of the default, so
+// pointer, slice and map defaults aren't shared. The v may be a struct or a
+// pointer to one and is only used for its type.
+//
+// Whether a field is missing can only be told for entities stored with the
+// JSON codec. With other codecs, which may not store zero fields at all, a
+// field holding its zero value is treated as missing.
+func WithFieldDefault(v any, field string, value any) newDBOption {
+	return func(db *BurrowDB) error {
+		_type := indirectType(reflect.TypeOf(v))
+		if _type.Kind() != reflect.Struct {
+			return fmt.Errorf("%w: %s is not a struct", ErrInvalidDstType, _type)
+		}
+
+		structField, ok := _type.FieldByName(field)
+		if !ok {
+			return fmt.Errorf("type %s has no field %s", _type, field)
+		}
+
+		if value == nil {
+			return fmt.Errorf("default for field %s of %s is nil", field, _type)
+		}
+
+		def := reflect.ValueOf(value)
+		if !def.Type().ConvertibleTo(structField.Type) {
+			return fmt.Errorf("default for field %s of %s has type %s, expected %s", field, _type, def.Type(), structField.Type)
+		}
+
+		if db.fieldDefaults == nil {
+			db.fieldDefaults = map[reflect.Type]map[string]reflect.Value{}
+		}
+
+		if db.fieldDefaults[_type] == nil {
+			db.fieldDefaults[_type] = map[string]reflect.Value{}
+		}
+
+		db.fieldDefaults[_type][field] = def.Convert(structField.Type)
+		return nil
+	}
+}
+
+// applyFieldDefaults sets each field of the struct pointed to by dst which is
+// missing from its decoded data to a copy of the default registered for its
+// type, if any.
+func (db *BurrowDB) applyFieldDefaults(codec Codec, data []byte, dst any) error {
+	_type := reflect.TypeOf(dst).Elem()
+	defaults := db.fieldDefaults[_type]
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if codec.Name() == JSONCodec.Name() {
+		err := json.Unmarshal(data, &obj)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal data for field defaults: %w", err)
+		}
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	for name, def := range defaults {
+		field := v.FieldByName(name)
+		if obj != nil {
+			structField, _ := _type.FieldByName(name)
+			if jsonName, ok := jsonFieldName(structField); !ok || hasMember(obj, jsonName) {
+				continue
+			}
+		} else if !field.IsZero() {
+			continue
+		}
+
+		field.Set(deepCopy(def))
+	}
+
+	return nil
+}
+
+// hasMember reports whether the passed JSON object has a member with the passed
+// name, matching case-insensitively as encoding/json does when decoding.
+func hasMember(obj map[string]json.RawMessage, name string) bool {
+	if _, ok := obj[name]; ok {
+		return true
+	}
+
+	for member := range obj {
+		if strings.EqualFold(member, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deepCopy returns a copy of the passed value which shares no pointers, slices
+// or maps with it. Unexported fields of structs are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Array, reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		if v.Kind() == reflect.Array {
+			for i := range v.Len() {
+				c.Index(i).Set(deepCopy(v.Index(i)))
+			}
+			return c
+		}
+		for i := range v.NumField() {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	}
+
+	return v
+}
+
+// WithDefaulter specifies a function which fills in the fields of entities of
+// the named type before they are stored, such as a slug computed from a name.
+// It is passed a pointer to a copy of the value being put, which it may modify,
+// and the modified value is stored and indexed in its place. An error returned
+// by fn is returned by the put.
+func WithDefaulter(typeName string, fn func(any) error) newDBOption {
+	return func(db *BurrowDB) error {
+		if fn == nil {
+			return fmt.Errorf("defaulter for %s is nil", typeName)
+		}
+
+		if db.defaulters == nil {
+			db.defaulters = map[string]func(any) error{}
+		}
+
+		db.defaulters[typeName] = fn
+		return nil
+	}
+}
+
+// applyDefaulter returns the passed struct value as filled in by the defaulter
+// of its type, or the value itself if the type has none.
+func (db *BurrowDB) applyDefaulter(v any) (any, error) {
+	_type := reflect.TypeOf(v)
+	typeName := db.typeName(_type)
+	fn, ok := db.defaulters[typeName]
+	if !ok {
+		return v, nil
+	}
+
+	ptr := reflect.New(_type)
+	ptr.Elem().Set(reflect.ValueOf(v))
+	err := fn(ptr.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply defaults to %s: %w", typeName, err)
+	}
+
+	return ptr.Elem().Interface(), nil
+}
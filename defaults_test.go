@@ -0,0 +1,183 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type defaultsItem struct {
+	ID    int
+	Name  string
+	Level int
+	Limit *int
+}
+
+func TestFieldDefault(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "defaultsItem"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored before Level and Limit were added, and after with a zero Limit.
+	err = os.WriteFile(filepath.Join(dir, "defaultsItem", "1"), []byte(`{"ID":1,"Name":"a"}`), 0666)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "defaultsItem", "2"), []byte(`{"ID":2,"Limit":0}`), 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := 10
+	db, err := NewDB(
+		WithDir(dir),
+		WithFieldDefault(defaultsItem{}, "Level", 3),
+		WithFieldDefault(&defaultsItem{}, "Limit", &limit),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item defaultsItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Level != 3 || item.Limit == nil || *item.Limit != 10 {
+		t.Fatalf("got %+v, want the defaults filled in", item)
+	}
+
+	var items []defaultsItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Level != 3 {
+		t.Fatalf("got %+v, want the defaults filled in", items)
+	}
+	if items[1].Limit == nil || *items[1].Limit != 0 {
+		t.Fatalf("got %+v, want the stored pointer to zero kept", items[1])
+	}
+}
+
+type defaultsFlags struct {
+	ID      int
+	Enabled bool
+	Tags    *[]string
+}
+
+func TestFieldDefaultMissing(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "defaultsFlags"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored before Enabled and Tags were added.
+	for _, id := range []string{"1", "2"} {
+		err = os.WriteFile(filepath.Join(dir, "defaultsFlags", id), []byte(`{"ID":`+id+`}`), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tags := []string{"default"}
+	db, err := NewDB(
+		WithDir(dir),
+		WithFieldDefault(defaultsFlags{}, "Enabled", true),
+		WithFieldDefault(defaultsFlags{}, "Tags", &tags),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(defaultsFlags{ID: 3, Enabled: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []defaultsFlags
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || !items[0].Enabled || !items[1].Enabled {
+		t.Fatalf("got %+v, want the missing fields defaulted", items)
+	}
+	if items[2].Enabled || items[2].Tags != nil {
+		t.Fatalf("got %+v, want the stored false and null kept", items[2])
+	}
+
+	// Each entity has its own copy of the default.
+	*items[0].Tags = append(*items[0].Tags, "changed")
+	if len(*items[1].Tags) != 1 || len(tags) != 1 {
+		t.Fatalf("got tags %v and default %v, want them unaffected by another entity", *items[1].Tags, tags)
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithFieldDefault(defaultsFlags{}, "Enabld", true))
+	if err == nil {
+		t.Fatal("got no error for a default of a field which doesn't exist")
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithFieldDefault(defaultsFlags{}, "Enabled", "yes"))
+	if err == nil {
+		t.Fatal("got no error for a default of the wrong type")
+	}
+}
+
+type defaulterItem struct {
+	ID   int
+	Name string
+	Slug string `burrowdb:"index"`
+}
+
+func TestDefaulter(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithDefaulter("defaulterItem", func(v any) error {
+		item := v.(*defaulterItem)
+		if item.Name == "" {
+			return errors.New("name is empty")
+		}
+		if item.Slug == "" {
+			item.Slug = strings.ReplaceAll(strings.ToLower(item.Name), " ", "-")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		defaulterItem{ID: 1, Name: "Hello World"},
+		defaulterItem{ID: 2, Name: "Kept", Slug: "custom"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item defaulterItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Slug != "hello-world" {
+		t.Fatalf("got slug %q, want hello-world", item.Slug)
+	}
+
+	// The filled in value is indexed, and set slugs are kept.
+	var items []defaulterItem
+	err = db.GetByField(&items, "Slug", "custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("got %+v, want item 2", items)
+	}
+
+	err = db.Put(defaulterItem{ID: 3})
+	if err == nil {
+		t.Fatal("got nil error for a value the defaulter rejects")
+	}
+}
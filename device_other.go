@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package burrowdb
+
+// sameDevice can't be determined on this platform so paths are assumed to be
+// on the same device.
+func sameDevice(a, b string) (bool, error) {
+	return true, nil
+}
+
+// isNoSpace can't be determined on this platform so errors are never reported
+// as being caused by a full disk.
+func isNoSpace(err error) bool {
+	return false
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// sameDevice reports whether the two passed paths are on the same device.
+func sameDevice(a, b string) (bool, error) {
+	devA, err := device(a)
+	if err != nil {
+		return false, err
+	}
+
+	devB, err := device(b)
+	if err != nil {
+		return false, err
+	}
+
+	return devA == devB, nil
+}
+
+// device returns the ID of the device containing the passed path.
+func device(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unable to get device of %q", path)
+	}
+
+	return uint64(stat.Dev), nil
+}
+
+// isNoSpace reports whether the passed error was caused by the filesystem being
+// full.
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT)
+}
@@ -0,0 +1,79 @@
+package burrowdb
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type diffItem struct {
+	ID   int
+	Name string
+}
+
+type diffOther struct {
+	ID int
+}
+
+func TestDiff(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 5 {
+		err = db.Put(diffItem{ID: i + 1, Name: "original"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clone, err := db.CopyTo(filepath.Join(t.TempDir(), "clone"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clone.Close()
+
+	report, err := db.Diff(clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Equal() {
+		t.Fatalf("got %+v for an unmodified clone, want no differences", report)
+	}
+
+	err = clone.Put(diffItem{ID: 2, Name: "changed"})
+	if err == nil {
+		err = clone.Put(diffItem{ID: 6, Name: "added"})
+	}
+	if err == nil {
+		err = clone.Delete(&diffItem{}, 4)
+	}
+	if err == nil {
+		err = clone.Put(diffOther{ID: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = db.Diff(clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := DiffReport{Types: []TypeDiff{
+		{Type: "diffItem", Added: []string{db.entityKey(6)}, Removed: []string{db.entityKey(4)}, Changed: []string{db.entityKey(2)}},
+		{Type: "diffOther", Added: []string{db.entityKey(1)}},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("got %+v, want %+v", report, want)
+	}
+
+	report, err = clone.Diff(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Types) != 2 || report.Types[1].Removed[0] != db.entityKey(1) {
+		t.Fatalf("got %+v diffing the other way, want the added type removed", report)
+	}
+}
@@ -0,0 +1,97 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+)
+
+// WithBatchedDirRead specifies that type dirs should be read n entries at a
+// time, rather than all at once, so that listing a type with millions of
+// entities doesn't hold an entry for each of them in memory. Count then uses
+// memory independent of the number of entities, while scans such as GetAll
+// still hold the key of each. It only affects types stored directly in their
+// type dir, rather than in the sub-directories of a layout or partitions.
+func WithBatchedDirRead(n int) newDBOption {
+	return func(db *BurrowDB) error {
+		if n < 1 {
+			return errors.New("dir read batch size must be positive")
+		}
+
+		db.dirReadBatch = n
+		return nil
+	}
+}
+
+// readDirBatched calls fn with each entry of the passed dir, reading the db's
+// batch size of entries at a time. A dir which doesn't exist has no entries.
+func (db *BurrowDB) readDirBatched(dir string, fn func(entry fs.DirEntry) error) error {
+	release, err := db.acquireFile()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f, err := os.Open(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to open type dir: %w", err)
+	}
+	defer f.Close()
+
+	for {
+		entries, err := f.ReadDir(db.dirReadBatch)
+		for _, entry := range entries {
+			err := fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read type dir: %w", err)
+		}
+	}
+}
+
+// Count returns the number of stored entities with the type of dst, without
+// reading them. The dst may be a struct or a pointer to one and is only used
+// for its type. With WithBatchedDirRead the type dir is read a batch at a
+// time, so the memory used doesn't grow with the number of entities.
+func (db *BurrowDB) Count(dst any) (_ int, err error) {
+	defer db.handleError("Count", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return 0, ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if db.isAppendLog(typeName) {
+		keys, _, err := db.logKeys(typeName)
+		return len(keys), err
+	}
+
+	var n int
+	err = db.eachEntityFile(typeName, func(entry fs.DirEntry) error {
+		if _, ok := db.fileKey(entry); ok {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,94 @@
+package burrowdb
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type dirReadItem struct {
+	ID   int
+	Name string
+}
+
+func TestBatchedDirRead(t *testing.T) {
+	const n = 5000
+	const batch = 7
+
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithBatchedDirRead(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(dirReadItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write the rest of the entities directly so the dir is large without
+	// thousands of puts.
+	typeDir := db.typeDir(db.typeName(reflect.TypeFor[dirReadItem]()))
+	for i := 2; i <= n; i++ {
+		data := fmt.Appendf(nil, `{"ID":%d,"Name":"item"}`, i)
+		err = os.WriteFile(filepath.Join(typeDir, fmt.Sprint(i)+db.fileExt), data, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := db.Count(dirReadItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Fatalf("got count %d, want %d", count, n)
+	}
+
+	// Reading the dir a batch at a time visits every entry once.
+	var seen int
+	err = db.readDirBatched(typeDir, func(fs.DirEntry) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != n+2 {
+		t.Fatalf("got %d entries, want %d entities and the codec and schema markers", seen, n)
+	}
+
+	keys, err := db.IntKeys(dirReadItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != n || keys[0] != 1 || keys[n-1] != n {
+		t.Fatalf("got %d keys from %v to %v, want %d from 1 to %d", len(keys), keys[0], keys[len(keys)-1], n, n)
+	}
+
+	var items []dirReadItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != n {
+		t.Fatalf("got %d entities, want %d", len(items), n)
+	}
+
+	if cfg := db.Config(); cfg.DirReadBatch != batch {
+		t.Fatalf("got batch %d in config, want %d", cfg.DirReadBatch, batch)
+	}
+
+	count, err = db.Count(openFilesItem{})
+	if err != nil || count != 0 {
+		t.Fatalf("got count %d and %v for an unstored type, want 0", count, err)
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithBatchedDirRead(0))
+	if err == nil {
+		t.Fatal("got no error for a batch size of 0")
+	}
+}
@@ -0,0 +1,163 @@
+package burrowdb
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const encryptTagValue = "encrypt" // Struct tag value to specify that a field is stored encrypted.
+
+// WithEncryption specifies the AES key used to encrypt fields tagged
+// `burrowdb:"encrypt"`. The key must be 16, 24 or 32 bytes long to select
+// AES-128, AES-192 or AES-256.
+func WithEncryption(key []byte) newDBOption {
+	return func(db *BurrowDB) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key: %w", err)
+		}
+
+		db.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("unable to create cipher: %w", err)
+		}
+		db.filenameKey = filenameKey(key)
+
+		return nil
+	}
+}
+
+// encryptedFields returns the JSON names of the fields of the passed type which
+// are tagged `burrowdb:"encrypt"`.
+func encryptedFields(_type reflect.Type) []string {
+	if _type.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for _, field := range reflect.VisibleFields(_type) {
+		if opts, _ := parseTag(field); field.Anonymous || !opts.encrypt {
+			continue
+		}
+
+		if name, ok := jsonFieldName(field); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// encryptFields replaces the value of each encrypted field of the passed type
+// in the encoded data with its ciphertext. Encrypted fields can only be stored
+// with the JSON codec, and the rest of the entity is left readable.
+func (db *BurrowDB) encryptFields(_type reflect.Type, codec Codec, data []byte) ([]byte, error) {
+	names := encryptedFields(_type)
+	if len(names) == 0 {
+		return data, nil
+	}
+
+	if codec.Name() != JSONCodec.Name() {
+		return nil, fmt.Errorf("%w: %s has encrypted fields and is stored with the %s codec", ErrNotJSON, _type, codec.Name())
+	}
+
+	if db.aead == nil {
+		return nil, fmt.Errorf("%w: %s has encrypted fields", ErrNoEncryptionKey, _type)
+	}
+
+	obj := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal value for encryption: %w", err)
+	}
+
+	for _, name := range names {
+		plain, ok := obj[name]
+		if !ok {
+			continue
+		}
+
+		nonce := make([]byte, db.aead.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate nonce: %w", err)
+		}
+
+		// The field name is authenticated so ciphertext can't be moved between fields.
+		sealed := db.aead.Seal(nonce, nonce, plain, []byte(name))
+		obj[name], err = json.Marshal(base64.StdEncoding.EncodeToString(sealed))
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal field %s: %w", name, err)
+		}
+	}
+
+	return db.marshalObject(obj)
+}
+
+// decryptFields replaces the ciphertext of each encrypted field of the passed
+// type in the stored data with its plaintext value.
+func (db *BurrowDB) decryptFields(_type reflect.Type, codec Codec, data []byte) ([]byte, error) {
+	names := encryptedFields(_type)
+	if len(names) == 0 || codec.Name() != JSONCodec.Name() {
+		return data, nil
+	}
+
+	if db.aead == nil {
+		return nil, fmt.Errorf("%w: %s has encrypted fields", ErrNoEncryptionKey, _type)
+	}
+
+	obj := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal data for decryption: %w", err)
+	}
+
+	for _, name := range names {
+		raw, ok := obj[name]
+		if !ok {
+			continue
+		}
+
+		var text string
+		err = json.Unmarshal(raw, &text)
+		if err != nil {
+			return nil, fmt.Errorf("encrypted field %s is not a string: %w", name, err)
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode field %s: %w", name, err)
+		}
+
+		n := db.aead.NonceSize()
+		if len(sealed) < n {
+			return nil, fmt.Errorf("encrypted field %s is too short", name)
+		}
+
+		obj[name], err = db.aead.Open(nil, sealed[:n], sealed[n:], []byte(name))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt field %s: %w", name, err)
+		}
+	}
+
+	return db.marshalObject(obj)
+}
+
+// marshalObject encodes the passed JSON object using the db's JSON options.
+func (db *BurrowDB) marshalObject(obj map[string]json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!db.jsonOpts.DisableHTMLEscape)
+	err := enc.Encode(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
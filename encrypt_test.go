@@ -0,0 +1,76 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type secretItem struct {
+	ID    int
+	Name  string
+	Token string `json:"token" burrowdb:"encrypt"`
+}
+
+func TestEncryption(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte("k"), 32)
+	db, err := NewDB(WithDir(dir), WithEncryption(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(secretItem{ID: 1, Name: "n", Token: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secretItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("got the plaintext stored: %s", data)
+	}
+	if !bytes.Contains(data, []byte(`"Name":"n"`)) {
+		t.Fatalf("got %s, want untagged fields stored in the clear", data)
+	}
+
+	var item secretItem
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Token != "hunter2" {
+		t.Fatalf("got %+v, want the field decrypted", item)
+	}
+
+	noKey, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = noKey.GetByID(&item, 1)
+	if !errors.Is(err, ErrNoEncryptionKey) {
+		t.Fatalf("got %v without a key, want %v", err, ErrNoEncryptionKey)
+	}
+
+	wrongKey, err := NewDB(WithDir(dir), WithEncryption(bytes.Repeat([]byte("w"), 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wrongKey.GetByID(&item, 1)
+	if err == nil {
+		t.Fatal("got no error decrypting with the wrong key")
+	}
+}
+
+func TestEncryptionKeySize(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithEncryption([]byte("short")))
+	if err == nil {
+		t.Fatal("got no error for a 5 byte key")
+	}
+}
@@ -0,0 +1,44 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithErrorHandler specifies a function called with the name of the operation,
+// such as "Put", and the error whenever a method of the db is about to return
+// an error, returning the error to return instead. This allows errors to be
+// annotated, translated or logged in one place.
+//
+// The handler should wrap err rather than replace it. If the returned error
+// doesn't wrap err, err is wrapped alongside it so that sentinel errors such as
+// ErrNoSuchEntity can still be detected with errors.Is, and err is returned
+// unchanged if the handler returns nil. Methods built on others, such as
+// PutAll on Put, return the errors already passed to the handler by them.
+func WithErrorHandler(handler func(op string, err error) error) newDBOption {
+	return func(db *BurrowDB) error {
+		if handler == nil {
+			return errors.New("error handler is nil")
+		}
+
+		db.errorHandler = handler
+		return nil
+	}
+}
+
+// handleError replaces the error pointed to by err, if it isn't nil, with the
+// result of passing it to the db's error handler for the named operation.
+func (db *BurrowDB) handleError(op string, err *error) {
+	if *err == nil || db.errorHandler == nil {
+		return
+	}
+
+	handled := db.errorHandler(op, *err)
+	switch {
+	case handled == nil:
+	case errors.Is(handled, *err):
+		*err = handled
+	default:
+		*err = fmt.Errorf("%w: %w", handled, *err)
+	}
+}
@@ -0,0 +1,69 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type handledItem struct {
+	ID int
+}
+
+func TestErrorHandler(t *testing.T) {
+	errReplaced := errors.New("replaced")
+	tests := []struct {
+		name    string
+		handler func(op string, err error) error
+		want    []error // errors the returned error must wrap.
+		text    string
+	}{
+		{
+			"Wraps",
+			func(op string, err error) error { return fmt.Errorf("%s failed: %w", op, err) },
+			[]error{ErrNoSuchEntity},
+			"GetByID failed: no such entity exists",
+		},
+		{
+			"Replaces",
+			func(string, error) error { return errReplaced },
+			[]error{errReplaced, ErrNoSuchEntity},
+			"replaced: no such entity exists",
+		},
+		{
+			"Nil",
+			func(string, error) error { return nil },
+			[]error{ErrNoSuchEntity},
+			"no such entity exists",
+		},
+	}
+
+	for _, test := range tests {
+		db, err := NewDB(WithDir(t.TempDir()), WithErrorHandler(test.handler))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.GetByID(&handledItem{}, 1)
+		for _, want := range test.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: got %v, want it to wrap %v", test.name, err, want)
+			}
+		}
+		if err == nil || err.Error() != test.text {
+			t.Errorf("%s: got %v, want %q", test.name, err, test.text)
+		}
+	}
+
+	// The handler isn't called when nothing fails.
+	db, err := NewDB(WithDir(t.TempDir()), WithErrorHandler(func(op string, err error) error {
+		t.Errorf("handler called by %s with %v", op, err)
+		return err
+	}))
+	if err == nil {
+		err = db.Put(handledItem{ID: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+)
+
+// exportRecord is a line written by ExportAll.
+type exportRecord struct {
+	Type  string          `json:"_type"`
+	ID    string          `json:"_id"`
+	Codec string          `json:"_codec"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// ExportAll writes every entity of every type to w as newline delimited JSON,
+// one object per entity annotated with its type, ID and codec, such as
+// {"_type":"User","_id":"123","_codec":"json","data":{...}}. Entities stored
+// with JSONCodec are written as is, while those stored with other codecs are
+// written as a base64 string. Types are visited in ascending order of name and
+// entities in the db's sort order, each read under the read lock of its type.
+func (db *BurrowDB) ExportAll(w io.Writer) (err error) {
+	defer db.handleError("ExportAll", &err)
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, typeName := range typeNames {
+		mu := db.typeLock(typeName)
+		mu.RLock()
+		keys, err := db.keys(typeName)
+		mu.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			mu.RLock()
+			record, err := db.exportRecord(typeName, key)
+			mu.RUnlock()
+			if errors.Is(err, ErrNoSuchEntity) {
+				// The entity was deleted since the keys were read.
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			err = enc.Encode(record)
+			if err != nil {
+				return fmt.Errorf("unable to write %s %q: %w", typeName, key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportRecord returns the line written by ExportAll for the entity of the
+// named type with the passed key. The lock of the type must be held.
+func (db *BurrowDB) exportRecord(typeName, key string) (exportRecord, error) {
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return exportRecord{}, err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return exportRecord{}, err
+	}
+
+	record := exportRecord{Type: typeName, ID: key, Codec: codec.Name(), Data: data}
+	if codec.Name() != JSONCodec.Name() {
+		record.Data, err = json.Marshal(data)
+		if err != nil {
+			return exportRecord{}, fmt.Errorf("unable to encode %s %q: %w", typeName, key, err)
+		}
+	}
+
+	return record, nil
+}
+
+// ImportMode decides what ImportAll and Restore do with an entity which is
+// already stored.
+type ImportMode int
+
+const (
+	// ImportOverwrite replaces the stored entity. This is the default.
+	ImportOverwrite ImportMode = iota
+
+	// ImportSkip keeps the stored entity, leaving out the imported one.
+	ImportSkip
+
+	// ImportFail stops at the first entity which is already stored, returning
+	// ErrEntityExists.
+	ImportFail
+)
+
+// ImportReport counts the entities written and left out by ImportAll or
+// Restore.
+type ImportReport struct {
+	Imported int // Number of entities written.
+	Skipped  int // Number of entities left out as they were already stored.
+}
+
+// ImportAll reads entities written by ExportAll from r and stores each under
+// its type and ID, doing as mode decides with any which is already stored, and
+// returns the number written and skipped. Each entity is written under the
+// lock of its type with the codec it was exported with, which must be known to
+// the db if it differs from the codec of the type. Indexes aren't updated, so
+// RebuildIndexes should be called for each indexed type.
+//
+// The entities before one which fails are kept, including with ImportFail.
+// WithImportBatchSize completes them in batches, syncing each and reporting
+// progress, so a large import can be resumed from its last complete batch.
+func (db *BurrowDB) ImportAll(r io.Reader, mode ImportMode, opts ...importOption) (_ ImportReport, err error) {
+	defer db.handleError("ImportAll", &err)
+
+	cfg, err := newImportConfig(opts)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	dec := json.NewDecoder(r)
+	for i := 0; ; i++ {
+		var record exportRecord
+		err = dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return report, cfg.finish(db, report)
+		} else if err != nil {
+			return report, fmt.Errorf("unable to read record %d: %w", i, err)
+		}
+
+		imported, err := db.importRecord(record, mode)
+		if err != nil {
+			return report, fmt.Errorf("record %d: %w", i, err)
+		}
+
+		if imported {
+			report.Imported++
+		} else {
+			report.Skipped++
+		}
+
+		err = cfg.handled(db, report)
+		if err != nil {
+			return report, err
+		}
+	}
+}
+
+// importRecord stores the entity of the passed line written by ExportAll,
+// reporting false if it was skipped as mode decides.
+func (db *BurrowDB) importRecord(record exportRecord, mode ImportMode) (bool, error) {
+	if !filepath.IsLocal(record.Type) || isReservedType(record.Type) {
+		return false, fmt.Errorf("invalid type %q", record.Type)
+	}
+	if !isSafeKey(record.ID) {
+		return false, fmt.Errorf("invalid ID %q", record.ID)
+	}
+
+	codec, ok := db.knownCodec(record.Codec)
+	if !ok {
+		return false, fmt.Errorf("%s %q is encoded with the unknown codec %q", record.Type, record.ID, record.Codec)
+	}
+
+	data := []byte(record.Data)
+	if codec.Name() != JSONCodec.Name() {
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return false, fmt.Errorf("unable to decode %s %q: %w", record.Type, record.ID, err)
+		}
+	}
+
+	mu := db.typeLock(record.Type)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if mode != ImportOverwrite {
+		exists, err := db.entityExists(record.Type, record.ID)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case exists && mode == ImportFail:
+			return false, fmt.Errorf("%w: %s %q", ErrEntityExists, record.Type, record.ID)
+		case exists:
+			return false, nil
+		}
+	}
+
+	typeCodec, err := db.storedCodec(record.Type)
+	if err != nil {
+		return false, err
+	}
+
+	if typeCodec.Name() == codec.Name() {
+		err = db.recordCodec(record.Type, codec)
+		if err == nil {
+			err = db.recordEntityCodec(record.Type, record.ID, nil)
+		}
+	} else {
+		err = db.recordEntityCodec(record.Type, record.ID, codec)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = db.writeEntity(record.Type, record.ID, data)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ExportOne writes the entity with the type of dst and the passed ID to w as
+// JSON followed by a newline, such as for piping to a file while debugging.
+// Entities stored with JSONCodec are written as stored, while those stored
+// with other codecs are decoded and written as indented JSON.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) ExportOne(dst any, id any, w io.Writer) (err error) {
+	defer db.handleError("ExportOne", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	if codec.Name() != JSONCodec.Name() {
+		v := reflect.New(_type)
+		err = db.decode(codec, typeName, key, data, v.Interface())
+		if err != nil {
+			return err
+		}
+
+		data, err = json.MarshalIndent(v.Interface(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal %s %q: %w", typeName, key, err)
+		}
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("unable to write %s %q: %w", typeName, key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type exportItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+type exportGob struct {
+	Num  int64 `burrowdb:"ID"`
+	Name string
+}
+
+func TestExportImportAll(t *testing.T) {
+	opts := []newDBOption{WithCodecForType("exportGob", GobCodec)}
+	db, err := NewDB(append(opts, WithDir(t.TempDir()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(exportItem{ID: 1, Status: "a"}, exportItem{ID: 2, Status: "b"}, exportGob{Num: 5, Name: "five"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = db.ExportAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Fatalf("got %d lines, want one per entity:\n%s", lines, buf.String())
+	}
+
+	imported, err := NewDB(append(opts, WithDir(t.TempDir()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = imported.ImportAll(&buf, ImportOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g exportGob
+	err = imported.GetByID(&g, 5)
+	if err != nil || g.Name != "five" {
+		t.Fatalf("got %+v, %v", g, err)
+	}
+
+	err = imported.RebuildIndexes(exportItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []exportItem
+	err = imported.GetByField(&items, "Status", "b")
+	if err != nil || len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("got %+v, %v", items, err)
+	}
+}
+
+func TestImportAllInvalidType(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.ImportAll(strings.NewReader(`{"_type":"../x","_id":"1","_codec":"json","data":{}}`), ImportOverwrite)
+	if err == nil {
+		t.Fatal("got no error importing a type outside the db dir")
+	}
+}
+
+func TestImportModes(t *testing.T) {
+	source, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = source.PutAll(exportItem{ID: 1, Status: "imported"}, exportItem{ID: 2, Status: "imported"}, exportItem{ID: 3, Status: "imported"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = source.ExportAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode    ImportMode
+		want    []string
+		report  ImportReport
+		wantErr error
+	}{
+		{ImportOverwrite, []string{"imported", "imported", "imported"}, ImportReport{Imported: 3}, nil},
+		{ImportSkip, []string{"imported", "stored", "imported"}, ImportReport{Imported: 2, Skipped: 1}, nil},
+		{ImportFail, []string{"imported", "stored"}, ImportReport{Imported: 1}, ErrEntityExists},
+	}
+	for _, test := range tests {
+		db, err := NewDB(WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Put(exportItem{ID: 2, Status: "stored"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := db.ImportAll(bytes.NewReader(buf.Bytes()), test.mode)
+		if !errors.Is(err, test.wantErr) {
+			t.Fatalf("got %v importing with mode %d, want %v", err, test.mode, test.wantErr)
+		}
+		if report != test.report {
+			t.Fatalf("got %+v importing with mode %d, want %+v", report, test.mode, test.report)
+		}
+
+		var items []exportItem
+		err = db.GetAll(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Status)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Fatalf("got %v with mode %d, want %v", got, test.mode, test.want)
+		}
+	}
+}
+
+func TestExportOne(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithCodecForType("exportGob", GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := exportItem{ID: 1, Status: "open"}
+	gobItem := exportGob{Num: 2, Name: "gob"}
+	err = db.PutAll(item, gobItem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = db.ExportOne(exportItem{}, 1, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotItem exportItem
+	err = json.Unmarshal(buf.Bytes(), &gotItem)
+	if err != nil || gotItem != item {
+		t.Fatalf("got %+v, %v, want %+v", gotItem, err, item)
+	}
+
+	buf.Reset()
+	err = db.ExportOne(&exportGob{}, 2, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotGob exportGob
+	err = json.Unmarshal(buf.Bytes(), &gotGob)
+	if err != nil || gotGob != gobItem {
+		t.Fatalf("got %+v, %v, want %+v", gotGob, err, gobItem)
+	}
+
+	err = db.ExportOne(exportItem{}, 3, &buf)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestImportBatches(t *testing.T) {
+	source, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 25 {
+		err = source.Put(exportItem{ID: i + 1, Status: "imported"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = source.ExportAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the batches sync, as there are fewer writes than the sync count.
+	db, err := NewDB(WithDir(t.TempDir()), WithSyncEvery(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []int
+	report, err := db.ImportAll(&buf, ImportOverwrite, WithImportBatchSize(10), WithImportProgress(func(r ImportReport) {
+		progress = append(progress, r.Imported)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 25 {
+		t.Fatalf("got %+v, want 25 imported", report)
+	}
+	if !slices.Equal(progress, []int{10, 20, 25}) {
+		t.Fatalf("got progress %v, want [10 20 25]", progress)
+	}
+	if n := syncs(db); n != 3 {
+		t.Fatalf("got %d syncs, want one for each batch", n)
+	}
+
+	var items []exportItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 25 {
+		t.Fatalf("got %d entities, want 25", len(items))
+	}
+
+	_, err = db.ImportAll(strings.NewReader(""), ImportOverwrite, WithImportBatchSize(-1))
+	if err == nil {
+		t.Fatal("got no error for a negative batch size")
+	}
+}
@@ -0,0 +1,115 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const nameTagOption = "name=" // Struct tag option prefixing the name a field is stored under.
+
+// storedFieldName returns the name of the JSON member the passed field is
+// stored under, which is the name set by `burrowdb:"name=..."` if it has one
+// and its JSON name otherwise. False is returned if the field isn't encoded.
+func storedFieldName(field reflect.StructField) (string, bool) {
+	name, ok := jsonFieldName(field)
+	if !ok {
+		return "", false
+	}
+
+	if opts, _ := parseTag(field); opts.name != "" {
+		return opts.name, true
+	}
+
+	return name, true
+}
+
+// renamedFields returns the stored names of the fields of the passed type which
+// are renamed by `burrowdb:"name=..."`, keyed by their JSON names.
+func renamedFields(_type reflect.Type) map[string]string {
+	if _type.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names map[string]string
+	for _, field := range reflect.VisibleFields(_type) {
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+
+		name, ok := jsonFieldName(field)
+		stored, _ := storedFieldName(field)
+		if !ok || stored == name {
+			continue
+		}
+
+		if names == nil {
+			names = map[string]string{}
+		}
+		names[name] = stored
+	}
+
+	return names
+}
+
+// renameFields replaces the JSON name of each renamed field of the passed type
+// in the encoded data with the name it is stored under. Entities which aren't
+// stored as JSON are left alone. ErrInvalidTag is returned if two members would
+// be stored under the same name.
+func (db *BurrowDB) renameFields(_type reflect.Type, codec Codec, data []byte) ([]byte, error) {
+	names := renamedFields(_type)
+	if len(names) == 0 || codec.Name() != JSONCodec.Name() {
+		return data, nil
+	}
+
+	obj := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal value for renaming: %w", err)
+	}
+
+	renamed := make(map[string]json.RawMessage, len(obj))
+	for name, raw := range obj {
+		stored, ok := names[name]
+		if !ok {
+			stored = name
+		}
+
+		if _, ok := renamed[stored]; ok {
+			return nil, fmt.Errorf("%w: more than one field of %s is stored as %q", ErrInvalidTag, _type, stored)
+		}
+		renamed[stored] = raw
+	}
+
+	return db.marshalObject(renamed)
+}
+
+// restoreFieldNames replaces the stored name of each renamed field of the
+// passed type in the stored data with its JSON name, undoing renameFields.
+func (db *BurrowDB) restoreFieldNames(_type reflect.Type, codec Codec, data []byte) ([]byte, error) {
+	names := renamedFields(_type)
+	if len(names) == 0 || codec.Name() != JSONCodec.Name() {
+		return data, nil
+	}
+
+	obj := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal data for renaming: %w", err)
+	}
+
+	stored := make(map[string]string, len(names))
+	for name, storedName := range names {
+		stored[storedName] = name
+	}
+
+	restored := make(map[string]json.RawMessage, len(obj))
+	for name, raw := range obj {
+		if jsonName, ok := stored[name]; ok {
+			name = jsonName
+		}
+		restored[name] = raw
+	}
+
+	return db.marshalObject(restored)
+}
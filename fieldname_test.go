@@ -0,0 +1,102 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type renamedItem struct {
+	ID      int
+	Name    string `json:"displayName" burrowdb:"name=n"`
+	Created int64  `json:"createdAt" burrowdb:"index,name=c"`
+	Data    []byte `burrowdb:"binary,name=d"`
+	Note    string `json:"note"`
+}
+
+type clashingItem struct {
+	ID    int
+	Name  string `burrowdb:"name=Other"`
+	Other string
+}
+
+func TestFieldNames(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithStrictTags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := renamedItem{ID: 1, Name: "first", Created: 42, Data: []byte("raw"), Note: "kept"}
+	err = db.Put(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Binary fields are packed, so read a copy of the entity without them.
+	err = db.Put(renamedItem{ID: 2, Name: "second", Created: 43, Note: "kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := db.GetRawJSON("renamedItem", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ID", "n", "c", "note"} {
+		if _, ok := members[name]; !ok {
+			t.Fatalf("got stored members %s, want %q among them", data, name)
+		}
+	}
+	for _, name := range []string{"displayName", "createdAt"} {
+		if _, ok := members[name]; ok {
+			t.Fatalf("got stored members %s, want no %q", data, name)
+		}
+	}
+
+	var got renamedItem
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != want.Name || got.Created != want.Created || string(got.Data) != string(want.Data) || got.Note != want.Note {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	var fields renamedItem
+	err = db.GetFields(&fields, 1, "Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields.Name != want.Name || fields.Note != "" {
+		t.Fatalf("got %+v selecting Name, want only %q", fields, want.Name)
+	}
+
+	var all []renamedItem
+	err = db.GetAll(&all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Name != "second" {
+		t.Fatalf("got %+v, want both entities", all)
+	}
+
+	// The JSON names are still used by encoding/json elsewhere.
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(encoded, &members); err != nil || members["displayName"] == nil {
+		t.Fatalf("got %s from json.Marshal, want the json tag names", encoded)
+	}
+
+	err = db.Put(clashingItem{ID: 1, Name: "a", Other: "b"})
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("got %v putting fields stored under the same name, want %v", err, ErrInvalidTag)
+	}
+}
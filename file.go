@@ -0,0 +1,239 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const tempFilePrefix = ".tmp-" // Prefix of the temp files used for atomic writes.
+
+// WithTempDir specifies the directory where temp files are written before being
+// renamed into place. The directory must be on the same device as the db dir
+// so that the rename is atomic. By default temp files are written in the
+// directory of the file being replaced.
+func WithTempDir(dir string) newDBOption {
+	return func(db *BurrowDB) error {
+		db.tempDir = dir
+		return nil
+	}
+}
+
+// WithNoCreate specifies that the db must not create any directories. NewDB
+// fails if the db dir doesn't exist, and writes fail if the directory of the
+// entity's type doesn't, so every directory must be provisioned beforehand.
+// ErrMissingDir is returned in either case.
+func WithNoCreate() newDBOption {
+	return func(db *BurrowDB) error {
+		db.noCreate = true
+		return nil
+	}
+}
+
+// mkdirAll creates the passed directory and any missing parents, unless the db
+// must not create directories in which case ErrMissingDir is returned if it
+// doesn't exist. If the db syncs written files and their directories, the
+// created directories are synced with them so that their entries in their
+// parents survive a crash.
+func (db *BurrowDB) mkdirAll(dir string) error {
+	if db.noCreate {
+		_, err := os.Stat(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %q", ErrMissingDir, dir)
+		}
+		return err
+	}
+
+	if db.syncer == nil || db.noDirSync {
+		return os.MkdirAll(dir, db.dirPerm())
+	}
+
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		_, err := os.Stat(d)
+		if !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		missing = append(missing, d)
+
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	err := os.MkdirAll(dir, db.dirPerm())
+	for _, d := range missing {
+		db.markWritten(d)
+	}
+
+	return err
+}
+
+// WithMmap specifies that entities of at least minSize bytes should be memory
+// mapped, rather than read into a buffer, when they are decoded by GetByID and
+// scans. This saves allocating and copying large entities. Files are read
+// normally on platforms which don't support memory mapping and when mapping
+// fails.
+//
+// The mapping is removed once the entity has been decoded, so a custom Codec
+// must not keep references to the data it is passed.
+func WithMmap(minSize int64) newDBOption {
+	return func(db *BurrowDB) error {
+		if minSize < 0 {
+			return errors.New("mmap minimum size must not be negative")
+		}
+
+		db.mmap = true
+		db.mmapMinSize = minSize
+		return nil
+	}
+}
+
+// checkTempDir creates the temp dir, if one is set, and checks that it is on
+// the same device as the db dir.
+func (db *BurrowDB) checkTempDir() error {
+	if db.tempDir == "" {
+		return nil
+	}
+
+	err := db.mkdirAll(db.tempDir)
+	if err != nil {
+		return fmt.Errorf("unable to create temp dir (%q): %w", db.tempDir, err)
+	}
+
+	same, err := sameDevice(db.tempDir, db.dir)
+	if err != nil {
+		return fmt.Errorf("unable to compare devices: %w", err)
+	}
+
+	if !same {
+		return fmt.Errorf("%w: %q is not on the same device as %q", ErrTempDirDevice, db.tempDir, db.dir)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file and renames it over filename so
+// that readers never observe a partially written file. The temp file is
+// removed if the write fails, and ErrNoSpace is returned if the filesystem is
+// full. If the db has a write-ahead log, the write is recorded in it first so
+// that it can be completed after a crash.
+func (db *BurrowDB) writeFileAtomic(filename string, data []byte) error {
+	if !db.wal {
+		return db.replaceFile(filename, data)
+	}
+
+	intent, err := db.logIntent(OpPut, filename, data)
+	if err != nil {
+		return err
+	}
+
+	err = db.replaceFile(filename, data)
+	if err != nil {
+		db.clearIntent(intent)
+		return err
+	}
+
+	return db.clearIntent(intent)
+}
+
+// replaceFile atomically replaces the contents of filename with data, as
+// described by writeFileAtomic, without using the write-ahead log.
+func (db *BurrowDB) replaceFile(filename string, data []byte) error {
+	temp, err := db.writeTemp(filename, data)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(temp, filename)
+	if err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("unable to rename temp file: %w", noSpace(err))
+	}
+
+	db.markRenamed(filename)
+
+	return nil
+}
+
+// writeTemp writes data to a new temp file which can be renamed over filename,
+// returning its path, syncing it first if the db syncs written files. The temp
+// file is removed if the write fails.
+func (db *BurrowDB) writeTemp(filename string, data []byte) (string, error) {
+	err := db.checkWritable()
+	if err != nil {
+		return "", err
+	}
+
+	dir := db.tempDir
+	if dir == "" {
+		dir = filepath.Dir(filename)
+	}
+
+	release, err := db.acquireFile()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	f, err := createTemp(dir, db.filePerm())
+	if err != nil {
+		return "", fmt.Errorf("unable to create temp file: %w", noSpace(err))
+	}
+
+	_, err = writeFile(f, data)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to write file: %w", noSpace(err))
+	}
+
+	// Sync the contents before the file is renamed into place, so a crash
+	// can't leave a partially written file in place of the previous one.
+	if db.syncer != nil {
+		err = f.Sync()
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", fmt.Errorf("unable to sync file: %w", noSpace(err))
+		}
+		db.syncer.countSynced()
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to close file: %w", noSpace(err))
+	}
+
+	return f.Name(), nil
+}
+
+// writeFile writes data to the passed temp file. It is a variable so that tests
+// can make writes fail.
+var writeFile = (*os.File).Write
+
+// noSpace returns the passed error wrapped with ErrNoSpace if it was caused by
+// the filesystem being full.
+func noSpace(err error) error {
+	if isNoSpace(err) {
+		return fmt.Errorf("%w: %w", ErrNoSpace, err)
+	}
+	return err
+}
+
+// createTemp creates a new temp file in dir with the passed permissions. Unlike
+// os.CreateTemp, the file is created as os.WriteFile would create it.
+func createTemp(dir string, perm os.FileMode) (*os.File, error) {
+	for {
+		name := filepath.Join(dir, tempFilePrefix+strconv.FormatUint(rand.Uint64(), 36))
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+}
@@ -0,0 +1,228 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fileItem struct {
+	ID   int
+	Data string
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(fileItem{ID: 1, Data: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers which don't take the lock, such as other processes, must only
+	// ever see a whole entity while it's being replaced.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			var item fileItem
+			data, err := os.ReadFile(filepath.Join(dir, "fileItem", "1"))
+			if err == nil {
+				err = json.Unmarshal(data, &item)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	for i := range 200 {
+		err = db.Put(fileItem{ID: 1, Data: strings.Repeat("b", i*100)})
+		if err != nil {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("got %v reading during a write", err)
+	default:
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "tmp")
+	db, err := NewDB(WithDir(filepath.Join(dir, "store")), WithTempDir(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(fileItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d files left in the temp dir", len(entries))
+	}
+
+	err = db.GetByID(&fileItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Temp files are created in the temp dir rather than beside the file they
+	// replace.
+	temp, err := db.writeTemp(db.entityPath("fileItem", "1"), []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(temp)
+	if filepath.Dir(temp) != tempDir {
+		t.Fatalf("got temp file %s, want it in %s", temp, tempDir)
+	}
+
+	// So puts fail if the temp dir can't be written to.
+	err = os.Remove(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(tempDir, nil, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(fileItem{ID: 2})
+	if err == nil {
+		t.Fatal("got no error with the temp dir replaced by a file")
+	}
+}
+
+func TestTempDirDevice(t *testing.T) {
+	// Look for a dir on another device, which most systems don't guarantee.
+	var other string
+	for _, candidate := range []string{"/dev/shm", "/run", "/tmp"} {
+		if same, err := sameDevice(candidate, t.TempDir()); err == nil && !same {
+			other = candidate
+			break
+		}
+	}
+	if other == "" {
+		t.Skip("no dir on another device")
+	}
+
+	tempDir, err := os.MkdirTemp(other, "burrowdb-")
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	_, err = NewDB(WithDir(t.TempDir()), WithTempDir(tempDir))
+	if !errors.Is(err, ErrTempDirDevice) {
+		t.Fatalf("got %v, want %v", err, ErrTempDirDevice)
+	}
+}
+
+func TestNoCreate(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewDB(WithDir(filepath.Join(dir, "missing")), WithNoCreate())
+	if !errors.Is(err, ErrMissingDir) {
+		t.Fatalf("got %v opening a missing dir, want %v", err, ErrMissingDir)
+	}
+
+	db, err := NewDB(WithDir(dir), WithNoCreate())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(fileItem{ID: 1})
+	if !errors.Is(err, ErrMissingDir) {
+		t.Fatalf("got %v without a type dir, want %v", err, ErrMissingDir)
+	}
+
+	err = os.Mkdir(filepath.Join(dir, "fileItem"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(fileItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoSpace(t *testing.T) {
+	f, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no /dev/full")
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte("x"))
+	if err == nil {
+		t.Fatal("got no error writing to /dev/full")
+	}
+
+	if got := noSpace(err); !errors.Is(got, ErrNoSpace) || !errors.Is(got, err) {
+		t.Fatalf("got %v, want %v wrapping %v", got, ErrNoSpace, err)
+	}
+
+	other := errors.New("other")
+	if got := noSpace(other); got != other {
+		t.Fatalf("got %v, want other errors left alone", got)
+	}
+
+	// A put whose temp file can't be written fails with ErrNoSpace, leaving no
+	// temp file behind.
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile = func(_ *os.File, data []byte) (int, error) { return f.Write(data) }
+	defer func() { writeFile = (*os.File).Write }()
+
+	err = db.Put(fileItem{ID: 1})
+	if !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("got %v, want %v", err, ErrNoSpace)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "fileItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			t.Fatalf("got temp file %s left behind", entry.Name())
+		}
+	}
+}
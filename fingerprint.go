@@ -0,0 +1,71 @@
+package burrowdb
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+)
+
+// Fingerprint returns a hex encoded SHA-256 digest of every entity in the store,
+// for detecting changes or checking that two stores hold the same data. The
+// name of each type and the key and contents of each of its entities are
+// hashed in ascending order, so stores with identical entities have the same
+// fingerprint whatever order they were written in. The stored contents are
+// hashed, so fields encrypted with WithEncryption differ each time they are
+// written. Files which can be regenerated, such as indexes, aren't hashed.
+// Every type is read locked while the digest is computed, so it is consistent
+// across types.
+func (db *BurrowDB) Fingerprint() (_ string, err error) {
+	defer db.handleError("Fingerprint", &err)
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return "", err
+	}
+	slices.Sort(typeNames)
+
+	// Make sure every stored type has a lock for rLockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	unlock := db.locks.rLockAll()
+	defer unlock()
+
+	h := sha256.New()
+	for _, typeName := range typeNames {
+		if isReservedType(typeName) {
+			continue
+		}
+
+		keys, err := db.keys(typeName)
+		if err != nil {
+			return "", err
+		}
+		slices.Sort(keys)
+
+		writeField(h, []byte(typeName))
+		writeField(h, binary.AppendUvarint(nil, uint64(len(keys))))
+		for _, key := range keys {
+			data, err := db.readEntity(typeName, key)
+			if err != nil {
+				return "", fmt.Errorf("unable to read %s %q: %w", typeName, key, err)
+			}
+
+			writeField(h, []byte(key))
+			writeField(h, data)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField writes b to h preceded by its length, so that the boundaries
+// between fields are part of the digest.
+func writeField(h hash.Hash, b []byte) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	h.Write(b)
+}
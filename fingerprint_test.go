@@ -0,0 +1,85 @@
+package burrowdb
+
+import "testing"
+
+type fingerprintItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestFingerprint(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty, err := db.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(fingerprintItem{ID: 1, Status: "open"}, fingerprintItem{ID: 2, Status: "closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := db.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == empty {
+		t.Fatal("got the same fingerprint after putting entities")
+	}
+
+	// A copy has the same fingerprint.
+	clone, err := db.CopyTo(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cloned, err := clone.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloned != before {
+		t.Fatalf("got %s for the copy, want %s", cloned, before)
+	}
+
+	// So does a store written in a different order.
+	other, err := NewDB(WithDir(t.TempDir()))
+	if err == nil {
+		err = other.PutAll(fingerprintItem{ID: 2, Status: "closed"}, fingerprintItem{ID: 1, Status: "open"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reordered, err := other.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reordered != before {
+		t.Fatalf("got %s for a store written in another order, want %s", reordered, before)
+	}
+
+	err = db.Put(fingerprintItem{ID: 2, Status: "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := db.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Fatal("got the same fingerprint after overwriting an entity")
+	}
+
+	cloned, err = clone.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloned != before {
+		t.Fatal("got the copy's fingerprint changed by a put to the original")
+	}
+}
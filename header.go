@@ -0,0 +1,53 @@
+package burrowdb
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const fileVersion = 1 // Version of the entity file format written after the magic.
+
+var fileMagic = []byte("BURROWDB") // Magic starting the header of entity files.
+
+// WithFileHeader specifies that each entity file should start with a short
+// header made of a magic string and a format version, identifying it as written
+// by BurrowDB. Reading a file without a valid header fails with ErrBadMagic
+// rather than decoding a foreign file. The option must be used every time a dir
+// written with it is opened, and doesn't apply to types stored in an append log.
+func WithFileHeader() newDBOption {
+	return func(db *BurrowDB) error {
+		db.fileHeader = true
+		return nil
+	}
+}
+
+// addHeader returns data preceded by the entity file header if the db uses
+// WithFileHeader.
+func (db *BurrowDB) addHeader(data []byte) []byte {
+	if !db.fileHeader {
+		return data
+	}
+
+	header := append(bytes.Clone(fileMagic), fileVersion)
+	return append(header, data...)
+}
+
+// stripHeader returns the contents of the file of the entity of the named type
+// with the passed key without its header, if the db uses WithFileHeader.
+// ErrBadMagic is returned if the header is missing or of an unknown version.
+func (db *BurrowDB) stripHeader(typeName, key string, data []byte) ([]byte, error) {
+	if !db.fileHeader {
+		return data, nil
+	}
+
+	rest, ok := bytes.CutPrefix(data, fileMagic)
+	if !ok || len(rest) == 0 {
+		return nil, fmt.Errorf("%w: %s %q has no header", ErrBadMagic, typeName, key)
+	}
+
+	if rest[0] != fileVersion {
+		return nil, fmt.Errorf("%w: %s %q has unknown format version %d", ErrBadMagic, typeName, key, rest[0])
+	}
+
+	return rest[1:], nil
+}
@@ -0,0 +1,59 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type headerItem struct {
+	ID   int
+	Name string
+}
+
+func TestFileHeader(t *testing.T) {
+	for _, opts := range [][]newDBOption{nil, {WithContentAddressing()}, {WithMmap(0)}} {
+		dir := t.TempDir()
+		db, err := NewDB(append(opts, WithDir(dir), WithFileHeader())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.PutBatch(headerItem{ID: 1, Name: "a"}, headerItem{ID: 2, Name: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Put(headerItem{ID: 2, Name: "c"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "headerItem", "1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, fileMagic) {
+			t.Fatalf("got %q, want the file to start with the header", data)
+		}
+
+		var item headerItem
+		err = db.GetByID(&item, 2)
+		if err != nil || item.Name != "c" {
+			t.Fatalf("got %+v, %v", item, err)
+		}
+
+		// A file written without the header is rejected.
+		err = os.WriteFile(filepath.Join(dir, "headerItem", "3"), []byte(`{"ID":3}`), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.GetByID(&item, 3)
+		if !errors.Is(err, ErrBadMagic) {
+			t.Fatalf("got %v, want ErrBadMagic", err)
+		}
+	}
+}
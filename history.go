@@ -0,0 +1,217 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+const historyDirName = ".history" // Name of the directory in each type dir holding the previous versions of its entities.
+
+// WithVersionHistory specifies that each put of an entity of the named type
+// which replaces a stored one should first archive the stored value, keeping
+// the keep most recent versions of each entity, which can be read with
+// History. Versions are stored in the type dir under .history/ID/, numbered in
+// the order they were archived, along with the codec they are decoded with.
+// They are kept when the entity is deleted, and can be used to recover it.
+func WithVersionHistory(typeName string, keep int) newDBOption {
+	return func(db *BurrowDB) error {
+		if keep < 1 {
+			return fmt.Errorf("number of versions of %s to keep must be positive", typeName)
+		}
+
+		if db.history == nil {
+			db.history = map[string]int{}
+		}
+		db.history[typeName] = keep
+
+		return nil
+	}
+}
+
+// WithDeleteHistory specifies that Delete, and the other methods removing
+// individual entities such as DeleteByField, should also archive the final
+// version of each entity of the named type, which must use WithVersionHistory.
+// Entities removed by retention limits, Truncate or Reset aren't archived.
+func WithDeleteHistory(typeName string) newDBOption {
+	return func(db *BurrowDB) error {
+		if db.deleteHistory == nil {
+			db.deleteHistory = map[string]bool{}
+		}
+		db.deleteHistory[typeName] = true
+
+		return nil
+	}
+}
+
+// History sets the slice pointed to by dst to the archived versions of the
+// entity with its element type and the passed ID, newest first. The stored
+// entity isn't included. The slice is empty if no versions have been archived.
+func (db *BurrowDB) History(dst any, id any) (err error) {
+	defer db.handleError("History", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	versions, err := db.versions(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(versions))
+	for _, version := range slices.Backward(versions) {
+		v, err := db.readVersion(elemType, key, version)
+		if err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// archiveVersion archives the stored entity of the named type with the passed
+// key as its newest version, removing the oldest versions beyond the number
+// kept, if the type keeps a version history. Nothing is archived if there is
+// no stored entity. The lock of the type must be held for writing.
+func (db *BurrowDB) archiveVersion(typeName, key string) error {
+	keep, ok := db.history[typeName]
+	if !ok {
+		return nil
+	}
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if errors.Is(err, ErrNoSuchEntity) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	versions, err := db.versions(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	var next uint64 = 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	err = db.mkdirAll(db.historyDir(typeName, key))
+	if err != nil {
+		return fmt.Errorf("unable to create history dir: %w", err)
+	}
+
+	data = append([]byte(codec.Name()+"\n"), data...)
+	err = db.writeFileAtomic(db.versionPath(typeName, key, next), data)
+	if err != nil {
+		return fmt.Errorf("unable to archive version: %w", err)
+	}
+
+	for _, version := range versions[:max(len(versions)+1-keep, 0)] {
+		err = os.Remove(db.versionPath(typeName, key, version))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove old version: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// versions returns the numbers of the archived versions of the entity of the
+// named type with the passed key, oldest first.
+func (db *BurrowDB) versions(typeName, key string) ([]uint64, error) {
+	entries, err := os.ReadDir(db.historyDir(typeName, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read history dir: %w", err)
+	}
+
+	var versions []uint64
+	for _, entry := range entries {
+		// Skip temp files left by interrupted archives.
+		version, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+
+	return versions, nil
+}
+
+// readVersion decodes the archived version of the entity of the passed type
+// with the passed key, returning a pointer to it.
+func (db *BurrowDB) readVersion(_type reflect.Type, key string, version uint64) (reflect.Value, error) {
+	typeName := db.typeName(_type)
+	data, err := os.ReadFile(db.versionPath(typeName, key, version))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("unable to read version %d: %w", version, err)
+	}
+
+	name, data, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("version %d of %s %q has no codec", version, typeName, key)
+	}
+
+	codec, ok := db.knownCodec(string(name))
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%w: version %d of %s %q is stored with the unknown codec %q", ErrCodecMismatch, version, typeName, key, name)
+	}
+
+	v := reflect.New(_type)
+	err = db.decode(codec, typeName, key, data, v.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return v, nil
+}
+
+// historyDir returns the path of the dir holding the archived versions of the
+// entity of the named type with the passed key.
+func (db *BurrowDB) historyDir(typeName, key string) string {
+	return fmt.Sprintf("%s/%s/%s", db.typeDir(typeName), historyDirName, key)
+}
+
+// versionPath returns the path of the file holding the passed archived version
+// of the entity of the named type with the passed key.
+func (db *BurrowDB) versionPath(typeName, key string, version uint64) string {
+	return fmt.Sprintf("%s/%020d", db.historyDir(typeName, key), version)
+}
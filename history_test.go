@@ -0,0 +1,121 @@
+package burrowdb
+
+import (
+	"slices"
+	"testing"
+)
+
+type historyItem struct {
+	ID   int
+	Name string
+}
+
+func TestVersionHistory(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithVersionHistory("historyItem", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		err = db.Put(historyItem{ID: 1, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.PutAll(historyItem{ID: 1, Name: "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var history []historyItem
+	err = db.History(&history, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []historyItem{{1, "e"}, {1, "d"}, {1, "c"}}
+	if !slices.Equal(history, want) {
+		t.Fatalf("got history %v, want %v", history, want)
+	}
+
+	versions, err := db.versions("historyItem", db.entityKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("got %d archived versions, want 3", len(versions))
+	}
+
+	// Deleting without WithDeleteHistory doesn't archive the final version.
+	err = db.Delete(&historyItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.History(&history, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(history, want) {
+		t.Fatalf("got history %v after delete, want %v", history, want)
+	}
+
+	var items []historyItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("got %v, want the history hidden from GetAll", items)
+	}
+
+	err = db.History(&history, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("got history %v of an entity never replaced, want none", history)
+	}
+
+	if cfg := db.Config(); cfg.VersionHistory["historyItem"] != 3 || cfg.DeleteHistory != nil {
+		t.Fatalf("got %v and %v in config, want 3 versions of historyItem kept", cfg.VersionHistory, cfg.DeleteHistory)
+	}
+}
+
+func TestDeleteHistory(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithVersionHistory("historyItem", 2), WithDeleteHistory("historyItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(historyItem{ID: 1, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Delete(&historyItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var history []historyItem
+	err = db.History(&history, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []historyItem{{1, "a"}}
+	if !slices.Equal(history, want) {
+		t.Fatalf("got history %v, want %v", history, want)
+	}
+
+	if cfg := db.Config(); !slices.Equal(cfg.DeleteHistory, []string{"historyItem"}) {
+		t.Fatalf("got %v in config, want [historyItem]", cfg.DeleteHistory)
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithVersionHistory("historyItem", 0))
+	if err == nil {
+		t.Fatal("got no error keeping 0 versions")
+	}
+}
@@ -0,0 +1,57 @@
+package burrowdb
+
+import "errors"
+
+// WithRawWriteHook specifies a function called with the bytes of each entity
+// written by Put and the methods built on it, as they are stored, after the
+// write succeeds. The bytes are those after encoding, encryption and any
+// header, so they can be mirrored exactly to another system. With
+// WithContentAddressing they are the reference stored in the entity file, and
+// with WithAppendLog the encoded entity appended to the log.
+//
+// The hook is called holding the type's lock, so it must not use the db, and
+// must not modify or keep data.
+func WithRawWriteHook(hook func(typeName, key string, data []byte)) newDBOption {
+	return func(db *BurrowDB) error {
+		if hook == nil {
+			return errors.New("raw write hook is nil")
+		}
+
+		db.rawWriteHook = hook
+		return nil
+	}
+}
+
+// WithRawReadHook specifies a function called with the bytes of each entity
+// read from its file, as they are stored, before they are decoded. The bytes
+// are those passed to the hook given to WithRawWriteHook when the entity was
+// written.
+//
+// The hook is called holding the type's lock, so it must not use the db, and
+// must not modify or keep data, which may be memory mapped.
+func WithRawReadHook(hook func(typeName, key string, data []byte)) newDBOption {
+	return func(db *BurrowDB) error {
+		if hook == nil {
+			return errors.New("raw read hook is nil")
+		}
+
+		db.rawReadHook = hook
+		return nil
+	}
+}
+
+// wroteRaw calls the db's raw write hook, if any, with the stored bytes of the
+// entity of the named type with the passed key.
+func (db *BurrowDB) wroteRaw(typeName, key string, data []byte) {
+	if db.rawWriteHook != nil {
+		db.rawWriteHook(typeName, key, data)
+	}
+}
+
+// readRaw calls the db's raw read hook, if any, with the stored bytes of the
+// entity of the named type with the passed key.
+func (db *BurrowDB) readRaw(typeName, key string, data []byte) {
+	if db.rawReadHook != nil {
+		db.rawReadHook(typeName, key, data)
+	}
+}
@@ -0,0 +1,71 @@
+package burrowdb
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+type hookItem struct {
+	ID   int
+	Name string
+}
+
+func TestRawHooks(t *testing.T) {
+	var written, read [][]byte
+	db, err := NewDB(
+		WithDir(t.TempDir()),
+		WithFileHeader(),
+		WithRawWriteHook(func(typeName, key string, data []byte) {
+			if typeName != "hookItem" || key != "1" {
+				t.Errorf("got write of %s %q, want hookItem 1", typeName, key)
+			}
+			written = append(written, bytes.Clone(data))
+		}),
+		WithRawReadHook(func(typeName, key string, data []byte) {
+			read = append(read, bytes.Clone(data))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(hookItem{ID: 1, Name: "audited"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := os.ReadFile(db.entityPath("hookItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 1 || !bytes.Equal(written[0], stored) {
+		t.Fatalf("got writes %q, want the stored bytes %q", written, stored)
+	}
+
+	var got hookItem
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read) != 1 || !bytes.Equal(read[0], stored) {
+		t.Fatalf("got reads %q, want the stored bytes %q", read, stored)
+	}
+
+	// Batches call the hook once each entity is in place.
+	err = db.PutBatch(hookItem{ID: 1, Name: "batched"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err = os.ReadFile(db.entityPath("hookItem", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 2 || !bytes.Equal(written[1], stored) {
+		t.Fatalf("got writes %q, want the batch's stored bytes %q", written, stored)
+	}
+}
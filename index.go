@@ -0,0 +1,674 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	indexDirName   = ".index" // Name of the directory in each type dir holding its indexes.
+	indexTagValue  = "index"  // Struct tag value to specify a secondary index on a field.
+	uniqueTagValue = "unique" // Struct tag value to specify a unique index on a field.
+	enumTagOption  = "enum"   // Struct tag option to store an index with one file per value.
+
+	omitEmptyTagOption = "omitempty" // Struct tag option to leave zero values out of an index.
+
+	// Prefix of the name of each value's file in a per-value index, which
+	// keeps the names of empty values and those starting with a dot valid.
+	indexValuePrefix = "="
+)
+
+// index maps the key of each indexed field value to the keys of the entities
+// with that value, sorted in ascending order.
+type index map[string][]string
+
+// add records that the entity with the passed key has the passed value.
+func (idx index) add(value, key string) {
+	keys := idx[value]
+	i, found := slices.BinarySearchFunc(keys, key, compareKeys)
+	if !found {
+		idx[value] = slices.Insert(keys, i, key)
+	}
+}
+
+// remove removes the entity with the passed key from every value.
+func (idx index) remove(key string) {
+	for value, keys := range idx {
+		keys = slices.DeleteFunc(keys, func(k string) bool {
+			return k == key
+		})
+
+		if len(keys) == 0 {
+			delete(idx, value)
+		} else {
+			idx[value] = keys
+		}
+	}
+}
+
+// indexSpec describes an indexed field, or combination of fields, of a type.
+type indexSpec struct {
+	name   string                // name of the index, which is the field name for a single field.
+	fields []reflect.StructField // fields whose values are indexed, in order.
+	unique bool
+	enum   bool // whether the index is stored with one file per value.
+
+	omitEmpty bool // whether zero values of the field aren't indexed.
+}
+
+// value returns the key of the value of the passed struct used in the index.
+// Values with a nil pointer field, or a zero field if the index omits empty
+// values, aren't indexed.
+func (spec indexSpec) value(v reflect.Value) (string, bool) {
+	if len(spec.fields) == 1 {
+		field := v.FieldByIndex(spec.fields[0].Index)
+		if spec.omitEmpty && field.IsZero() {
+			return "", false
+		}
+		return indexValue(field)
+	}
+
+	values := make([]string, len(spec.fields))
+	for i, field := range spec.fields {
+		value, ok := indexValue(v.FieldByIndex(field.Index))
+		if !ok {
+			return "", false
+		}
+		values[i] = value
+	}
+
+	return compositeValue(values), true
+}
+
+// indexSpecs returns the indexed fields of the passed struct type, followed by
+// its composite indexes given to WithCompositeIndex. Fields are
+// indexed with the struct tag `burrowdb:"index"`, or `burrowdb:"unique"` (or
+// `burrowdb:"index,unique"`) to also prevent two entities having the same value.
+//
+// Fields with only a few distinct values, such as a status, can be tagged
+// `burrowdb:"index,enum"` to store the IDs with each value in their own file.
+// GetByField then only reads the file of the value it is passed, however many
+// entities have other values. Adding omitempty, as in
+// `burrowdb:"unique,omitempty"`, leaves entities whose field has its zero value
+// out of the index, so that any number of them may leave a unique field unset.
+// It has no effect on fields which aren't indexed, such as the ID field.
+func (db *BurrowDB) indexSpecs(_type reflect.Type) []indexSpec {
+	var specs []indexSpec
+	for _, field := range reflect.VisibleFields(_type) {
+		opts, _ := parseTag(field)
+		if opts.index || opts.unique {
+			specs = append(specs, indexSpec{
+				name:      field.Name,
+				fields:    []reflect.StructField{field},
+				unique:    opts.unique,
+				enum:      opts.enum,
+				omitEmpty: opts.omitEmpty,
+			})
+		}
+	}
+
+	return append(specs, db.compositeSpecs(_type)...)
+}
+
+// indexValue returns the key of the passed field value used in an index. Nil
+// pointers aren't indexed.
+func indexValue(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	return keyFor(v.Interface()), true
+}
+
+// GetByField gets every entity with the element type of the slice pointed to by
+// dst whose indexed field has the passed value, replacing the slice's contents.
+// ErrNotIndexed is returned if the field isn't indexed.
+func (db *BurrowDB) GetByField(dst any, field string, value any) (err error) {
+	defer db.handleError("GetByField", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	specs := db.indexSpecs(elemType)
+	i := slices.IndexFunc(specs, func(spec indexSpec) bool {
+		return spec.name == field && len(spec.fields) == 1
+	})
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotIndexed, field)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.readIndexValue(typeName, specs[i], keyFor(value))
+	if err != nil {
+		return err
+	}
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return fmt.Errorf("unable to load entities indexed by %s: %w", field, err)
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// GetByFieldPage is like GetByField but sets the slice pointed to by dst to at
+// most limit of the matching entities, skipping the first offset of them in key
+// order, and returns the total number of matches so a client can page through
+// them. Only the entities in the page are loaded.
+func (db *BurrowDB) GetByFieldPage(dst any, field string, value any, offset, limit int) (total int, err error) {
+	defer db.handleError("GetByFieldPage", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return 0, ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return 0, ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return 0, ErrInvalidValueType
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid offset %d", offset)
+	}
+
+	if limit < 1 {
+		return 0, fmt.Errorf("invalid limit %d", limit)
+	}
+
+	specs := db.indexSpecs(elemType)
+	i := slices.IndexFunc(specs, func(spec indexSpec) bool {
+		return spec.name == field && len(spec.fields) == 1
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotIndexed, field)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.readIndexValue(typeName, specs[i], keyFor(value))
+	if err != nil {
+		return 0, err
+	}
+
+	total = len(keys)
+	keys = keys[min(offset, total):min(offset+limit, total)]
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load entities indexed by %s: %w", field, err)
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return total, nil
+}
+
+// DeleteByField removes every entity with the type of dst whose indexed field
+// has the passed value, along with its entries in every index of the type, and
+// returns the number removed. The matches are found with the field's index, so
+// entities aren't read, and the type is locked throughout so none can be added
+// in between. ErrNotIndexed is returned if the field isn't indexed.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) DeleteByField(dst any, field string, value any) (_ int, err error) {
+	defer db.handleError("DeleteByField", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return 0, ErrInvalidDstType
+	}
+
+	specs := db.indexSpecs(_type)
+	i := slices.IndexFunc(specs, func(spec indexSpec) bool {
+		return spec.name == field && len(spec.fields) == 1
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotIndexed, field)
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys, err := db.readIndexValue(typeName, specs[i], keyFor(value))
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, key := range keys {
+		err = db.delete(_type, key)
+		if errors.Is(err, ErrNoSuchEntity) {
+			// The index is stale, so drop the missing entity from it.
+			err = db.removeFromIndexes(_type, key)
+		} else if err == nil {
+			n++
+		}
+		if err != nil {
+			return n, fmt.Errorf("unable to delete %q: %w", key, err)
+		}
+	}
+
+	return n, nil
+}
+
+// RebuildIndexes regenerates every index of the type of dst from the stored
+// entities, replacing the existing index files. This repairs indexes which have
+// got out of sync with the entities, for example after a crash mid-write.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) RebuildIndexes(dst any) (err error) {
+	defer db.handleError("RebuildIndexes", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	return db.rebuildIndexes(_type)
+}
+
+// rebuildIndexes regenerates every index of the passed struct type as
+// RebuildIndexes does. The lock of the type must be held.
+func (db *BurrowDB) rebuildIndexes(_type reflect.Type) error {
+	typeName := db.typeName(_type)
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	specs := db.indexSpecs(_type)
+	indexes := make(map[string]index, len(specs))
+	for _, spec := range specs {
+		indexes[spec.name] = index{}
+	}
+
+	values, err := db.loadAll(_type, keys)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		key := keys[i]
+		for _, spec := range specs {
+			value, ok := spec.value(v.Elem())
+			if !ok {
+				continue
+			}
+
+			idx := indexes[spec.name]
+			if spec.unique && len(idx[value]) > 0 {
+				return fmt.Errorf("%w: %s %q is used by both %q and %q", ErrUniqueViolation, spec.name, value, idx[value][0], key)
+			}
+			idx.add(value, key)
+		}
+	}
+
+	// Remove the indexes of fields which are no longer indexed.
+	entries, err := os.ReadDir(db.indexDir(typeName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read index dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if _, ok := indexes[entry.Name()]; ok {
+			continue
+		}
+
+		err = os.RemoveAll(db.indexPath(typeName, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove stale index: %w", err)
+		}
+	}
+
+	return db.writeIndexes(_type, indexes)
+}
+
+// indexUpdates returns every index of the passed type updated for the entity
+// with the passed key being given the value v. ErrUniqueViolation is returned if
+// v would share the value of a unique field with another entity.
+func (db *BurrowDB) indexUpdates(_type reflect.Type, key string, v reflect.Value) (map[string]index, error) {
+	indexes, err := db.readIndexes(_type)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.updateIndexes(_type, indexes, key, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexes, nil
+}
+
+// readIndexes returns every index of the passed type keyed by name, or nil if
+// the type has no indexes.
+func (db *BurrowDB) readIndexes(_type reflect.Type) (map[string]index, error) {
+	specs := db.indexSpecs(_type)
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	indexes := make(map[string]index, len(specs))
+	for _, spec := range specs {
+		idx, err := db.readIndex(db.typeName(_type), spec)
+		if err != nil {
+			return nil, err
+		}
+		indexes[spec.name] = idx
+	}
+
+	return indexes, nil
+}
+
+// updateIndexes updates the passed indexes of the passed type, keyed by name,
+// for the entity with the passed key being given the value v.
+// ErrUniqueViolation is returned if v would share the value of a unique field
+// with another entity.
+func (db *BurrowDB) updateIndexes(_type reflect.Type, indexes map[string]index, key string, v reflect.Value) error {
+	for _, spec := range db.indexSpecs(_type) {
+		idx := indexes[spec.name]
+		idx.remove(key)
+		value, ok := spec.value(v)
+		if ok {
+			if spec.unique && len(idx[value]) > 0 {
+				return fmt.Errorf("%w: %s %q is already used by %q", ErrUniqueViolation, spec.name, value, idx[value][0])
+			}
+			idx.add(value, key)
+		}
+	}
+
+	return nil
+}
+
+// removeFromIndexes removes the entity with the passed key from every index of
+// the passed type.
+func (db *BurrowDB) removeFromIndexes(_type reflect.Type, key string) error {
+	typeName := db.typeName(_type)
+	specs := db.indexSpecs(_type)
+	indexes := make(map[string]index, len(specs))
+	for _, spec := range specs {
+		idx, err := db.readIndex(typeName, spec)
+		if err != nil {
+			return err
+		}
+
+		idx.remove(key)
+		indexes[spec.name] = idx
+	}
+
+	return db.writeIndexes(_type, indexes)
+}
+
+// removeFromRawIndexes removes the entity with the passed key from every index
+// stored for the named type, without knowing the type's fields. The lock of the
+// type must be held.
+func (db *BurrowDB) removeFromRawIndexes(typeName, key string) error {
+	indexes, err := db.readRawIndexes(typeName)
+	if err != nil {
+		return err
+	}
+
+	for field, idx := range indexes {
+		idx.remove(key)
+		if isDir(db.indexPath(typeName, field)) {
+			err = db.writeEnumIndex(typeName, field, idx)
+		} else {
+			err = db.writeIndexFile(db.indexPath(typeName, field), idx)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write index %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// readIndex returns the index of the passed field of the named type. An empty
+// index is returned if it has not been written.
+func (db *BurrowDB) readIndex(typeName string, spec indexSpec) (index, error) {
+	field := spec.name
+	if !spec.enum {
+		idx := index{}
+		err := readIndexFile(db.indexPath(typeName, field), &idx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read index %s: %w", field, err)
+		}
+		return idx, nil
+	}
+
+	entries, err := os.ReadDir(db.indexPath(typeName, field))
+	if errors.Is(err, os.ErrNotExist) {
+		return index{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read index %s: %w", field, err)
+	}
+
+	idx := make(index, len(entries))
+	for _, entry := range entries {
+		value, ok, err := indexValueOf(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid value file in index %s: %w", field, err)
+		} else if !ok {
+			continue
+		}
+
+		keys, err := db.readIndexValue(typeName, spec, value)
+		if err != nil {
+			return nil, err
+		}
+		idx[value] = keys
+	}
+
+	return idx, nil
+}
+
+// readIndexValue returns the keys of the entities of the named type which have
+// the passed value of the indexed field. Only the value's file is read if the
+// index is stored with one file per value.
+func (db *BurrowDB) readIndexValue(typeName string, spec indexSpec, value string) ([]string, error) {
+	if !spec.enum {
+		idx, err := db.readIndex(typeName, spec)
+		if err != nil {
+			return nil, err
+		}
+		return idx[value], nil
+	}
+
+	var keys []string
+	err := readIndexFile(db.indexValuePath(typeName, spec.name, value), &keys)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read index %s: %w", spec.name, err)
+	}
+
+	return keys, nil
+}
+
+// readIndexFile unmarshals the index file at the passed path into dst. Nothing
+// is done if the file doesn't exist.
+func readIndexFile(filename string, dst any) error {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+// writeIndexes atomically writes each of the passed indexes of the passed type,
+// keyed by name.
+func (db *BurrowDB) writeIndexes(_type reflect.Type, indexes map[string]index) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	typeName := db.typeName(_type)
+	err := db.mkdirAll(db.indexDir(typeName))
+	if err != nil {
+		return fmt.Errorf("unable to create index dir: %w", err)
+	}
+
+	for _, spec := range db.indexSpecs(_type) {
+		idx, ok := indexes[spec.name]
+		if !ok {
+			continue
+		}
+
+		if spec.enum {
+			err = db.writeEnumIndex(typeName, spec.name, idx)
+		} else {
+			err = db.writeIndexFile(db.indexPath(typeName, spec.name), idx)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write index %s: %w", spec.name, err)
+		}
+	}
+
+	return nil
+}
+
+// writeEnumIndex writes the keys with each value of the passed index of the
+// named field to the value's own file, removing the files of values which no
+// entity has any more.
+func (db *BurrowDB) writeEnumIndex(typeName, field string, idx index) error {
+	dir := db.indexPath(typeName, field)
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		// The field was previously indexed in a single file.
+		err = os.Remove(dir)
+		if err != nil {
+			return fmt.Errorf("unable to remove old index: %w", err)
+		}
+	}
+
+	err := db.mkdirAll(dir)
+	if err != nil {
+		return fmt.Errorf("unable to create index dir: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read index dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		value, ok, err := indexValueOf(entry.Name())
+		if !ok || err != nil || len(idx[value]) > 0 {
+			continue
+		}
+
+		err = os.Remove(fmt.Sprintf("%s/%s", dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove unused value: %w", err)
+		}
+	}
+
+	for value, keys := range idx {
+		err = db.writeIndexFile(db.indexValuePath(typeName, field, value), keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeIndexFile atomically writes v as JSON to the index file at the passed
+// path, replacing a directory left by a per-value index.
+func (db *BurrowDB) writeIndexFile(filename string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to marshal index: %w", err)
+	}
+
+	if isDir(filename) {
+		err = os.RemoveAll(filename)
+		if err != nil {
+			return fmt.Errorf("unable to remove old index: %w", err)
+		}
+	}
+
+	return db.writeFileAtomic(filename, data)
+}
+
+// indexDir returns the directory holding the indexes of the named type.
+func (db *BurrowDB) indexDir(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), indexDirName)
+}
+
+// indexPath returns the path of the index of the named field of the named type.
+func (db *BurrowDB) indexPath(typeName, field string) string {
+	return fmt.Sprintf("%s/%s", db.indexDir(typeName), field)
+}
+
+// indexValuePath returns the path of the file holding the keys with the passed
+// value in the per-value index of the named field of the named type.
+func (db *BurrowDB) indexValuePath(typeName, field, value string) string {
+	return fmt.Sprintf("%s/%s%s", db.indexPath(typeName, field), indexValuePrefix, url.PathEscape(value))
+}
+
+// indexValueOf returns the value whose keys are held by the file in a per-value
+// index with the passed name. False is returned for other files, such as temp
+// files.
+func indexValueOf(filename string) (string, bool, error) {
+	escaped, ok := strings.CutPrefix(filename, indexValuePrefix)
+	if !ok {
+		return "", false, nil
+	}
+
+	value, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
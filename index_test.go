@@ -0,0 +1,248 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+type indexItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+	Email  string `burrowdb:"unique"`
+}
+
+func indexItemIDs(items []indexItem) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestGetByField(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		indexItem{ID: 1, Status: "open", Email: "a"},
+		indexItem{ID: 2, Status: "open", Email: "b"},
+		indexItem{ID: 10, Status: "done", Email: "c"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []indexItem
+	err = db.GetByField(&items, "Status", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("got %v open, want [1 2]", got)
+	}
+
+	// Replacing and deleting entities updates the index.
+	err = db.Put(indexItem{ID: 2, Status: "done", Email: "b"})
+	if err == nil {
+		err = db.Delete(indexItem{}, 10)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByField(&items, "Status", "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{2}) {
+		t.Fatalf("got %v done, want [2]", got)
+	}
+
+	err = db.GetByField(&items, "ID", 1)
+	if !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("got %v for an unindexed field, want %v", err, ErrNotIndexed)
+	}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(indexItem{ID: 1, Email: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(indexItem{ID: 2, Email: "a"})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("got %v, want %v", err, ErrUniqueViolation)
+	}
+
+	err = db.GetByID(&indexItem{}, 2)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want the violating entity not stored", err)
+	}
+
+	// An entity may keep its own value.
+	err = db.Put(indexItem{ID: 1, Status: "open", Email: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebuildIndexes(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(indexItem{ID: 1, Status: "open", Email: "a"}, indexItem{ID: 2, Status: "done", Email: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "indexItem", ".index", "Status"), []byte(`{"open":["99"]}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []indexItem
+	err = db.GetByField(&items, "Status", "open")
+	if err == nil {
+		t.Fatal("got no error reading a stale index")
+	}
+
+	err = db.RebuildIndexes(indexItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByField(&items, "Status", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{1}) {
+		t.Fatalf("got %v open, want [1]", got)
+	}
+}
+
+func TestGetByFieldPage(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 25 {
+		err = db.Put(indexItem{ID: i + 1, Status: "open", Email: strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Put(indexItem{ID: 100, Status: "done", Email: "done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for offset := 0; ; offset += 10 {
+		var items []indexItem
+		total, err := db.GetByFieldPage(&items, "Status", "open", offset, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 25 {
+			t.Fatalf("got total %d, want 25", total)
+		}
+		if len(items) == 0 {
+			break
+		}
+		got = append(got, indexItemIDs(items)...)
+	}
+
+	want := make([]int, 25)
+	for i := range want {
+		want[i] = i + 1
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	var items []indexItem
+	_, err = db.GetByFieldPage(&items, "Status", "open", 0, 0)
+	if err == nil {
+		t.Fatal("got nil error for a zero limit")
+	}
+}
+
+func TestDeleteByField(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		indexItem{ID: 1, Status: "open", Email: "a"},
+		indexItem{ID: 2, Status: "done", Email: "b"},
+		indexItem{ID: 3, Status: "open", Email: "c"},
+		indexItem{ID: 4, Status: "open", Email: "d"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := db.DeleteByField(indexItem{}, "Status", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("got %d deleted, want 3", n)
+	}
+
+	var items []indexItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{2}) {
+		t.Fatalf("got %v stored, want [2]", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "indexItem", ".index", "Status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"done":["2"]}` {
+		t.Fatalf("got Status index %s, want only done", data)
+	}
+
+	err = db.GetByField(&items, "Email", "a")
+	if err != nil || len(items) != 0 {
+		t.Fatalf("got %v and %v for a deleted email, want nothing", indexItemIDs(items), err)
+	}
+
+	// Emails of deleted entities can be reused.
+	err = db.Put(indexItem{ID: 5, Status: "open", Email: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = db.DeleteByField(indexItem{}, "Status", "archived")
+	if err != nil || n != 0 {
+		t.Fatalf("got %d deleted and %v for an unused value, want 0", n, err)
+	}
+
+	_, err = db.DeleteByField(indexItem{}, "ID", 2)
+	if !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("got %v for an unindexed field, want %v", err, ErrNotIndexed)
+	}
+}
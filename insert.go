@@ -0,0 +1,236 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const seqFileName = ".seq" // Name of the file in each type dir holding the last assigned ID.
+
+// IDGenerator generates the IDs given to entities by Insert, for schemes such as
+// snowflakes or ULIDs. It must be safe for concurrent use.
+type IDGenerator interface {
+	// Next returns a new ID, which must be assignable or convertible to the ID
+	// field of every type inserted.
+	Next() (any, error)
+}
+
+// WithIDGenerator specifies that Insert should give entities whose ID field is
+// the zero value an ID from gen, rather than the next ID in their type's
+// sequence. Entities with an ID already set are stored under it, and the ID
+// field needn't be an integer.
+func WithIDGenerator(gen IDGenerator) newDBOption {
+	return func(db *BurrowDB) error {
+		if gen == nil {
+			return errors.New("ID generator is nil")
+		}
+
+		db.idGenerator = gen
+		return nil
+	}
+}
+
+// SequentialIDs is an IDGenerator giving increasing integer IDs from 1. The
+// last ID is held in memory only, so a new SequentialIDs starts from 1 again.
+type SequentialIDs struct {
+	last atomic.Int64
+}
+
+func (s *SequentialIDs) Next() (any, error) {
+	return s.last.Add(1), nil
+}
+
+// Insert puts the passed struct, or pointer to one, into the db after assigning
+// it the next ID in its type's sequence. The ID field must be an integer. If v
+// is a pointer, the struct it points to is also given the ID. If the db has an
+// ID generator set by WithIDGenerator, it is used instead of the sequence.
+//
+// The stored value is returned with its ID set, as a copy which doesn't alias
+// v, along with the ID, or 0 if it isn't an integer.
+func (db *BurrowDB) Insert(v any) (_ any, _ int64, err error) {
+	defer db.handleError("Insert", &err)
+
+	_type := indirectType(reflect.TypeOf(v))
+	if _type.Kind() != reflect.Struct {
+		return nil, 0, ErrInvalidValueType
+	}
+
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if db.idGenerator != nil {
+		return db.insertGenerated(v, _type, idField)
+	}
+
+	if !isIntKind(idField.Type.Kind()) {
+		return nil, 0, fmt.Errorf("%w: %s", ErrNonIntegerID, idField.Type)
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	id, err := db.nextID(typeName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stored := reflect.New(_type).Elem()
+	stored.Set(reflect.Indirect(reflect.ValueOf(v)))
+	setInt(stored.FieldByIndex(idField.Index), id)
+
+	err = db.put(stored.Interface())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.writeSeq(typeName, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if p := reflect.ValueOf(v); p.Kind() == reflect.Pointer {
+		setInt(p.Elem().FieldByIndex(idField.Index), id)
+	}
+
+	return stored.Interface(), id, nil
+}
+
+// insertGenerated inserts the passed struct, or pointer to one, of the passed
+// type as Insert does, giving it an ID from the db's ID generator if its ID
+// field is the zero value.
+func (db *BurrowDB) insertGenerated(v any, _type reflect.Type, idField *reflect.StructField) (any, int64, error) {
+	stored := reflect.New(_type).Elem()
+	stored.Set(reflect.Indirect(reflect.ValueOf(v)))
+
+	id := stored.FieldByIndex(idField.Index)
+	if id.IsZero() {
+		next, err := db.idGenerator.Next()
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to generate ID: %w", err)
+		}
+
+		gen := reflect.ValueOf(next)
+		if !gen.IsValid() || !gen.Type().ConvertibleTo(id.Type()) ||
+			gen.Kind() != id.Kind() && !(isIntKind(gen.Kind()) && isIntKind(id.Kind())) {
+			return nil, 0, fmt.Errorf("%w: generated ID %T can't be stored in %s", ErrInvalidValueType, next, idField.Type)
+		}
+		id.Set(gen.Convert(id.Type()))
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	err := db.put(stored.Interface())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if p := reflect.ValueOf(v); p.Kind() == reflect.Pointer {
+		p.Elem().FieldByIndex(idField.Index).Set(id)
+	}
+
+	var n int64
+	if id.CanInt() {
+		n = id.Int()
+	} else if id.CanUint() {
+		n = int64(id.Uint())
+	}
+
+	return stored.Interface(), n, nil
+}
+
+// nextID returns the next unused ID in the sequence of the named type.
+func (db *BurrowDB) nextID(typeName string) (int64, error) {
+	id, err := db.readSeq(typeName)
+	if err != nil {
+		return 0, err
+	}
+
+	// Skip IDs which have been used by entities stored with Put.
+	for {
+		id++
+		key, err := db.idKey(id)
+		if err != nil {
+			return 0, err
+		}
+
+		if db.isAppendLog(typeName) {
+			_, ok, err := db.logRecord(typeName, key)
+			if err != nil {
+				return 0, err
+			} else if !ok {
+				return id, nil
+			}
+			continue
+		}
+
+		_, err = os.Stat(db.entityPath(typeName, key))
+		if errors.Is(err, os.ErrNotExist) {
+			return id, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("unable to stat entity: %w", err)
+		}
+	}
+}
+
+// readSeq returns the last ID assigned in the sequence of the named type, or 0
+// if none has been.
+func (db *BurrowDB) readSeq(typeName string) (int64, error) {
+	data, err := os.ReadFile(db.seqPath(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("unable to read sequence: %w", err)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse sequence: %w", err)
+	}
+
+	return id, nil
+}
+
+// writeSeq records the passed ID as the last assigned in the sequence of the
+// named type.
+func (db *BurrowDB) writeSeq(typeName string, id int64) error {
+	err := db.writeFileAtomic(db.seqPath(typeName), []byte(strconv.FormatInt(id, 10)))
+	if err != nil {
+		return fmt.Errorf("unable to write sequence: %w", err)
+	}
+	return nil
+}
+
+// seqPath returns the path of the file holding the sequence of the named type.
+func (db *BurrowDB) seqPath(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), seqFileName)
+}
+
+// isIntKind reports whether the passed kind is a signed or unsigned integer.
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// setInt sets the passed signed or unsigned integer value to n.
+func setInt(v reflect.Value, n int64) {
+	if v.CanInt() {
+		v.SetInt(n)
+	} else {
+		v.SetUint(uint64(n))
+	}
+}
@@ -0,0 +1,117 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+type insertItem struct {
+	Num  int64 `burrowdb:"ID"`
+	Name string
+}
+
+type insertNamed struct {
+	ID string
+}
+
+func TestInsert(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(insertItem{Num: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sequence skips IDs used by entities stored with Put.
+	v, id, err := db.Insert(insertItem{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || v.(insertItem).Num != 1 {
+		t.Fatalf("got %v, %d, want ID 1", v, id)
+	}
+
+	item := &insertItem{Name: "b"}
+	v, id, err = db.Insert(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 || item.Num != 3 || v.(insertItem).Num != 3 {
+		t.Fatalf("got %v, %d, %+v, want ID 3", v, id, item)
+	}
+
+	var stored insertItem
+	err = db.GetByID(&stored, 3)
+	if err != nil || stored.Name != "b" {
+		t.Fatalf("got %+v, %v", stored, err)
+	}
+
+	_, _, err = db.Insert(insertNamed{})
+	if !errors.Is(err, ErrNonIntegerID) {
+		t.Fatalf("got %v, want %v", err, ErrNonIntegerID)
+	}
+}
+
+// sortableIDs generates string IDs which sort in the order they were
+// generated, like ULIDs.
+type sortableIDs struct {
+	n atomic.Int64
+}
+
+func (s *sortableIDs) Next() (any, error) {
+	return fmt.Sprintf("01H%020d", s.n.Add(1)), nil
+}
+
+func TestIDGenerator(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithIDGenerator(&sortableIDs{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for range 3 {
+		item := &insertNamed{}
+		_, _, err = db.Insert(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+	}
+
+	if !slices.IsSorted(ids) || ids[0] == ids[1] {
+		t.Fatalf("got IDs %v, want distinct sorted IDs", ids)
+	}
+
+	var all []insertNamed
+	err = db.GetAll(&all)
+	if err != nil || len(all) != 3 || all[0].ID != ids[0] {
+		t.Fatalf("got %+v, %v", all, err)
+	}
+
+	// An ID which is already set is kept.
+	v, _, err := db.Insert(insertNamed{ID: "set"})
+	if err != nil || v.(insertNamed).ID != "set" {
+		t.Fatalf("got %+v, %v", v, err)
+	}
+
+	seq, err := NewDB(WithDir(t.TempDir()), WithIDGenerator(&SequentialIDs{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, id, err := seq.Insert(insertItem{})
+	if err != nil || id != 1 {
+		t.Fatalf("got %d, %v, want ID 1", id, err)
+	}
+
+	_, _, err = seq.Insert(insertNamed{})
+	if !errors.Is(err, ErrInvalidValueType) {
+		t.Fatalf("got %v, want %v for an integer ID in a string field", err, ErrInvalidValueType)
+	}
+}
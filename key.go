@@ -0,0 +1,269 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithKeyFilename specifies how the ID of an entity is formatted as the name of
+// its file, for example to zero-pad numeric IDs as user_0001. The parse
+// function must invert format, returning the text keyFor would give the ID, so
+// that scans can order entities and IntKeys can recover integer IDs.
+func WithKeyFilename(format func(id any) string, parse func(filename string) string) newDBOption {
+	return func(db *BurrowDB) error {
+		if format == nil || parse == nil {
+			return errors.New("key filename format and parse functions must both be set")
+		}
+
+		db.keyFormat = format
+		db.keyParse = parse
+		return nil
+	}
+}
+
+// WithPaddedIntKeys specifies that integer IDs should be zero-padded to the
+// passed width in filenames, so that listing a type dir lexically gives the
+// same order as sorting its IDs numerically. IDs must be non-negative and have
+// no more digits than width for the orders to agree.
+func WithPaddedIntKeys(width int) newDBOption {
+	return func(db *BurrowDB) error {
+		if width < 1 || width > 20 {
+			return errors.New("padded key width must be between 1 and 20")
+		}
+
+		db.keyWidth = width
+		return nil
+	}
+}
+
+// WithFileExtension specifies an extension, such as ".json", to give the file
+// of every entity so that it is recognised by external tools. Keys and IDs are
+// unaffected, and files in a type dir without the extension are ignored. The
+// same extension must be used every time a dir is opened.
+func WithFileExtension(ext string) newDBOption {
+	return func(db *BurrowDB) error {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+
+		if ext == "." || strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("invalid file extension %q", ext)
+		}
+
+		db.fileExt = ext
+		return nil
+	}
+}
+
+// WithIDFieldNames specifies the names of the fields which may hold the ID of
+// an entity, such as "ID", "Id" and "UUID", replacing the default of "ID". A
+// struct must have only one field with any of the names, or tagged
+// `burrowdb:"ID"`, or ErrMultipleIDFields is returned.
+func WithIDFieldNames(names ...string) newDBOption {
+	return func(db *BurrowDB) error {
+		if len(names) == 0 {
+			return errors.New("no ID field names given")
+		}
+
+		db.idFieldNames = append(db.idFieldNames, names...)
+		return nil
+	}
+}
+
+// WithRejectZeroID specifies that Put, and the other methods which store a value
+// under the ID in its ID field, return ErrZeroID if the field holds its zero
+// value, as it usually means the ID was never set. Insert can still be used to
+// give such values an ID.
+func WithRejectZeroID() newDBOption {
+	return func(db *BurrowDB) error {
+		db.rejectZeroID = true
+		return nil
+	}
+}
+
+// WithKeyNormalizer specifies a function applied to string IDs before they are
+// used as keys by every method, such as strings.ToLower so that "Alice" and
+// "alice" refer to the same entity. The ID field of a stored entity keeps the
+// value it was put with. The same normalizer must be used every time a dir is
+// opened.
+func WithKeyNormalizer(normalize func(string) string) newDBOption {
+	return func(db *BurrowDB) error {
+		if normalize == nil {
+			return errors.New("key normalizer is nil")
+		}
+
+		db.keyNormalizer = normalize
+		return nil
+	}
+}
+
+// entityKey returns the key, which is also the filename, of the entity with the
+// passed ID.
+func (db *BurrowDB) entityKey(id any) string {
+	text := db.keyText(id)
+	if db.encryptKeys {
+		return db.encryptKey(text)
+	}
+
+	return text
+}
+
+// keyText returns the text keying the entity with the passed ID, which is its
+// filename unless WithEncryptedKeys is used.
+func (db *BurrowDB) keyText(id any) string {
+	if v := reflect.ValueOf(id); db.keyNormalizer != nil && v.Kind() == reflect.String {
+		id = db.keyNormalizer(v.String())
+	}
+
+	if db.keyFormat != nil {
+		return db.keyFormat(id)
+	}
+
+	if db.keyWidth > 0 {
+		if digits, ok := integerText(reflect.ValueOf(id)); ok {
+			return padDigits(digits, db.keyWidth)
+		}
+	}
+
+	return keyFor(id)
+}
+
+// idKey returns the key of the entity with the passed ID, keying pointer IDs by
+// the value they point to as derefID does. Every method taking an ID should
+// key it with idKey so that pointer IDs refer to the same entity throughout.
+func (db *BurrowDB) idKey(id any) (string, error) {
+	id, err := derefID(id)
+	if err != nil {
+		return "", err
+	}
+
+	return db.entityKey(id), nil
+}
+
+// isSafeKey reports whether the passed key, received from outside the db such
+// as by an import, can be used as a filename without escaping its type dir or
+// being mistaken for a hidden file.
+func isSafeKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, `/\`) && !strings.HasPrefix(key, ".")
+}
+
+// derefID returns the value pointed to by the passed ID if it is a pointer, such
+// as the value of an ID field of type *int64, so that the entity is keyed by the
+// value rather than its address. ErrNilID is returned for a nil pointer.
+func derefID(id any) (any, error) {
+	v := reflect.ValueOf(id)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, ErrNilID
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return id, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// integerText returns the decimal text of the passed value if it is a number
+// with an integer value, whatever its Go type, so that 123, int64(123) and
+// 123.0 are keyed the same.
+func integerText(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return "", false
+		}
+		return strconv.FormatInt(int64(f), 10), true
+	}
+
+	return "", false
+}
+
+// padDigits zero-pads the passed decimal integer text to width characters,
+// keeping any sign first.
+func padDigits(digits string, width int) string {
+	sign, digits := "", digits
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+		width--
+	}
+
+	if len(digits) < width {
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+
+	return sign + digits
+}
+
+// idText returns the text keyFor gives the ID of the entity of the named type
+// with the passed key. With WithEncryptedKeys the key names file of the entity's type must have
+// been loaded for the ID to be recovered.
+func (db *BurrowDB) idText(typeName, key string) string {
+	if db.encryptKeys {
+		if text, ok := db.locks.keyTexts.Load(db.keyTextKey(typeName, key)); ok {
+			key = text.(string)
+		}
+	}
+
+	if db.keyParse != nil {
+		return db.keyParse(key)
+	}
+	return key
+}
+
+// compareKeys returns a function comparing two keys of entities of the named
+// type by the IDs they were formatted from.
+func (db *BurrowDB) compareKeys(typeName string) func(a, b string) int {
+	return func(a, b string) int {
+		return compareKeys(db.idText(typeName, a), db.idText(typeName, b))
+	}
+}
+
+// keyFor returns the text used to key the passed ID or indexed value. Unless
+// WithKeyFilename is used this is also the filename of the entity. IDs
+// implementing fmt.Stringer, with either a value or pointer receiver,
+// are keyed by String.
+//
+// Numbers are keyed canonically so the same logical ID gives the same key
+// whatever its Go type: integer values, including floats such as 123.0, are
+// keyed in decimal, and other floats by their shortest representation.
+func keyFor(id any) string {
+	if s, ok := id.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	// Values don't have the methods of their pointer type so take a copy which
+	// does.
+	v := reflect.ValueOf(id)
+	if v.IsValid() && v.Kind() != reflect.Pointer {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		if s, ok := p.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
+	if text, ok := integerText(v); ok {
+		return text
+	}
+
+	switch v.Kind() {
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", id)
+}
@@ -0,0 +1,480 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type keyItem struct {
+	Num int64 `burrowdb:"ID"`
+}
+
+func TestKeyFilename(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithKeyFilename(
+		func(id any) string { return fmt.Sprintf("k_%04v", id) },
+		func(name string) string { return strings.TrimLeft(strings.TrimPrefix(name, "k_"), "0") },
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, num := range []int64{10, 9, 100} {
+		err = db.Put(keyItem{Num: num})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "keyItem", "k_0009"))
+	if err != nil {
+		t.Fatalf("got %v, want the entity stored under the formatted name", err)
+	}
+
+	err = db.GetByID(&keyItem{}, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := db.IntKeys(keyItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{9, 10, 100}; !slices.Equal(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+
+	var items []keyItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[2].Num != 100 {
+		t.Fatalf("got %v, want the entities ordered by their parsed keys", items)
+	}
+
+	err = db.Delete(keyItem{}, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPaddedIntKeys(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithPaddedIntKeys(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, num := range []int64{10, 9, 100} {
+		err = db.Put(keyItem{Num: num})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "keyItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	if want := []string{"000009", "000010", "000100"}; !slices.Equal(names, want) {
+		t.Fatalf("got filenames %v, want %v", names, want)
+	}
+
+	keys, err := db.IntKeys(keyItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{9, 10, 100}; !slices.Equal(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+
+	// IDs of any integer kind name the same entity.
+	err = db.GetByID(&keyItem{}, 9)
+	if err == nil {
+		err = db.Delete(keyItem{}, int32(9))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyForNumbers(t *testing.T) {
+	tests := []struct {
+		id   any
+		want string
+	}{
+		{123, "123"},
+		{int64(123), "123"},
+		{uint8(123), "123"},
+		{123.0, "123"},
+		{float32(123), "123"},
+		{"123", "123"},
+		{-5, "-5"},
+		{1.5, "1.5"},
+		{float32(0.1), "0.1"},
+	}
+
+	for _, test := range tests {
+		if got := keyFor(test.id); got != test.want {
+			t.Errorf("%T %v: got %q, want %q", test.id, test.id, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalIDs(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(keyItem{Num: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []any{7, int8(7), uint64(7), 7.0, float32(7)} {
+		err = db.GetByID(&keyItem{}, id)
+		if err != nil {
+			t.Errorf("%T %v: got %v", id, id, err)
+		}
+	}
+}
+
+type ptrIDItem struct {
+	ID   *int64
+	Name string
+}
+
+func TestPointerID(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := int64(42)
+	err = db.Put(ptrIDItem{ID: &id, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got ptrIDItem
+	err = db.GetByID(&got, 42)
+	if err != nil || got.ID == nil || *got.ID != 42 || got.Name != "a" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	err = db.GetByID(&got, &id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(ptrIDItem{Name: "nil"})
+	if !errors.Is(err, ErrNilID) {
+		t.Fatalf("got %v, want ErrNilID", err)
+	}
+
+	// Every method taking an ID or keying a value keys pointer IDs by the value
+	// they point to.
+	raw, err := db.GetRawJSON("ptrIDItem", &id)
+	if err != nil || !strings.Contains(string(raw), `"a"`) {
+		t.Fatalf("got %s, %v", raw, err)
+	}
+
+	m, err := db.GetAsMap("ptrIDItem", &id)
+	if err != nil || m["Name"] != "a" {
+		t.Fatalf("got %v, %v", m, err)
+	}
+
+	var fields ptrIDItem
+	err = db.GetFields(&fields, &id, "Name")
+	if err != nil || fields.Name != "a" {
+		t.Fatalf("got %+v, %v", fields, err)
+	}
+
+	many := map[int64]ptrIDItem{}
+	err = db.GetManyMap(&many, []any{&id})
+	if err != nil || many[42].Name != "a" {
+		t.Fatalf("got %v, %v", many, err)
+	}
+
+	err = db.PutList("ptrIDList", &id, []string{"x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	err = db.GetList("ptrIDList", 42, &list)
+	if err != nil || !slices.Equal(list, []string{"x"}) {
+		t.Fatalf("got %v, %v", list, err)
+	}
+
+	err = db.Merge(ptrIDItem{ID: &id, Name: "b"}, func(existing, incoming any) (any, error) {
+		if existing == nil {
+			return nil, errors.New("existing entity not passed")
+		}
+		v := incoming.(ptrIDItem)
+		v.Name = existing.(ptrIDItem).Name + v.Name
+		return v, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := db.PutIf(ptrIDItem{ID: &id, Name: "c"}, func(existing any) bool {
+		return existing != nil && existing.(ptrIDItem).Name == "ab"
+	})
+	if err != nil || !ok {
+		t.Fatalf("got %v, %v, want the condition to see the stored entity", ok, err)
+	}
+
+	prev, existed, err := Upsert(db, ptrIDItem{ID: &id, Name: "d"})
+	if err != nil || !existed || prev.Name != "c" {
+		t.Fatalf("got %+v, %v, %v", prev, existed, err)
+	}
+
+	err = db.BulkLoad([]ptrIDItem{{ID: &id, Name: "e"}}, false, WithOnCollision(func(existing, incoming any) (any, error) {
+		v := incoming.(ptrIDItem)
+		v.Name = existing.(ptrIDItem).Name + v.Name
+		return v, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.GetByID(&got, &id)
+	if err != nil || got.Name != "de" {
+		t.Fatalf("got %+v, %v, want the collision resolved", got, err)
+	}
+
+	err = db.Delete(ptrIDItem{}, &id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&got, 42)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want ErrNoSuchEntity", err)
+	}
+}
+
+func TestFileExtension(t *testing.T) {
+	for _, layout := range []Layout{Flat{}, Sharded{Levels: 2}} {
+		t.Run(fmt.Sprintf("%T", layout), func(t *testing.T) {
+			db, err := NewDB(WithDir(t.TempDir()), WithFileExtension(".json"), WithLayout(layout))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = db.PutAll(keyItem{Num: 1}, keyItem{Num: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename := db.entityPath("keyItem", "1")
+			if !strings.HasSuffix(filename, "/1.json") {
+				t.Fatalf("got %s, want the extension added", filename)
+			}
+			_, err = os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Files without the extension are ignored.
+			err = os.WriteFile(strings.TrimSuffix(filename, ".json")+"9", nil, 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := db.IntKeys(keyItem{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(keys, []int64{1, 2}) {
+				t.Fatalf("got keys %v, want [1 2]", keys)
+			}
+
+			err = db.Delete(keyItem{}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var items []keyItem
+			err = db.GetAll(&items)
+			if err != nil || len(items) != 1 || items[0].Num != 2 {
+				t.Fatalf("got %v, %v", items, err)
+			}
+		})
+	}
+}
+
+type idNamesUpper struct {
+	ID   int
+	Name string
+}
+
+type idNamesMixed struct {
+	Id   int
+	Name string
+}
+
+type idNamesUUID struct {
+	UUID string
+	Name string
+}
+
+type idNamesBoth struct {
+	Id   int
+	UUID string
+}
+
+func TestIDFieldNames(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithIDFieldNames("ID", "Id", "UUID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		idNamesUpper{ID: 1, Name: "upper"},
+		idNamesMixed{Id: 2, Name: "mixed"},
+		idNamesUUID{UUID: "a-b", Name: "uuid"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var upper idNamesUpper
+	var mixed idNamesMixed
+	var uuid idNamesUUID
+	err = db.GetByID(&upper, 1)
+	if err == nil {
+		err = db.GetByID(&mixed, 2)
+	}
+	if err == nil {
+		err = db.GetByID(&uuid, "a-b")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upper.Name != "upper" || mixed.Name != "mixed" || uuid.Name != "uuid" {
+		t.Fatalf("got %+v, %+v, %+v", upper, mixed, uuid)
+	}
+
+	err = db.Put(idNamesBoth{Id: 1, UUID: "a"})
+	if !errors.Is(err, ErrMultipleIDFields) {
+		t.Fatalf("got %v for two ID fields, want %v", err, ErrMultipleIDFields)
+	}
+
+	// Without the option only ID is accepted.
+	plain, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = plain.Put(idNamesMixed{Id: 2})
+	if !errors.Is(err, ErrNoIDField) {
+		t.Fatalf("got %v, want %v", err, ErrNoIDField)
+	}
+}
+
+type normalizedUser struct {
+	ID    string
+	Email string
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithKeyNormalizer(func(id string) string {
+		return strings.ToLower(strings.TrimSpace(id))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(normalizedUser{ID: "Alice", Email: "old"})
+	if err == nil {
+		err = db.Put(normalizedUser{ID: " alice ", Email: "new"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []normalizedUser
+	err = db.GetAll(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("got %+v, want one user", users)
+	}
+
+	var user normalizedUser
+	err = db.GetByID(&user, "ALICE")
+	if err != nil || user.Email != "new" {
+		t.Fatalf("got %+v, %v", user, err)
+	}
+
+	err = db.Delete(normalizedUser{}, "aLiCe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&user, "alice")
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v after deleting, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+type zeroIDItem struct {
+	ID   int
+	Name string
+}
+
+func TestRejectZeroID(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithRejectZeroID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(zeroIDItem{Name: "unset"})
+	if !errors.Is(err, ErrZeroID) {
+		t.Fatalf("got %v putting a zero ID, want %v", err, ErrZeroID)
+	}
+
+	keys, err := db.IntKeys(zeroIDItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("got keys %v, want none stored", keys)
+	}
+
+	// Insert gives the value an ID rather than rejecting it.
+	_, id, err := db.Insert(zeroIDItem{Name: "inserted"})
+	if err == nil && id == 0 {
+		err = fmt.Errorf("got ID %d, want a non-zero ID", id)
+	}
+	if err == nil {
+		err = db.Put(zeroIDItem{ID: 2, Name: "set"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Zero IDs are stored by default.
+	other, err := NewDB(WithDir(t.TempDir()))
+	if err == nil {
+		err = other.Put(zeroIDItem{Name: "unset"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,305 @@
+package burrowdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const keyNamesFileName = ".keys" // Name of the file in each type dir mapping encrypted filenames to IDs.
+
+// WithEncryptedKeys specifies that the files of entities should be named by an
+// HMAC of their ID under the key given to WithEncryption, rather than by the
+// ID itself, so that listing the db dir doesn't reveal any IDs. The ID of each
+// file is recorded, encrypted, in a file in its type dir so that scans can
+// still order entities and IntKeys can recover integer IDs. Every method which
+// takes an ID derives the same filename from it.
+//
+// Indexed values, and the names of types, are still stored in the clear. The
+// same key must be used every time a dir is opened, and WithEncryption must
+// also be given. The IDs of deleted entities stay in the key names file until
+// Compact removes them.
+func WithEncryptedKeys() newDBOption {
+	return func(db *BurrowDB) error {
+		db.encryptKeys = true
+		return nil
+	}
+}
+
+// keyNameLog is the index of the key names file of a type, recording which
+// filenames have their IDs in the file.
+type keyNameLog struct {
+	mu     sync.Mutex
+	offset int64           // number of bytes of the file which have been indexed.
+	stored map[string]bool // filenames whose IDs are in the indexed part of the file.
+}
+
+// filenameKey derives the key of the HMAC naming entity files from the passed
+// encryption key, so that the encryption key itself is never used for both.
+func filenameKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("burrowdb filenames"))
+	return mac.Sum(nil)
+}
+
+// encryptKey returns the filename derived from the passed ID text.
+func (db *BurrowDB) encryptKey(text string) string {
+	mac := hmac.New(sha256.New, db.filenameKey)
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// putKey returns the key of the entity with the passed ID, as idKey does, for
+// an entity which is about to be put. If the db encrypts keys the text of the
+// ID is remembered so that recordKeyName can record it; the IDs passed to
+// methods which only read or delete aren't kept.
+func (db *BurrowDB) putKey(typeName string, id any) (string, error) {
+	key, err := db.idKey(id)
+	if err != nil || !db.encryptKeys {
+		return key, err
+	}
+
+	// idKey has checked that the ID isn't a nil pointer.
+	id, _ = derefID(id)
+	db.locks.keyTexts.Store(db.keyTextKey(typeName, key), db.keyText(id))
+
+	return key, nil
+}
+
+// keyTextKey returns the key under which the text of the ID of the entity of
+// the named type with the passed key is remembered. Texts are remembered for
+// each type apart, as the same ID may be stored in several types and pruned
+// from only some of them.
+func (db *BurrowDB) keyTextKey(typeName, key string) string {
+	return db.typeDirName(typeName) + "/" + key
+}
+
+// recordKeyName appends the ID of the entity of the named type with the passed
+// key to the key names file of the type, if the db encrypts keys and it isn't
+// already there. Keys whose ID isn't known, such as those of entities copied
+// from another store, are skipped. The lock of the type must be held for
+// writing.
+func (db *BurrowDB) recordKeyName(typeName, key string) error {
+	if !db.encryptKeys {
+		return nil
+	}
+
+	text, ok := db.locks.keyTexts.Load(db.keyTextKey(typeName, key))
+	if !ok {
+		return nil
+	}
+
+	return db.withKeyNames(typeName, func(l *keyNameLog) error {
+		if l.stored[key] {
+			return nil
+		}
+
+		nonce := make([]byte, db.aead.NonceSize())
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return fmt.Errorf("unable to generate nonce: %w", err)
+		}
+
+		// The filename is authenticated so IDs can't be moved between files.
+		sealed := db.aead.Seal(nonce, nonce, []byte(text.(string)), []byte(key))
+		line := fmt.Sprintf("%s %s\n", key, base64.StdEncoding.EncodeToString(sealed))
+
+		err = db.mkdirAll(db.typeDir(typeName))
+		if err != nil {
+			return fmt.Errorf("unable to create type dir: %w", err)
+		}
+
+		release, err := db.acquireFile()
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		filename := db.keyNamesPath(typeName)
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, db.filePerm())
+		if err != nil {
+			return fmt.Errorf("unable to open key names file: %w", err)
+		}
+		defer f.Close()
+
+		_, err = f.WriteString(line)
+		if err != nil {
+			return fmt.Errorf("unable to write key name: %w", noSpace(err))
+		}
+		db.markWritten(filename)
+
+		// The line is indexed again when the file is next read.
+		l.stored[key] = true
+
+		return nil
+	})
+}
+
+// loadKeyNames reads the IDs recorded in the key names file of the named type,
+// if the db encrypts keys, so that idText can recover them. The lock of the
+// type must be held.
+func (db *BurrowDB) loadKeyNames(typeName string) error {
+	if !db.encryptKeys {
+		return nil
+	}
+
+	return db.withKeyNames(typeName, func(*keyNameLog) error { return nil })
+}
+
+// withKeyNames calls fn with the index of the key names file of the named type,
+// bringing it up to date with the file first.
+func (db *BurrowDB) withKeyNames(typeName string, fn func(l *keyNameLog) error) error {
+	l := db.locks.forKeyNames(db.typeDirName(typeName))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := db.indexKeyNames(typeName, l)
+	if err != nil {
+		return err
+	}
+
+	return fn(l)
+}
+
+// indexKeyNames reads the lines written to the key names file of the named type
+// since it was last indexed. The whole file is read again if it has shrunk,
+// such as by Reset or Restore. An incomplete final line, left by a crash, is
+// ignored.
+func (db *BurrowDB) indexKeyNames(typeName string, l *keyNameLog) error {
+	release, err := db.acquireFile()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f, err := os.Open(db.keyNamesPath(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		l.offset, l.stored = 0, map[string]bool{}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to open key names file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat key names file: %w", err)
+	}
+
+	if l.stored == nil || info.Size() < l.offset {
+		l.offset, l.stored = 0, map[string]bool{}
+	}
+
+	_, err = f.Seek(l.offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("unable to seek key names file: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read key names file: %w", err)
+		}
+
+		key, text, err := db.openKeyName(bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			return fmt.Errorf("key names file of %s: %w", typeName, err)
+		}
+
+		db.locks.keyTexts.Store(db.keyTextKey(typeName, key), text)
+		l.stored[key] = true
+		l.offset += int64(len(line))
+	}
+}
+
+// openKeyName returns the filename and decrypted ID text of the passed line of
+// a key names file.
+func (db *BurrowDB) openKeyName(line []byte) (string, string, error) {
+	key, encoded, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return "", "", fmt.Errorf("malformed line %q", line)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return "", "", fmt.Errorf("unable to decode ID of %s: %w", key, err)
+	}
+
+	n := db.aead.NonceSize()
+	if len(sealed) < n {
+		return "", "", fmt.Errorf("encrypted ID of %s is too short", key)
+	}
+
+	text, err := db.aead.Open(nil, sealed[:n], sealed[n:], key)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to decrypt ID of %s: %w", key, err)
+	}
+
+	return string(key), string(text), nil
+}
+
+// pruneKeyNames rewrites the key names file of the named type without the IDs
+// of entities whose keys aren't in live, forgetting their texts, if the db
+// encrypts keys. The lock of the type must be held for writing.
+func (db *BurrowDB) pruneKeyNames(typeName string, live map[string]bool) error {
+	if !db.encryptKeys {
+		return nil
+	}
+
+	return db.withKeyNames(typeName, func(l *keyNameLog) error {
+		filename := db.keyNamesPath(typeName)
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read key names file: %w", err)
+		}
+
+		// An incomplete final line, left by a crash, is dropped too.
+		var kept []byte
+		var pruned []string
+		for line := range bytes.Lines(data) {
+			key, _, _ := bytes.Cut(line, []byte(" "))
+			if !live[string(key)] || !bytes.HasSuffix(line, []byte("\n")) {
+				pruned = append(pruned, string(key))
+				continue
+			}
+			kept = append(kept, line...)
+		}
+
+		if len(pruned) == 0 {
+			return nil
+		}
+
+		err = db.writeFileAtomic(filename, kept)
+		if err != nil {
+			return fmt.Errorf("unable to write key names file: %w", err)
+		}
+
+		for _, key := range pruned {
+			db.locks.keyTexts.Delete(db.keyTextKey(typeName, key))
+		}
+
+		// The file is indexed again when it is next read.
+		l.offset, l.stored = 0, nil
+
+		return nil
+	})
+}
+
+// keyNamesPath returns the path of the key names file of the named type.
+func (db *BurrowDB) keyNamesPath(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), keyNamesFileName)
+}
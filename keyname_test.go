@@ -0,0 +1,246 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type keyNameItem struct {
+	ID   string
+	Note string
+}
+
+type keyNameCounter struct {
+	ID int
+}
+
+func TestEncryptedKeys(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte("k"), 32)
+	db, err := NewDB(WithDir(dir), WithEncryption(key), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"alice", "bob", "carol"} {
+		err = db.Put(keyNameItem{ID: id, Note: "note"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.PutAll(keyNameCounter{ID: 10}, keyNameCounter{ID: 3}, keyNameCounter{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "keyNameItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == keyNamesFileName {
+			data, err := os.ReadFile(filepath.Join(dir, "keyNameItem", entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("alice")) {
+				t.Fatalf("got key names file %q, want the IDs encrypted", data)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if len(entry.Name()) != 64 || strings.Contains(entry.Name(), "alice") {
+			t.Fatalf("got filename %q, want an opaque HMAC", entry.Name())
+		}
+	}
+
+	var item keyNameItem
+	err = db.GetByID(&item, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "bob" {
+		t.Fatalf("got %+v, want bob", item)
+	}
+
+	err = db.Delete(&keyNameItem{}, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy of the dir opened afresh recovers the IDs from the key names file.
+	copied := t.TempDir()
+	err = os.CopyFS(copied, os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewDB(WithDir(copied), WithEncryption(key), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []keyNameItem
+	err = reopened.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []keyNameItem{{"alice", "note"}, {"bob", "note"}}
+	if !slices.Equal(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+
+	ints, err := reopened.IntKeys(keyNameCounter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ints, []int64{2, 3, 10}) {
+		t.Fatalf("got integer keys %v, want [2 3 10]", ints)
+	}
+
+	if !reopened.Config().EncryptedKeys {
+		t.Fatal("got EncryptedKeys false in config, want true")
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithEncryptedKeys())
+	if !errors.Is(err, ErrNoEncryptionKey) {
+		t.Fatalf("got %v without an encryption key, want %v", err, ErrNoEncryptionKey)
+	}
+}
+
+func TestEncryptedKeysForget(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithEncryption(bytes.Repeat([]byte("k"), 32)), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	textCount := func() int {
+		n := 0
+		db.locks.keyTexts.Range(func(any, any) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	err = db.PutAll(keyNameItem{ID: "alice"}, keyNameItem{ID: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// IDs which are only looked up aren't remembered.
+	var item keyNameItem
+	for _, id := range []string{"x", "y", "z"} {
+		err = db.GetByID(&item, id)
+		if !errors.Is(err, ErrNoSuchEntity) {
+			t.Fatalf("got %v, want ErrNoSuchEntity", err)
+		}
+	}
+	if n := textCount(); n != 2 {
+		t.Fatalf("got %d IDs remembered, want 2", n)
+	}
+
+	err = db.Delete(&keyNameItem{}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "keyNameItem", keyNamesFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 1 {
+		t.Fatalf("got %d lines in the key names file, want 1", lines)
+	}
+	if n := textCount(); n != 1 {
+		t.Fatalf("got %d IDs remembered after Compact, want 1", n)
+	}
+
+	// A pruned ID is recorded again when its entity is put again.
+	err = db.Put(keyNameItem{ID: "alice", Note: "again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "keyNameItem", keyNamesFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Fatalf("got %d lines in the key names file, want 2", lines)
+	}
+
+	reopened, err := NewDB(WithDir(dir), WithEncryption(bytes.Repeat([]byte("k"), 32)), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []keyNameItem
+	err = reopened.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []keyNameItem{{"alice", "again"}, {"bob", ""}}
+	if !slices.Equal(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+}
+
+type keyNameOther struct {
+	ID int
+}
+
+func TestEncryptedKeysSharedID(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithEncryption(bytes.Repeat([]byte("k"), 32)), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(keyNameCounter{ID: 1}, keyNameCounter{ID: 10}, keyNameOther{ID: 1}, keyNameOther{ID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pruning an ID from one type keeps it for the others storing it.
+	err = db.Delete(&keyNameOther{}, 1)
+	if err == nil {
+		err = db.Compact()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ints, err := db.IntKeys(keyNameCounter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ints, []int64{1, 10}) {
+		t.Fatalf("got integer keys %v, want [1 10]", ints)
+	}
+
+	ints, err = db.IntKeys(keyNameOther{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ints, []int64{2}) {
+		t.Fatalf("got integer keys %v, want [2]", ints)
+	}
+}
@@ -0,0 +1,158 @@
+package burrowdb
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// latestOption is an option which can be passed to LatestID to change how the
+// latest entity is chosen.
+type latestOption func(*latestConfig)
+
+// latestConfig holds the settings of a single LatestID.
+type latestConfig struct {
+	byID bool // whether the entity with the highest integer ID is chosen.
+}
+
+// WithLatestByID specifies that LatestID should choose the entity with the
+// highest integer ID rather than the most recently modified one. An error is
+// returned if any ID isn't an integer.
+func WithLatestByID() latestOption {
+	return func(c *latestConfig) {
+		c.byID = true
+	}
+}
+
+// LatestID returns the ID of the most recently modified entity with the type of
+// dst, for resuming work from where it was left off. Entities are compared by
+// the modification times of their files, or of their records in an append log,
+// with ties going to the highest ID. ErrNoSuchEntity is returned if no entity of
+// the type is stored.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+// The ID is returned with the type of its field.
+func (db *BurrowDB) LatestID(dst any, opts ...latestOption) (_ any, err error) {
+	defer db.handleError("LatestID", &err)
+
+	var cfg latestConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return nil, ErrInvalidDstType
+	}
+
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return nil, err
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var key string
+	if cfg.byID {
+		key, err = db.highestIntKey(typeName)
+	} else {
+		key, err = db.latestKey(typeName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := db.loadOne(_type, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Elem().FieldByIndex(idField.Index).Interface(), nil
+}
+
+// latestKey returns the key of the most recently modified entity of the named
+// type, as described by LatestID. The lock of the type must be held.
+func (db *BurrowDB) latestKey(typeName string) (string, error) {
+	modTimes, err := db.modTimes(typeName)
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestTime time.Time
+	for key, modTime := range modTimes {
+		c := modTime.Compare(latestTime)
+		if latest == "" || c > 0 || (c == 0 && db.compareKeys(typeName)(key, latest) > 0) {
+			latest, latestTime = key, modTime
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("%w: no %s is stored", ErrNoSuchEntity, typeName)
+	}
+
+	return latest, nil
+}
+
+// highestIntKey returns the key of the entity of the named type with the
+// highest integer ID. The lock of the type must be held.
+func (db *BurrowDB) highestIntKey(typeName string) (string, error) {
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return "", err
+	}
+
+	var highest string
+	var highestID int64
+	for _, key := range keys {
+		n, err := strconv.ParseInt(db.idText(typeName, key), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q", ErrNonIntegerKey, key)
+		}
+
+		if highest == "" || n > highestID {
+			highest, highestID = key, n
+		}
+	}
+
+	if highest == "" {
+		return "", fmt.Errorf("%w: no %s is stored", ErrNoSuchEntity, typeName)
+	}
+
+	return highest, nil
+}
+
+// modTimes returns the modification time of every entity of the named type
+// keyed by its key. The lock of the type must be held.
+func (db *BurrowDB) modTimes(typeName string) (map[string]time.Time, error) {
+	if db.isAppendLog(typeName) {
+		_, modTimes, err := db.logKeys(typeName)
+		return modTimes, err
+	}
+
+	modTimes := map[string]time.Time{}
+	err := db.eachEntityFile(typeName, func(entry fs.DirEntry) error {
+		key, ok := db.fileKey(entry)
+		if !ok {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to stat entity: %w", err)
+		}
+		modTimes[key] = info.ModTime()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return modTimes, nil
+}
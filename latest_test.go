@@ -0,0 +1,71 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+type latestItem struct {
+	ID   int
+	Name string
+}
+
+func TestLatestID(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.LatestID(latestItem{})
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for an empty type, want %v", err, ErrNoSuchEntity)
+	}
+
+	for _, id := range []int{3, 10, 2} {
+		err = db.Put(latestItem{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Make the modification times distinct whatever the filesystem's resolution.
+	start := time.Now().Add(-time.Hour)
+	for i, id := range []int{10, 2, 3} {
+		modTime := start.Add(time.Duration(i) * time.Minute)
+		err = os.Chtimes(db.entityPath("latestItem", db.entityKey(id)), modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id, err := db.LatestID(&latestItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Fatalf("got latest ID %v, want 3", id)
+	}
+
+	err = db.Put(latestItem{ID: 2, Name: "modified"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err = db.LatestID(latestItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 {
+		t.Fatalf("got latest ID %v after modifying 2, want 2", id)
+	}
+
+	id, err = db.LatestID(latestItem{}, WithLatestByID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 10 {
+		t.Fatalf("got highest ID %v, want 10", id)
+	}
+}
@@ -0,0 +1,74 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Layout decides where the file of each entity is stored.
+type Layout interface {
+	// PathFor returns the path of the file storing the entity of the named
+	// type with the passed key in the db dir. The path must be inside the
+	// type's directory, dir/typeName, and its final element must be the key
+	// so that scans can recover it. Directories between the two mustn't
+	// start with a dot. The key includes any extension set by
+	// WithFileExtension.
+	PathFor(dir, typeName, key string) string
+}
+
+// Flat stores every entity of a type directly in the type's directory, as
+// dir/typeName/key. This is the default layout.
+type Flat struct{}
+
+func (Flat) PathFor(dir, typeName, key string) string {
+	return fmt.Sprintf("%s/%s/%s", dir, typeName, key)
+}
+
+// Sharded spreads the entities of each type over nested sub-directories named
+// by a hash of their key, as dir/typeName/3f/key for one level. This keeps
+// directories small for types with very many entities.
+type Sharded struct {
+	Levels int // Number of levels of sub-directories, each of up to 256, from 0 to 4.
+}
+
+func (s Sharded) PathFor(dir, typeName, key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	sum := h.Sum32()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s", dir, typeName)
+	for i := range s.Levels {
+		fmt.Fprintf(&b, "/%02x", byte(sum>>(8*i)))
+	}
+	fmt.Fprintf(&b, "/%s", key)
+
+	return b.String()
+}
+
+// WithLayout specifies where the file of each entity is stored. The same
+// layout must be used every time a dir is opened, as entities stored with a
+// different layout aren't found.
+func WithLayout(layout Layout) newDBOption {
+	return func(db *BurrowDB) error {
+		if layout == nil {
+			return errors.New("layout is nil")
+		}
+
+		if s, ok := layout.(Sharded); ok && (s.Levels < 0 || s.Levels > 4) {
+			return errors.New("sharded layout levels must be between 0 and 4")
+		}
+
+		db.layout = layout
+		return nil
+	}
+}
+
+// isFlat reports whether the db stores every entity directly in its type's
+// directory.
+func (db *BurrowDB) isFlat() bool {
+	_, ok := db.layout.(Flat)
+	return db.layout == nil || ok
+}
@@ -0,0 +1,102 @@
+package burrowdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+type layoutItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestShardedPathFor(t *testing.T) {
+	layout := Sharded{Levels: 2}
+	got := layout.PathFor("db", "t", "key")
+	if got != layout.PathFor("db", "t", "key") {
+		t.Fatal("got different paths for the same key")
+	}
+
+	parts := strings.Split(got, "/")
+	if len(parts) != 5 || parts[0] != "db" || parts[1] != "t" || len(parts[2]) != 2 || len(parts[3]) != 2 || parts[4] != "key" {
+		t.Fatalf("got %s, want two levels of shard dirs", got)
+	}
+
+	if got := (Sharded{}).PathFor("db", "t", "key"); got != "db/t/key" {
+		t.Fatalf("got %s for no levels, want db/t/key", got)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, layout := range []Layout{Flat{}, Sharded{Levels: 2}} {
+		t.Run(fmt.Sprintf("%T", layout), func(t *testing.T) {
+			db, err := NewDB(WithDir(t.TempDir()), WithLayout(layout))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range 20 {
+				err = db.Put(layoutItem{ID: i, Status: "s"})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = os.Stat(layout.PathFor(db.dir, "layoutItem", "7"))
+			if err != nil {
+				t.Fatalf("got %v, want the entity stored where the layout puts it", err)
+			}
+
+			var items []layoutItem
+			err = db.GetAll(&items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 20 || items[19].ID != 19 {
+				t.Fatalf("got %d items, want all 20 in order", len(items))
+			}
+
+			report, err := db.Verify()
+			if err != nil || !report.OK() || report.Entities != 20 {
+				t.Fatalf("got %+v, %v", report, err)
+			}
+
+			err = db.Delete(layoutItem{}, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			err = db.Backup(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restored, err := NewDB(WithDir(t.TempDir()), WithLayout(layout))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = restored.Restore(&buf, ImportOverwrite)
+			if err == nil {
+				err = restored.GetByField(&items, "Status", "s")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 19 {
+				t.Fatalf("got %d items restored, want 19", len(items))
+			}
+		})
+	}
+}
+
+func TestShardedLevels(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithLayout(Sharded{Levels: 5}))
+	if err == nil {
+		t.Fatal("got no error for 5 levels")
+	}
+}
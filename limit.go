@@ -0,0 +1,129 @@
+package burrowdb
+
+import "fmt"
+
+// WithMaxEntities specifies the maximum number of entities of the named type.
+// Putting a new entity when the type already holds max returns ErrEntityLimit
+// and nothing is written, while overwriting an existing entity and deleting are
+// always allowed. This keeps one type from exhausting the inodes of the
+// filesystem.
+//
+// The entities are counted the first time the limit is checked and the count
+// is then kept up to date as entities are written and deleted by any db with
+// the same dir, so changes made to the files by other means aren't seen.
+func WithMaxEntities(typeName string, max int) newDBOption {
+	return func(db *BurrowDB) error {
+		if max < 1 {
+			return fmt.Errorf("max entities of %s must be positive", typeName)
+		}
+
+		if db.maxEntities == nil {
+			db.maxEntities = map[string]int{}
+		}
+
+		db.maxEntities[typeName] = max
+		return nil
+	}
+}
+
+// reserveEntity accounts for the entity of the named type with the passed key
+// being written, or deleted if deleting, returning ErrEntityLimit if writing it
+// would take the type over its maximum number of entities. The lock of the type
+// must be held.
+func (db *BurrowDB) reserveEntity(typeName, key string, deleting bool) error {
+	if isReservedType(typeName) {
+		return nil
+	}
+
+	count, ok, err := db.entityCount(typeName)
+	if err != nil || !ok {
+		return err
+	}
+
+	exists, err := db.entityExists(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case deleting && exists:
+		count--
+	case !deleting && !exists:
+		if max, ok := db.maxEntities[typeName]; ok && count >= max {
+			return fmt.Errorf("%w: %s already holds %d entities", ErrEntityLimit, typeName, count)
+		}
+		count++
+	}
+
+	db.setEntityCount(typeName, count)
+	return nil
+}
+
+// reserveBatchEntities accounts for every staged write of a batch, as
+// reserveEntity does, checking the limit of each type against the batch as a
+// whole. The locks of the types must be held.
+func (db *BurrowDB) reserveBatchEntities(writes []*batchWrite) error {
+	added := map[string]int{}
+	for _, w := range writes {
+		if !w.existed {
+			added[w.enc.typeName]++
+		}
+	}
+
+	counts := map[string]int{}
+	for typeName, n := range added {
+		count, ok, err := db.entityCount(typeName)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if max, ok := db.maxEntities[typeName]; ok && count+n > max {
+			return fmt.Errorf("%w: %s holds %d entities and can't hold %d more", ErrEntityLimit, typeName, count, n)
+		}
+		counts[typeName] = count + n
+	}
+
+	for typeName, count := range counts {
+		db.setEntityCount(typeName, count)
+	}
+
+	return nil
+}
+
+// entityCount returns the number of entities of the named type, counting them
+// if the type has a maximum and they haven't been counted yet. False is
+// returned if they aren't being counted. The lock of the type must be held.
+func (db *BurrowDB) entityCount(typeName string) (int, bool, error) {
+	s := &db.locks.size
+	s.mu.Lock()
+	count, ok := s.counts[typeName]
+	s.mu.Unlock()
+	if ok {
+		return count, true, nil
+	}
+
+	if _, limited := db.maxEntities[typeName]; !limited {
+		return 0, false, nil
+	}
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to count entities: %w", err)
+	}
+
+	return len(keys), true, nil
+}
+
+// setEntityCount records the number of entities of the named type.
+func (db *BurrowDB) setEntityCount(typeName string, count int) {
+	s := &db.locks.size
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counts == nil {
+		s.counts = map[string]int{}
+	}
+	s.counts[typeName] = count
+}
@@ -0,0 +1,67 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+)
+
+type limitItem struct {
+	ID   int
+	Name string
+}
+
+func TestMaxEntities(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithMaxEntities("limitItem", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		err = db.Put(limitItem{ID: i + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.Put(limitItem{ID: 4})
+	if !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("got %v putting beyond the limit, want %v", err, ErrEntityLimit)
+	}
+
+	err = db.PutBatch(limitItem{ID: 1, Name: "batch"}, limitItem{ID: 5})
+	if !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("got %v putting a batch beyond the limit, want %v", err, ErrEntityLimit)
+	}
+
+	// Overwrites are allowed at the limit, and deleting makes room.
+	err = db.Put(limitItem{ID: 2, Name: "overwritten"})
+	if err == nil {
+		err = db.Delete(limitItem{}, 1)
+	}
+	if err == nil {
+		err = db.Put(limitItem{ID: 4})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The count is shared with other dbs using the dir.
+	other, err := NewDB(WithDir(dir), WithMaxEntities("limitItem", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = other.Put(limitItem{ID: 5})
+	if !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("got %v from another db, want %v", err, ErrEntityLimit)
+	}
+
+	keys, err := db.IntKeys(limitItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("got keys %v, want 3", keys)
+	}
+}
@@ -0,0 +1,62 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// WithLoader specifies a function GetByID calls when the entity it is asked for
+// isn't stored, making the db a read-through cache of another source. The
+// loader is passed the name of the entity's type and the ID, and returns the
+// entity, as a struct or pointer to one of the requested type with the same ID,
+// and whether it was found. A found entity is put, as Put does, before being
+// returned, while ErrNoSuchEntity is returned if it wasn't.
+//
+// The loader is called without holding any lock, so concurrent misses for the
+// same entity may each call it.
+func WithLoader(load func(typeName string, id any) (any, bool, error)) newDBOption {
+	return func(db *BurrowDB) error {
+		if load == nil {
+			return errors.New("loader is nil")
+		}
+
+		db.loader = load
+		return nil
+	}
+}
+
+// readThrough loads the entity of the named type with the passed ID and key
+// using the db's loader, puts it, and decodes what was stored into dst. The
+// loader is passed the value of a pointer ID.
+func (db *BurrowDB) readThrough(dst any, typeName string, id any, key string) error {
+	id, err := derefID(id)
+	if err != nil {
+		return err
+	}
+
+	v, ok, err := db.loader(typeName, id)
+	if err != nil {
+		return fmt.Errorf("unable to load %s %v: %w", typeName, id, err)
+	} else if !ok {
+		return ErrNoSuchEntity
+	}
+
+	_type := reflect.TypeOf(dst).Elem()
+	if reflect.TypeOf(v) == reflect.PointerTo(_type) && !reflect.ValueOf(v).IsNil() {
+		v = reflect.ValueOf(v).Elem().Interface()
+	}
+	if reflect.TypeOf(v) != _type {
+		return fmt.Errorf("%w: loader returned %T, expected %s", ErrInvalidValueType, v, _type)
+	}
+
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	err = db.put(v)
+	mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("unable to store loaded %s %v: %w", typeName, id, err)
+	}
+
+	return db.getByID(dst, typeName, key)
+}
@@ -0,0 +1,78 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+)
+
+type loaderItem struct {
+	ID   int
+	Name string
+}
+
+func TestLoader(t *testing.T) {
+	calls := 0
+	load := func(typeName string, id any) (any, bool, error) {
+		calls++
+		if typeName != "loaderItem" {
+			t.Errorf("got type %s, want loaderItem", typeName)
+		}
+
+		if id == 1 {
+			return &loaderItem{ID: 1, Name: "loaded"}, true, nil
+		}
+		return nil, false, nil
+	}
+
+	db, err := NewDB(WithDir(t.TempDir()), WithLoader(load))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got loaderItem
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (loaderItem{ID: 1, Name: "loaded"}) || calls != 1 {
+		t.Fatalf("got %+v after %d calls, want the loaded entity after 1", got, calls)
+	}
+
+	// The second get is served by the store.
+	got = loaderItem{}
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "loaded" || calls != 1 {
+		t.Fatalf("got %+v after %d calls, want the stored entity without loading again", got, calls)
+	}
+
+	err = db.GetByID(&got, 2)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for an entity the loader doesn't have, want %v", err, ErrNoSuchEntity)
+	}
+
+	ids, err := db.IntKeys(loaderItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("got keys %v, want only the loaded entity stored", ids)
+	}
+}
+
+func TestLoaderWrongType(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithLoader(func(string, any) (any, bool, error) {
+		return "not an entity", true, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got loaderItem
+	err = db.GetByID(&got, 1)
+	if !errors.Is(err, ErrInvalidValueType) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidValueType)
+	}
+}
@@ -0,0 +1,207 @@
+package burrowdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// lockSet holds the locks guarding each type stored in a single directory.
+type lockSet struct {
+	mu    sync.Mutex
+	types map[string]*sync.RWMutex
+
+	changeLog sync.Mutex // guards the change log.
+	blobs     sync.Mutex // guards content addressed data.
+	applied   sync.Mutex // guards the number of applied change log entries.
+
+	changed chan struct{} // closed when an entry is added to the change log, guarded by changeLog.
+
+	appendLogs map[string]*appendLog // indexes of append logs keyed by type, guarded by mu.
+	partitions map[string]string     // partitions of entities being put keyed by type dir and key, guarded by mu.
+
+	keyNames map[string]*keyNameLog // indexes of key names files keyed by type dir, guarded by mu.
+	keyTexts sync.Map               // texts of IDs keyed by type dir and the filenames WithEncryptedKeys derives from them.
+
+	size storeSize // total size of the entities, guarded by its own mutex.
+}
+
+// forType returns the lock guarding the named type, creating it if it doesn't
+// already exist.
+func (l *lockSet) forType(typeName string) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	mu, ok := l.types[typeName]
+	if !ok {
+		mu = &sync.RWMutex{}
+		l.types[typeName] = mu
+	}
+
+	return mu
+}
+
+// forAppendLog returns the index of the append log of the named type, creating
+// an empty one if it doesn't already exist.
+func (l *lockSet) forAppendLog(typeName string) *appendLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.appendLogs[typeName]
+	if !ok {
+		log = &appendLog{}
+		if l.appendLogs == nil {
+			l.appendLogs = map[string]*appendLog{}
+		}
+		l.appendLogs[typeName] = log
+	}
+
+	return log
+}
+
+// forgetAppendLog discards the index of the append log of the named type, so
+// that it is indexed again when next used.
+func (l *lockSet) forgetAppendLog(typeName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.appendLogs, typeName)
+}
+
+// forKeyNames returns the index of the key names file of the named type,
+// creating an empty one if it doesn't already exist.
+func (l *lockSet) forKeyNames(typeName string) *keyNameLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.keyNames[typeName]
+	if !ok {
+		log = &keyNameLog{}
+		if l.keyNames == nil {
+			l.keyNames = map[string]*keyNameLog{}
+		}
+		l.keyNames[typeName] = log
+	}
+
+	return log
+}
+
+// forgetKeyNames discards the index of the key names file of the named type,
+// so that it is read again when next used.
+func (l *lockSet) forgetKeyNames(typeName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keyNames, typeName)
+}
+
+// partitionHint returns the partition the entity with the passed key in the
+// passed type dir is being put in, if it is being put.
+func (l *lockSet) partitionHint(typeDir, key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	partition, ok := l.partitions[typeDir+"/"+key]
+	return partition, ok
+}
+
+// setPartitionHint records that the entity with the passed key in the passed
+// type dir is being put in the passed partition.
+func (l *lockSet) setPartitionHint(typeDir, key, partition string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.partitions == nil {
+		l.partitions = map[string]string{}
+	}
+	l.partitions[typeDir+"/"+key] = partition
+}
+
+// clearPartitionHint removes the hint set by setPartitionHint.
+func (l *lockSet) clearPartitionHint(typeDir, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.partitions, typeDir+"/"+key)
+}
+
+// lockAll acquires the lock of every type, along with the locks guarding data
+// shared between types, returning a function which releases them. The type
+// locks are acquired in ascending order of name.
+func (l *lockSet) lockAll() func() {
+	return l.acquireAll((*sync.RWMutex).Lock, (*sync.RWMutex).Unlock)
+}
+
+// rLockAll acquires the read lock of every type, along with the locks guarding
+// data shared between types, returning a function which releases them. The
+// type locks are acquired in ascending order of name.
+func (l *lockSet) rLockAll() func() {
+	return l.acquireAll((*sync.RWMutex).RLock, (*sync.RWMutex).RUnlock)
+}
+
+// acquireAll acquires every type lock with lock, in ascending order of name,
+// then the locks guarding data shared between types, returning a function
+// which releases them using unlock.
+func (l *lockSet) acquireAll(lock, unlock func(*sync.RWMutex)) func() {
+	l.mu.Lock()
+	names := make([]string, 0, len(l.types))
+	for name := range l.types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	mus := make([]*sync.RWMutex, len(names))
+	for i, name := range names {
+		mus[i] = l.types[name]
+	}
+	l.mu.Unlock()
+
+	for _, mu := range mus {
+		lock(mu)
+	}
+	// Apply holds the applied lock while writing the change log and blobs.
+	l.applied.Lock()
+	l.changeLog.Lock()
+	l.blobs.Lock()
+
+	return func() {
+		l.blobs.Unlock()
+		l.changeLog.Unlock()
+		l.applied.Unlock()
+		for _, mu := range slices.Backward(mus) {
+			unlock(mu)
+		}
+	}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*lockSet{} // Lock sets keyed by canonical directory.
+)
+
+// locksFor returns the lock set for the passed directory. Every BurrowDB opened
+// on the same directory within this process shares a lock set, regardless of
+// the path used to reach it. The directory must already exist.
+func locksFor(dir string) (*lockSet, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path: %w", err)
+	}
+
+	canonical, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve symlinks: %w", err)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	locks, ok := registry[canonical]
+	if !ok {
+		locks = &lockSet{types: map[string]*sync.RWMutex{}}
+		registry[canonical] = locks
+	}
+
+	return locks, nil
+}
@@ -0,0 +1,38 @@
+package burrowdb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type lockItem struct {
+	ID int
+}
+
+func TestLocksShared(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same dir reached by another path shares the locks.
+	b, err := NewDB(WithDir(filepath.Join(dir, ".")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.locks != b.locks {
+		t.Fatal("handles on the same dir have different lock sets")
+	}
+	if a.typeLock("lockItem") != b.typeLock("lockItem") {
+		t.Fatal("handles on the same dir have different type locks")
+	}
+
+	c, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.locks == c.locks {
+		t.Fatal("handles on different dirs share a lock set")
+	}
+}
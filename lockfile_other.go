@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package burrowdb
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(f *os.File) error {
+	return errors.New("process locks are not supported on this platform")
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(f *os.File) error {
+	return errors.New("process locks are not supported on this platform")
+}
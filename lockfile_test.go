@@ -0,0 +1,54 @@
+//go:build unix || windows
+
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestProcessLock(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithProcessLock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock is held per process, so it's contended from a child.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProcessLockHelper$")
+	cmd.Env = append(os.Environ(), "BURROWDB_LOCK_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDB(WithDir(dir), WithProcessLock())
+	if err != nil {
+		t.Fatalf("got %v reopening after Close", err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestProcessLockHelper is run by TestProcessLock in a child process, and
+// passes if the lock of the dir is held by another process.
+func TestProcessLockHelper(t *testing.T) {
+	dir := os.Getenv("BURROWDB_LOCK_DIR")
+	if dir == "" {
+		t.Skip("run by TestProcessLock")
+	}
+
+	_, err := NewDB(WithDir(dir), WithProcessLock())
+	if !errors.Is(err, ErrStoreLocked) {
+		t.Fatalf("got %v, want %v", err, ErrStoreLocked)
+	}
+}
@@ -0,0 +1,323 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Merge reads the stored entity with the same type and ID as v, passes it and v
+// to combine, and puts the value combine returns. This is done under the
+// type's lock so no other write can happen in between, making it suitable for
+// read-modify-write updates such as counters.
+//
+// If there is no stored entity, combine is passed a nil existing value. The
+// value returned by combine must have the same type as v and is stored under
+// its own ID.
+func (db *BurrowDB) Merge(v any, combine func(existing, incoming any) (any, error)) (err error) {
+	defer db.handleError("Merge", &err)
+
+	_type := reflect.TypeOf(v)
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return err
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	key, err := db.idKey(reflect.ValueOf(v).FieldByIndex(idField.Index).Interface())
+	if err != nil {
+		return err
+	}
+
+	existing, err := db.existing(_type, key)
+	if err != nil {
+		return err
+	}
+
+	result, err := combine(existing, v)
+	if err != nil {
+		return fmt.Errorf("unable to combine values: %w", err)
+	}
+
+	if reflect.TypeOf(result) != _type {
+		return fmt.Errorf("%w: combine returned %T, expected %s", ErrInvalidValueType, result, _type)
+	}
+
+	return db.put(result)
+}
+
+// PutIf puts v, as Put does, only if cond reports true for the stored entity
+// with the same type and ID, which is passed as nil if there is none. Whether v
+// was put is returned. This is done under the type's lock so the stored entity
+// can't change between cond being checked and v being put, making it suitable
+// for guarding state transitions.
+func (db *BurrowDB) PutIf(v any, cond func(existing any) bool) (_ bool, err error) {
+	defer db.handleError("PutIf", &err)
+
+	_type := reflect.TypeOf(v)
+	if _type.Kind() != reflect.Struct {
+		return false, ErrInvalidValueType
+	}
+
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return false, err
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	key, err := db.idKey(reflect.ValueOf(v).FieldByIndex(idField.Index).Interface())
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := db.existing(_type, key)
+	if err != nil {
+		return false, err
+	}
+
+	if !cond(existing) {
+		return false, nil
+	}
+
+	err = db.put(v)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Upsert puts v, as Put does, returning the entity it replaced and whether there
+// was one. This is done under the type's lock so no other write can happen
+// between the previous entity being read and v being put, making it suitable
+// for swap-style updates.
+func Upsert[T any](db *BurrowDB, v T) (prev T, existed bool, err error) {
+	defer db.handleError("Upsert", &err)
+
+	_type := reflect.TypeOf(v)
+	if _type == nil || _type.Kind() != reflect.Struct {
+		return prev, false, ErrInvalidValueType
+	}
+
+	idField, err := db.findIDField(_type)
+	if err != nil {
+		return prev, false, err
+	}
+
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	key, err := db.idKey(reflect.ValueOf(v).FieldByIndex(idField.Index).Interface())
+	if err != nil {
+		return prev, false, err
+	}
+
+	existing, err := db.existing(_type, key)
+	if err != nil {
+		return prev, false, err
+	}
+
+	err = db.put(v)
+	if err != nil {
+		return prev, false, err
+	}
+
+	if existing == nil {
+		return prev, false, nil
+	}
+
+	return existing.(T), true, nil
+}
+
+// existing returns the stored entity of the passed type with the passed key, or
+// nil if there is none. The lock of the type must be held.
+func (db *BurrowDB) existing(_type reflect.Type, key string) (any, error) {
+	v, err := db.loadOne(_type, key)
+	if errors.Is(err, ErrNoSuchEntity) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// MapInPlace decodes each stored entity with the type of dst into dst, calls fn
+// to modify it and puts the result back, visiting entities in the db's sort
+// order. Each entity is read, modified and put under the type's lock, which is
+// released between entities so other writes can interleave. If fn returns
+// ErrUnchanged the entity isn't written. Any other error stops the iteration
+// and is returned.
+//
+// The dst must be a pointer to a struct. fn mustn't change its ID, as the
+// modified value is put under its own ID.
+func (db *BurrowDB) MapInPlace(dst any, fn func() error) (err error) {
+	defer db.handleError("MapInPlace", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type.Elem())
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	keys, err := db.keys(typeName)
+	mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = db.mapEntity(dst, key, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapEntity decodes the entity with the type of dst and the passed key into
+// dst, calls fn and puts the result, as described by MapInPlace. An entity
+// deleted since its key was read is skipped.
+func (db *BurrowDB) mapEntity(dst any, key string, fn func() error) error {
+	_type := reflect.TypeOf(dst).Elem()
+	mu := db.typeLock(db.typeName(_type))
+	mu.Lock()
+	defer mu.Unlock()
+
+	v, err := db.loadOne(_type, key)
+	if errors.Is(err, ErrNoSuchEntity) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	reflect.ValueOf(dst).Elem().Set(v.Elem())
+
+	err = fn()
+	if errors.Is(err, ErrUnchanged) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return db.put(reflect.ValueOf(dst).Elem().Interface())
+}
+
+// Increment adds delta to the named integer field of the entity with the type
+// of dst and the passed ID, puts it and returns the field's new value. The
+// updated entity is decoded into dst, which must be a pointer to a struct. This
+// is done under the type's lock so concurrent increments aren't lost. An error
+// is returned if the field isn't an integer or the result would overflow it.
+func (db *BurrowDB) Increment(dst any, id any, field string, delta int64) (_ int64, err error) {
+	defer db.handleError("Increment", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return 0, ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Struct {
+		return 0, ErrInvalidDstType
+	}
+
+	structField, ok := _type.Elem().FieldByName(field)
+	if !ok {
+		return 0, fmt.Errorf("type %s has no field %s", _type.Elem(), field)
+	}
+
+	switch structField.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		return 0, fmt.Errorf("field %s of %s is a %s rather than an integer", field, _type.Elem(), structField.Type)
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return 0, err
+	}
+
+	mu := db.typeLock(db.typeName(_type.Elem()))
+	mu.Lock()
+	defer mu.Unlock()
+
+	v, err := db.loadOne(_type.Elem(), key)
+	if err != nil {
+		return 0, err
+	}
+
+	f := v.Elem().FieldByIndex(structField.Index)
+	n := f.Int() + delta
+	if (delta > 0 && n < f.Int()) || (delta < 0 && n > f.Int()) || f.OverflowInt(n) {
+		return 0, fmt.Errorf("incrementing field %s of %s by %d overflows it", field, _type.Elem(), delta)
+	}
+	f.SetInt(n)
+
+	err = db.put(v.Elem().Interface())
+	if err != nil {
+		return 0, err
+	}
+	reflect.ValueOf(dst).Elem().Set(v.Elem())
+
+	return n, nil
+}
+
+// UpdateFunc decodes the entity with the type of dst and the passed ID into dst,
+// calls fn to modify it and puts the result. This is done under the type's lock
+// so no other write can happen in between, and concurrent updates of the same
+// entity are applied one after another rather than conflicting, so no update is
+// lost and none needs retrying. If fn returns ErrUnchanged nothing is put, and
+// any other error is returned. ErrNoSuchEntity is returned if there is no such
+// entity.
+func (db *BurrowDB) UpdateFunc(dst any, id any, fn func() error) (err error) {
+	defer db.handleError("UpdateFunc", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	mu := db.typeLock(db.typeName(_type.Elem()))
+	mu.Lock()
+	defer mu.Unlock()
+
+	v, err := db.loadOne(_type.Elem(), key)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(dst).Elem().Set(v.Elem())
+
+	err = fn()
+	if errors.Is(err, ErrUnchanged) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return db.put(reflect.ValueOf(dst).Elem().Interface())
+}
@@ -0,0 +1,262 @@
+package burrowdb
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type mergeCounter struct {
+	ID    string
+	Count int
+}
+
+func TestMerge(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(existing, v any) (any, error) {
+		c := v.(mergeCounter)
+		if existing != nil {
+			c.Count += existing.(mergeCounter).Count
+		}
+		return c, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			err := db.Merge(mergeCounter{ID: "c", Count: 1}, add)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var c mergeCounter
+	err = db.GetByID(&c, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Count != 50 {
+		t.Fatalf("got a count of %d, want 50", c.Count)
+	}
+}
+
+func TestMergeError(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCombine := errors.New("combine failed")
+	err = db.Merge(mergeCounter{ID: "c", Count: 1}, func(existing, v any) (any, error) {
+		return nil, errCombine
+	})
+	if !errors.Is(err, errCombine) {
+		t.Fatalf("got %v, want %v", err, errCombine)
+	}
+
+	err = db.GetByID(&mergeCounter{}, "c")
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want nothing stored", err)
+	}
+}
+
+func TestPutIf(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isPending := func(existing any) bool {
+		return existing != nil && existing.(mergeCounter).Count == 0
+	}
+
+	ok, err := db.PutIf(mergeCounter{ID: "c", Count: 1}, isPending)
+	if err != nil || ok {
+		t.Fatalf("got %t, %v without a stored entity, want false", ok, err)
+	}
+
+	err = db.Put(mergeCounter{ID: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = db.PutIf(mergeCounter{ID: "c", Count: 1}, isPending)
+	if err != nil || !ok {
+		t.Fatalf("got %t, %v, want true", ok, err)
+	}
+
+	ok, err = db.PutIf(mergeCounter{ID: "c", Count: 2}, isPending)
+	if err != nil || ok {
+		t.Fatalf("got %t, %v once the condition fails, want false", ok, err)
+	}
+
+	var c mergeCounter
+	err = db.GetByID(&c, "c")
+	if err != nil || c.Count != 1 {
+		t.Fatalf("got %+v, %v, want only the first conditional put stored", c, err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev, replaced, err := Upsert(db, mergeCounter{ID: "c", Count: 1})
+	if err != nil || replaced || prev != (mergeCounter{}) {
+		t.Fatalf("got %+v, %t, %v for a new entity", prev, replaced, err)
+	}
+
+	prev, replaced, err = Upsert(db, mergeCounter{ID: "c", Count: 2})
+	if err != nil || !replaced || prev.Count != 1 {
+		t.Fatalf("got %+v, %t, %v, want the replaced entity", prev, replaced, err)
+	}
+
+	var c mergeCounter
+	err = db.GetByID(&c, "c")
+	if err != nil || c.Count != 2 {
+		t.Fatalf("got %+v, %v, want the new value stored", c, err)
+	}
+
+	_, _, err = Upsert(db, &mergeCounter{})
+	if !errors.Is(err, ErrInvalidValueType) {
+		t.Fatalf("got %v for a pointer, want %v", err, ErrInvalidValueType)
+	}
+}
+
+type mergeName struct {
+	ID   int
+	Name string
+}
+
+func TestMapInPlace(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(mergeName{ID: 1, Name: "ann"}, mergeName{ID: 2, Name: "BOB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v mergeName
+	var written []int
+	err = db.MapInPlace(&v, func() error {
+		upper := strings.ToUpper(v.Name)
+		if upper == v.Name {
+			return ErrUnchanged
+		}
+		v.Name = upper
+		written = append(written, v.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(written, []int{1}) {
+		t.Fatalf("got %v modified, want [1]", written)
+	}
+
+	var all []mergeName
+	err = db.GetAll(&all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []mergeName{{1, "ANN"}, {2, "BOB"}}; !slices.Equal(all, want) {
+		t.Fatalf("got %+v, want %+v", all, want)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(mergeCounter{ID: "hits", Count: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			var counter mergeCounter
+			_, err := db.Increment(&counter, "hits", "Count", 2)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var counter mergeCounter
+	n, err := db.Increment(&counter, "hits", "Count", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 104 || counter.Count != 104 {
+		t.Fatalf("got %d and %+v, want 104", n, counter)
+	}
+
+	_, err = db.Increment(&counter, "hits", "ID", 1)
+	if err == nil {
+		t.Fatal("got nil error incrementing a string field")
+	}
+
+	_, err = db.Increment(&counter, "misses", "Count", 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestUpdateFunc(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(mergeCounter{ID: "hits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			var counter mergeCounter
+			err := db.UpdateFunc(&counter, "hits", func() error {
+				counter.Count++
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var counter mergeCounter
+	err = db.GetByID(&counter, "hits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.Count != 50 {
+		t.Fatalf("got count %d, want 50", counter.Count)
+	}
+
+	err = db.UpdateFunc(&counter, "misses", func() error { return nil })
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+}
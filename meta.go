@@ -0,0 +1,137 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+const metaDirName = ".meta" // Name of the directory in each type dir holding the metadata of individual entities.
+
+// SetMeta replaces the metadata of the entity with the type of dst and the
+// passed ID with meta, such as tags or an owner, which is stored as JSON in a
+// file alongside the entity rather than in it, so it can be attached without
+// changing the struct. Passing empty metadata removes it, as does deleting the
+// entity. The dst may be a struct or a pointer to one and is only used for its
+// type. ErrNoSuchEntity is returned if there is no such entity.
+func (db *BurrowDB) SetMeta(dst any, id any, meta map[string]string) (err error) {
+	defer db.handleError("SetMeta", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	exists, err := db.entityExists(typeName, key)
+	if err != nil {
+		return err
+	} else if !exists {
+		return ErrNoSuchEntity
+	}
+
+	if len(meta) == 0 {
+		return db.removeMeta(typeName, key)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("unable to marshal metadata: %w", err)
+	}
+
+	err = db.mkdirAll(fmt.Sprintf("%s/%s", db.typeDir(typeName), metaDirName))
+	if err != nil {
+		return fmt.Errorf("unable to create metadata dir: %w", err)
+	}
+
+	err = db.writeFileAtomic(db.metaPath(typeName, key), data)
+	if err != nil {
+		return fmt.Errorf("unable to write metadata: %w", err)
+	}
+
+	return nil
+}
+
+// GetMeta returns the metadata set by SetMeta on the entity with the type of
+// dst and the passed ID, or nil if it has none. The dst may be a struct or a
+// pointer to one and is only used for its type. ErrNoSuchEntity is returned if
+// there is no such entity.
+func (db *BurrowDB) GetMeta(dst any, id any) (_ map[string]string, err error) {
+	defer db.handleError("GetMeta", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return nil, ErrInvalidDstType
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	exists, err := db.entityExists(typeName, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrNoSuchEntity
+	}
+
+	data, err := os.ReadFile(db.metaPath(typeName, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read metadata: %w", err)
+	}
+
+	var meta map[string]string
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal metadata of %s %q: %w", typeName, key, err)
+	}
+
+	return meta, nil
+}
+
+// removeMeta removes the metadata of the entity of the named type with the
+// passed key, if it has any.
+func (db *BurrowDB) removeMeta(typeName, key string) error {
+	err := os.Remove(db.metaPath(typeName, key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove metadata: %w", err)
+	}
+	return nil
+}
+
+// metaPath returns the path of the file holding the metadata of the entity of
+// the named type with the passed key.
+func (db *BurrowDB) metaPath(typeName, key string) string {
+	return fmt.Sprintf("%s/%s/%s", db.typeDir(typeName), metaDirName, key)
+}
+
+// entityExists reports whether there is an entity of the named type with the
+// passed key.
+func (db *BurrowDB) entityExists(typeName, key string) (bool, error) {
+	_, err := db.entitySize(typeName, key)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrNoSuchEntity) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
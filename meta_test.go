@@ -0,0 +1,86 @@
+package burrowdb
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+type metaItem struct {
+	ID   int
+	Name string
+}
+
+func TestMeta(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(metaItem{ID: 1, Name: "tagged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := db.GetMeta(metaItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != nil {
+		t.Fatalf("got %v before setting metadata, want nil", meta)
+	}
+
+	want := map[string]string{"owner": "alice", "tags": "red,blue"}
+	err = db.SetMeta(metaItem{}, 1, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err = db.GetMeta(&metaItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(meta, want) {
+		t.Fatalf("got %v, want %v", meta, want)
+	}
+
+	// The metadata is kept apart from the entity.
+	var got metaItem
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (metaItem{ID: 1, Name: "tagged"}) {
+		t.Fatalf("got %+v, want the entity unchanged", got)
+	}
+
+	err = db.SetMeta(metaItem{}, 2, want)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v setting metadata of a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+
+	// Deleting the entity clears its metadata, so it isn't seen if the entity
+	// is put again.
+	err = db.Delete(metaItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.GetMeta(metaItem{}, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v after deleting, want %v", err, ErrNoSuchEntity)
+	}
+
+	err = db.Put(metaItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err = db.GetMeta(metaItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != nil {
+		t.Fatalf("got %v after putting the entity again, want nil", meta)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package burrowdb
+
+// mmapFile isn't supported on this platform so files are always read normally.
+func mmapFile(filename string, minSize int64) ([]byte, func(), bool) {
+	return nil, nil, false
+}
@@ -0,0 +1,86 @@
+package burrowdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type mmapItem struct {
+	ID   int
+	Body string
+}
+
+func TestMmap(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithMmap(1<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One entity is large enough to be mapped and the other isn't.
+	big := strings.Repeat("x", 1<<20)
+	err = db.PutAll(mmapItem{ID: 1, Body: big}, mmapItem{ID: 2, Body: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item mmapItem
+	err = db.GetByID(&item, 1)
+	if err != nil || item.Body != big {
+		t.Fatalf("got %v, want the mapped entity", err)
+	}
+
+	var items []mmapItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Body != big || items[1].Body != "small" {
+		t.Fatal("got the wrong entities scanning")
+	}
+
+	err = db.GetByID(&item, 3)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+func TestMmapNegative(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithMmap(-1))
+	if err == nil {
+		t.Fatal("got no error for a negative size")
+	}
+}
+
+// BenchmarkMmap compares decoding a large entity from a memory mapped file
+// with reading it into memory first.
+func BenchmarkMmap(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []newDBOption
+	}{
+		{"ReadFile", nil},
+		{"Mmap", []newDBOption{WithMmap(0)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			db, err := NewDB(append([]newDBOption{WithDir(b.TempDir())}, bench.opts...)...)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			err = db.Put(mmapItem{ID: 1, Body: strings.Repeat("x", 4<<20)})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.SetBytes(4 << 20)
+			for b.Loop() {
+				var item mmapItem
+				err = db.GetByID(&item, 1)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package burrowdb
+
+import (
+	"math"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file at the passed path into memory if it has at least
+// minSize bytes, returning its contents and a function which unmaps them. False
+// is returned if the file can't be mapped, so it should be read normally.
+func mmapFile(filename string, minSize int64) ([]byte, func(), bool) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, nil, false
+	}
+
+	size := info.Size()
+	if size == 0 || size < minSize || size > math.MaxInt {
+		return nil, nil, false
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	return data, func() { syscall.Munmap(data) }, true
+}
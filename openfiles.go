@@ -0,0 +1,56 @@
+package burrowdb
+
+import "fmt"
+
+// OpenFilesPolicy is what an operation does when it needs to open a file while
+// the number of files set by WithMaxOpenFiles are already open.
+type OpenFilesPolicy int
+
+const (
+	// WaitForFile blocks the operation until another closes its file. This is
+	// the default.
+	WaitForFile OpenFilesPolicy = iota
+
+	// FailOnMaxFiles returns ErrTooManyOpenFiles from the operation without
+	// waiting.
+	FailOnMaxFiles
+)
+
+// WithMaxOpenFiles specifies the maximum number of files the db holds open at
+// once, counting entity files, temp files, append logs, the change log and the
+// write-ahead log, and what an operation does when it needs another. This keeps
+// scans with a high parallelism, or many concurrent callers, within the
+// process's file descriptor limit. Files opened by whole-store operations such
+// as Backup and Compact aren't counted.
+func WithMaxOpenFiles(n int, policy OpenFilesPolicy) newDBOption {
+	return func(db *BurrowDB) error {
+		if n < 1 {
+			return fmt.Errorf("max open files must be positive")
+		}
+
+		db.openFiles = make(chan struct{}, n)
+		db.openFilesPolicy = policy
+		return nil
+	}
+}
+
+// acquireFile reserves one of the files which may be open at once, returning a
+// function to release it once the file is closed. It waits for a file to be
+// released, or returns ErrTooManyOpenFiles, as decided by the db's policy.
+func (db *BurrowDB) acquireFile() (func(), error) {
+	if db.openFiles == nil {
+		return func() {}, nil
+	}
+
+	if db.openFilesPolicy == FailOnMaxFiles {
+		select {
+		case db.openFiles <- struct{}{}:
+		default:
+			return nil, fmt.Errorf("%w: %d files are already open", ErrTooManyOpenFiles, cap(db.openFiles))
+		}
+	} else {
+		db.openFiles <- struct{}{}
+	}
+
+	return func() { <-db.openFiles }, nil
+}
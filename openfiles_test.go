@@ -0,0 +1,137 @@
+package burrowdb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type openFilesItem struct {
+	ID   int
+	Name string
+}
+
+func TestMaxOpenFiles(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithParallelism(8), WithMaxOpenFiles(2, WaitForFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 20 {
+		err = db.Put(openFilesItem{ID: i + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hold every file so that a read must wait for one to be released.
+	release := make([]func(), 2)
+	for i := range release {
+		release[i], err = db.acquireFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		var item openFilesItem
+		done <- db.GetByID(&item, 1)
+	}()
+
+	select {
+	case err = <-done:
+		t.Fatalf("got %v before a file was released, want the read to wait", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release[0]()
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	release[1]()
+
+	// Concurrent scans and writes never hold more than the budget.
+	stop := make(chan struct{})
+	var peak int
+	var sampler sync.WaitGroup
+	sampler.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				peak = max(peak, len(db.openFiles))
+			}
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			var items []openFilesItem
+			err := db.GetAll(&items)
+			if err == nil && len(items) != 20 {
+				err = errors.New("scan missed entities")
+			}
+			if err == nil {
+				err = db.Put(openFilesItem{ID: i + 1, Name: "concurrent"})
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	close(stop)
+	sampler.Wait()
+
+	if peak > 2 {
+		t.Fatalf("got %d files open at once, want at most 2", peak)
+	}
+
+	if cfg := db.Config(); cfg.MaxOpenFiles != 2 || cfg.OpenFilesPolicy != WaitForFile {
+		t.Fatalf("got %d files with policy %d in config, want 2 with %d", cfg.MaxOpenFiles, cfg.OpenFilesPolicy, WaitForFile)
+	}
+}
+
+func TestMaxOpenFilesFail(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithMaxOpenFiles(1, FailOnMaxFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(openFilesItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := db.acquireFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item openFilesItem
+	err = db.GetByID(&item, 1)
+	if !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Fatalf("got %v with every file open, want %v", err, ErrTooManyOpenFiles)
+	}
+
+	err = db.Put(openFilesItem{ID: 2})
+	if !errors.Is(err, ErrTooManyOpenFiles) {
+		t.Fatalf("got %v putting with every file open, want %v", err, ErrTooManyOpenFiles)
+	}
+
+	release()
+	err = db.GetByID(&item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewDB(WithDir(t.TempDir()), WithMaxOpenFiles(0, WaitForFile))
+	if err == nil {
+		t.Fatal("got no error for a max of 0 open files")
+	}
+}
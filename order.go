@@ -0,0 +1,72 @@
+package burrowdb
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortOrder is the order in which scans such as GetAll visit entities.
+type SortOrder int
+
+const (
+	// Ascending visits entities with integer keys in numeric order, followed by
+	// the remaining entities in lexical order. This is the default.
+	Ascending SortOrder = iota
+
+	// Descending visits entities in the reverse of Ascending.
+	Descending
+
+	// Insertion visits entities from least to most recently written, using the
+	// modification time of their files. Overwriting an entity moves it to the
+	// end.
+	Insertion
+)
+
+// WithSortOrder specifies the order in which scans visit entities.
+func WithSortOrder(order SortOrder) newDBOption {
+	return func(db *BurrowDB) error {
+		db.sortOrder = order
+		return nil
+	}
+}
+
+// sortKeys sorts the passed keys into the passed order using compare. The
+// modification times of the keys' files are only needed for Insertion.
+func sortKeys(keys []string, order SortOrder, modTimes map[string]time.Time, compare func(a, b string) int) {
+	switch order {
+	case Descending:
+		slices.SortFunc(keys, func(a, b string) int {
+			return compare(b, a)
+		})
+	case Insertion:
+		slices.SortFunc(keys, func(a, b string) int {
+			c := modTimes[a].Compare(modTimes[b])
+			if c != 0 {
+				return c
+			}
+			return compare(a, b)
+		})
+	default:
+		slices.SortFunc(keys, compare)
+	}
+}
+
+// compareKeys compares keys numerically if they are both integers, otherwise
+// lexically. Integer keys are ordered before all others.
+func compareKeys(a, b string) int {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,363 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+const partitionDateFormat = "2006-01-02" // Format of the names of partition dirs.
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// WithTimePartition specifies that entities of the named type should be stored
+// in sub-directories of the type's directory named by the UTC date of their
+// time.Time field with the passed name, as dir/typeName/2024-01-15/key. This
+// suits time-series types, which can then be read by date range with
+// GetByTimeRange or GetByDateRange and pruned a day at a time with
+// DropPartitions.
+//
+// Partitioned types ignore the layout set by WithLayout and can't be stored in
+// an append log or written with PutBatch. Getting an entity by ID looks for it
+// in each partition, so costs one stat per partition.
+func WithTimePartition(typeName, field string) newDBOption {
+	return func(db *BurrowDB) error {
+		if field == "" {
+			return fmt.Errorf("time partition field for %s must be set", typeName)
+		}
+
+		if db.partitions == nil {
+			db.partitions = map[string]string{}
+		}
+		db.partitions[typeName] = field
+
+		return nil
+	}
+}
+
+// isPartitioned reports whether the named type is partitioned by date.
+func (db *BurrowDB) isPartitioned(typeName string) bool {
+	_, ok := db.partitions[typeName]
+	return ok
+}
+
+// isPartitionDir reports whether the passed path is the type dir of a
+// partitioned type.
+func (db *BurrowDB) isPartitionDir(path string) bool {
+	for typeName := range db.partitions {
+		if filepath.Clean(path) == filepath.Clean(db.typeDir(typeName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// partitionOf returns the name of the partition dir the passed struct value of
+// a partitioned type is stored in.
+func (db *BurrowDB) partitionOf(v reflect.Value) (string, error) {
+	name := db.partitions[db.typeName(v.Type())]
+	field, ok := v.Type().FieldByName(name)
+	if !ok || field.Type != timeType {
+		return "", fmt.Errorf("time partition field %s of %s must be a time.Time", name, v.Type())
+	}
+
+	t := v.FieldByIndex(field.Index).Interface().(time.Time)
+	return t.UTC().Format(partitionDateFormat), nil
+}
+
+// partitionedPath returns the path of the file storing the entity of the named
+// partitioned type with the passed key. The partition of an entity being put is
+// taken from the hint set by placeEntity, otherwise each partition is searched.
+// If the entity doesn't exist, a path directly in the type dir is returned.
+func (db *BurrowDB) partitionedPath(typeName, key string) string {
+	dir := db.typeDir(typeName)
+	if partition, ok := db.locks.partitionHint(dir, key); ok {
+		return fmt.Sprintf("%s/%s/%s%s", dir, partition, key, db.fileExt)
+	}
+
+	partitions, _ := db.partitionDirs(typeName)
+	for _, partition := range partitions {
+		filename := fmt.Sprintf("%s/%s/%s%s", dir, partition, key, db.fileExt)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+
+	return fmt.Sprintf("%s/%s%s", dir, key, db.fileExt)
+}
+
+// partitionDirs returns the names of the partition dirs of the named type in
+// ascending order of date.
+func (db *BurrowDB) partitionDirs(typeName string) ([]string, error) {
+	entries, err := os.ReadDir(db.typeDir(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read type dir: %w", err)
+	}
+
+	var partitions []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if _, err := time.Parse(partitionDateFormat, entry.Name()); err == nil {
+			partitions = append(partitions, entry.Name())
+		}
+	}
+	slices.Sort(partitions)
+
+	return partitions, nil
+}
+
+// placeEntity directs the write of the passed struct value with the passed key
+// to the partition of its date, if its type is partitioned, returning a
+// function to call once the write is done. If the write succeeded, the function
+// removes the entity's file from any partition it was previously stored in. The
+// lock of the type must be held for writing.
+func (db *BurrowDB) placeEntity(v reflect.Value, key string) (func(written bool) error, error) {
+	typeName := db.typeName(v.Type())
+	if !db.isPartitioned(typeName) {
+		return func(bool) error { return nil }, nil
+	}
+
+	partition, err := db.partitionOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	old := db.partitionedPath(typeName, key)
+	dir := db.typeDir(typeName)
+	db.locks.setPartitionHint(dir, key, partition)
+
+	return func(written bool) error {
+		db.locks.clearPartitionHint(dir, key)
+		if !written || old == fmt.Sprintf("%s/%s/%s%s", dir, partition, key, db.fileExt) {
+			return nil
+		}
+		return db.removeStale(old)
+	}, nil
+}
+
+// removeStale removes the file of an entity which has been moved to another
+// partition, releasing any content addressed data it refers to.
+func (db *BurrowDB) removeStale(filename string) error {
+	var ref []byte
+	if db.contentAddressing {
+		var err error
+		ref, err = os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read moved entity: %w", err)
+		}
+	}
+
+	err := os.Remove(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to remove moved entity: %w", err)
+	}
+
+	// The size of the removed file wasn't accounted for by the write.
+	db.forgetStoreSize()
+
+	return db.releaseBlob(ref)
+}
+
+// partitionKeys returns the keys of the entities in the named partitions of the
+// named type in the db's sort order.
+func (db *BurrowDB) partitionKeys(typeName string, partitions []string) ([]string, error) {
+	var keys []string
+	modTimes := map[string]time.Time{}
+	for _, partition := range partitions {
+		entries, err := os.ReadDir(fmt.Sprintf("%s/%s", db.typeDir(typeName), partition))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read partition %s: %w", partition, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			key, ok := strings.CutSuffix(entry.Name(), db.fileExt)
+			if !ok {
+				continue
+			}
+
+			if db.sortOrder == Insertion {
+				info, err := entry.Info()
+				if err != nil {
+					return nil, fmt.Errorf("unable to stat entity: %w", err)
+				}
+				modTimes[key] = info.ModTime()
+			}
+
+			keys = append(keys, key)
+		}
+	}
+	sortKeys(keys, db.sortOrder, modTimes, db.compareKeys(typeName))
+
+	return keys, nil
+}
+
+// partitionsBetween returns the names of the partitions of the named type from
+// the date of from to the date of to, inclusive.
+func (db *BurrowDB) partitionsBetween(typeName string, from, to time.Time) ([]string, error) {
+	partitions, err := db.partitionDirs(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	first := from.UTC().Format(partitionDateFormat)
+	last := to.UTC().Format(partitionDateFormat)
+	return slices.DeleteFunc(partitions, func(partition string) bool {
+		return partition < first || partition > last
+	}), nil
+}
+
+// GetByTimeRange sets the slice pointed to by dst to every entity of its
+// element type whose partition field is at or after from and before to, in the
+// db's sort order. Only the partitions of the dates in the range are read. The
+// type must be partitioned with WithTimePartition.
+func (db *BurrowDB) GetByTimeRange(dst any, from, to time.Time) (err error) {
+	defer db.handleError("GetByTimeRange", &err)
+
+	return db.getPartitioned(dst, from, to, true)
+}
+
+// GetByDateRange sets the slice pointed to by dst to every entity of its
+// element type in the partitions of the UTC dates from that of from to that of
+// to, inclusive, in the db's sort order. Unlike GetByTimeRange, the time of day
+// is ignored so whole days are got, and partitions outside the range aren't
+// read. The type must be partitioned with WithTimePartition.
+func (db *BurrowDB) GetByDateRange(dst any, from, to time.Time) (err error) {
+	defer db.handleError("GetByDateRange", &err)
+
+	return db.getPartitioned(dst, from, to, false)
+}
+
+// getPartitioned sets the slice pointed to by dst to every entity in the
+// partitions of the dates from that of from to that of to, as described by
+// GetByDateRange. If exact, only the entities whose partition field is at or
+// after from and before to are kept, as described by GetByTimeRange.
+func (db *BurrowDB) getPartitioned(dst any, from, to time.Time, exact bool) error {
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	typeName := db.typeName(elemType)
+	if !db.isPartitioned(typeName) {
+		return fmt.Errorf("%s isn't partitioned by time", typeName)
+	}
+
+	field, ok := elemType.FieldByName(db.partitions[typeName])
+	if !ok || field.Type != timeType {
+		return fmt.Errorf("time partition field %s of %s must be a time.Time", db.partitions[typeName], elemType)
+	}
+
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	partitions, err := db.partitionsBetween(typeName, from, to)
+	if err != nil {
+		return err
+	}
+
+	keys, err := db.partitionKeys(typeName, partitions)
+	if err != nil {
+		return err
+	}
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		t := v.Elem().FieldByIndex(field.Index).Interface().(time.Time)
+		if exact && (t.Before(from) || !t.Before(to)) {
+			continue
+		}
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return nil
+}
+
+// DropPartitions deletes every entity with the type of dst stored in a
+// partition of a date before that of before, removing the partition dirs. Each
+// entity is deleted as Delete would, so indexes are kept up to date. The dst
+// may be a struct or a pointer to one and is only used for its type, which must
+// be partitioned with WithTimePartition.
+func (db *BurrowDB) DropPartitions(dst any, before time.Time) (err error) {
+	defer db.handleError("DropPartitions", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	if !db.isPartitioned(typeName) {
+		return fmt.Errorf("%s isn't partitioned by time", typeName)
+	}
+
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	partitions, err := db.partitionDirs(typeName)
+	if err != nil {
+		return err
+	}
+
+	last := before.UTC().Format(partitionDateFormat)
+	for _, partition := range partitions {
+		if partition >= last {
+			break
+		}
+
+		keys, err := db.partitionKeys(typeName, []string{partition})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			err = db.delete(_type, key)
+			if err != nil {
+				return fmt.Errorf("unable to delete %q: %w", key, err)
+			}
+		}
+
+		err = os.Remove(fmt.Sprintf("%s/%s", db.typeDir(typeName), partition))
+		if err != nil {
+			return fmt.Errorf("unable to remove partition %s: %w", partition, err)
+		}
+	}
+
+	return nil
+}
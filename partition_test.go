@@ -0,0 +1,139 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type partitionItem struct {
+	ID   int
+	At   time.Time
+	Kind string `burrowdb:"index"`
+}
+
+func TestTimePartition(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithTimePartition("partitionItem", "At"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		err = db.Put(partitionItem{ID: i, At: day.Add(time.Duration(i) * 12 * time.Hour), Kind: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "partitionItem", "2024-01-16", "2"))
+	if err != nil {
+		t.Fatalf("entity isn't stored in its partition: %v", err)
+	}
+
+	// Moving an entity to another day moves it between partitions.
+	err = db.Put(partitionItem{ID: 0, At: day.AddDate(0, 0, 1), Kind: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got partitionItem
+	err = db.GetByID(&got, 0)
+	if err != nil || !got.At.Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	var all []partitionItem
+	err = db.GetAll(&all)
+	if err != nil || len(all) != 6 {
+		t.Fatalf("got %d entities, %v, want 6", len(all), err)
+	}
+
+	typeNames, err := db.typeNames()
+	if err != nil || !slices.Equal(typeNames, []string{"partitionItem"}) {
+		t.Fatalf("got type names %v, %v", typeNames, err)
+	}
+
+	var items []partitionItem
+	err = db.GetByTimeRange(&items, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if want := []int{0, 2, 3}; !slices.Equal(ids, want) {
+		t.Fatalf("got IDs %v, want %v", ids, want)
+	}
+
+	err = db.DropPartitions(partitionItem{}, day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByID(&got, 1)
+	if err == nil {
+		t.Fatal("got an entity from a dropped partition")
+	}
+
+	err = db.GetByField(&items, "Kind", "a")
+	if err != nil || len(items) != 3 {
+		t.Fatalf("got %d indexed entities, %v, want 3", len(items), err)
+	}
+}
+
+func TestGetByDateRange(t *testing.T) {
+	var read []string
+	db, err := NewDB(
+		WithDir(t.TempDir()),
+		WithTimePartition("partitionItem", "At"),
+		WithRawReadHook(func(typeName, key string, data []byte) {
+			read = append(read, key)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two entities a day from the 10th to the 14th.
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		err = db.Put(partitionItem{ID: i + 1, At: day.Add(time.Duration(i) * 12 * time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The times within the first and last days are ignored.
+	var items []partitionItem
+	err = db.GetByDateRange(&items, day.AddDate(0, 0, 1).Add(18*time.Hour), day.AddDate(0, 0, 2).Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if want := []int{3, 4, 5, 6}; !slices.Equal(ids, want) {
+		t.Fatalf("got IDs %v, want %v", ids, want)
+	}
+
+	slices.Sort(read)
+	if want := []string{"3", "4", "5", "6"}; !slices.Equal(read, want) {
+		t.Fatalf("read entities %v, want only those in the range's partitions %v", read, want)
+	}
+
+	err = db.GetByDateRange(&items, day.AddDate(0, 0, 20), day.AddDate(0, 0, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("got %d entities after the last partition, want 0", len(items))
+	}
+}
@@ -0,0 +1,96 @@
+package burrowdb
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WithFileMode specifies the permissions files in the db dir are created with,
+// which are 0666 by default. As with os.WriteFile, the process umask is applied
+// when a file is created, but not by FixPermissions.
+func WithFileMode(mode os.FileMode) newDBOption {
+	return func(db *BurrowDB) error {
+		if mode&^os.ModePerm != 0 || mode == 0 {
+			return fmt.Errorf("invalid file mode %v", mode)
+		}
+
+		db.fileMode = mode
+		return nil
+	}
+}
+
+// WithDirMode specifies the permissions directories in the db dir are created
+// with, which are 0777 by default. As with os.MkdirAll, the process umask is
+// applied when a directory is created, but not by FixPermissions.
+func WithDirMode(mode os.FileMode) newDBOption {
+	return func(db *BurrowDB) error {
+		if mode&^os.ModePerm != 0 || mode == 0 {
+			return fmt.Errorf("invalid dir mode %v", mode)
+		}
+
+		db.dirMode = mode
+		return nil
+	}
+}
+
+// filePerm returns the permissions files are created with.
+func (db *BurrowDB) filePerm() os.FileMode {
+	return cmp.Or(db.fileMode, 0666)
+}
+
+// dirPerm returns the permissions directories are created with.
+func (db *BurrowDB) dirPerm() os.FileMode {
+	return cmp.Or(db.dirMode, 0777)
+}
+
+// FixPermissions walks the db dir, including the db dir itself, and resets the
+// permissions of every file to those given by WithFileMode and of every
+// directory to those given by WithDirMode, such as after a Restore or CopyTo
+// made with a different umask. Only the kinds with a mode given are changed, so
+// at least one of the options must have been used. Symlinks aren't followed.
+func (db *BurrowDB) FixPermissions() (err error) {
+	defer db.handleError("FixPermissions", &err)
+
+	if db.fileMode == 0 && db.dirMode == 0 {
+		return errors.New("no file or dir mode has been given")
+	}
+
+	unlock := db.locks.lockAll()
+	defer unlock()
+
+	return filepath.WalkDir(db.dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var mode os.FileMode
+		switch {
+		case entry.IsDir():
+			mode = db.dirMode
+		case entry.Type().IsRegular():
+			mode = db.fileMode
+		}
+		if mode == 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to stat %s: %w", filename, err)
+		}
+
+		if info.Mode().Perm() == mode {
+			return nil
+		}
+
+		err = os.Chmod(filename, mode)
+		if err != nil {
+			return fmt.Errorf("unable to fix permissions of %s: %w", filename, err)
+		}
+		return nil
+	})
+}
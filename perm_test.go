@@ -0,0 +1,60 @@
+//go:build unix
+
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type permItem struct {
+	ID int
+}
+
+func TestFixPermissions(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithFileMode(0640), WithDirMode(0750))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(permItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "permItem", "1")
+	err = os.Chmod(filename, 0600)
+	if err == nil {
+		err = os.Chmod(filepath.Dir(filename), 0700)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.FixPermissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{filename: 0640, filepath.Dir(filename): 0750} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s: got mode %v, want %v", path, info.Mode().Perm(), want)
+		}
+	}
+
+	unset, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unset.FixPermissions()
+	if err == nil {
+		t.Fatal("got no error without a mode given")
+	}
+}
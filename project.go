@@ -0,0 +1,196 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GetFields gets the entity with the type of the passed destination with the
+// passed ID, populating only the named fields of dst and leaving the rest at
+// their zero values. For entities stored as JSON, only the named fields are
+// decoded, which is cheaper than GetByID for wide structs.
+func (db *BurrowDB) GetFields(dst any, id any, fields ...string) (err error) {
+	defer db.handleError("GetFields", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	elemType := _type.Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	selected := make([]reflect.StructField, 0, len(fields))
+	for _, name := range fields {
+		field, ok := elemType.FieldByName(name)
+		if !ok {
+			return fmt.Errorf("type %s has no field %s", elemType, name)
+		}
+		selected = append(selected, field)
+	}
+
+	key, err := db.idKey(id)
+	if err != nil {
+		return err
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return err
+	}
+
+	if codec.Name() == JSONCodec.Name() {
+		data, err = db.filterJSON(data, selected)
+		if err != nil {
+			return err
+		}
+	}
+
+	full := reflect.New(elemType)
+	err = db.decode(codec, typeName, key, data, full.Interface())
+	if err != nil {
+		return err
+	}
+
+	// Copy the selected fields so that defaults given to other fields are dropped.
+	projected := reflect.New(elemType).Elem()
+	for _, field := range selected {
+		projected.FieldByIndex(field.Index).Set(full.Elem().FieldByIndex(field.Index))
+	}
+	reflect.ValueOf(dst).Elem().Set(projected)
+
+	return nil
+}
+
+// filterJSON returns the encoded JSON object with every member removed other
+// than those of the passed fields.
+func (db *BurrowDB) filterJSON(data []byte, fields []reflect.StructField) ([]byte, error) {
+	obj := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal data: %w", err)
+	}
+
+	filtered := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		name, ok := storedFieldName(field)
+		if !ok {
+			continue
+		}
+
+		if raw, ok := obj[name]; ok {
+			filtered[name] = raw
+		}
+	}
+
+	return db.marshalObject(filtered)
+}
+
+// jsonFieldName returns the name of the member the passed field is encoded as
+// in JSON. False is returned if the field is omitted from JSON.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
+
+// Select sets the slice pointed to by dst to the entities of its element type,
+// in the db's sort order, for which pred reports true, populating only the
+// named fields of each and leaving the rest at their zero values. Every field
+// is kept if none are named.
+//
+// Each entity is appended to the slice pointed to by dst before pred is called,
+// so pred inspects it as the slice's last element. It is removed again if pred
+// reports false. The type is read locked throughout, so pred must not write
+// entities of the type.
+func (db *BurrowDB) Select(dst any, pred func() bool, fields ...string) (err error) {
+	defer db.handleError("Select", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidValueType
+	}
+
+	selected := make([]reflect.StructField, 0, len(fields))
+	for _, name := range fields {
+		field, ok := elemType.FieldByName(name)
+		if !ok {
+			return fmt.Errorf("type %s has no field %s", elemType, name)
+		}
+		selected = append(selected, field)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+
+	codec, err := db.storedCodec(typeName)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.ValueOf(dst).Elem()
+	slice.Set(reflect.MakeSlice(_type.Elem(), 0, 0))
+	for _, key := range keys {
+		v, err := db.load(codec, elemType, key)
+		if err != nil {
+			return err
+		}
+
+		n := slice.Len()
+		slice.Set(reflect.Append(slice, v.Elem()))
+		if !pred() {
+			slice.Set(slice.Slice(0, n))
+			continue
+		}
+
+		if len(selected) == 0 {
+			continue
+		}
+
+		projected := reflect.New(elemType).Elem()
+		for _, field := range selected {
+			projected.FieldByIndex(field.Index).Set(v.Elem().FieldByIndex(field.Index))
+		}
+		slice.Index(n).Set(projected)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGetFields(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithEncryption(bytes.Repeat([]byte("k"), 16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(secretItem{ID: 1, Name: "n", Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item secretItem
+	err = db.GetFields(&item, 1, "Token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (secretItem{Token: "tok"}); item != want {
+		t.Fatalf("got %+v, want only the decrypted field %+v", item, want)
+	}
+
+	err = db.GetFields(&item, 1, "Name", "ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (secretItem{ID: 1, Name: "n"}); item != want {
+		t.Fatalf("got %+v, want the other fields zeroed %+v", item, want)
+	}
+
+	err = db.GetFields(&item, 1, "Missing")
+	if err == nil {
+		t.Fatal("got no error for a missing field")
+	}
+
+	err = db.GetFields(&item, 2, "Name")
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+}
+
+type selectItem struct {
+	ID   int
+	Name string
+	Age  int
+	Bio  string
+}
+
+func TestSelect(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(
+		selectItem{ID: 1, Name: "a", Age: 17, Bio: "long"},
+		selectItem{ID: 2, Name: "b", Age: 30, Bio: "long"},
+		selectItem{ID: 3, Name: "c", Age: 45, Bio: "long"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []selectItem
+	err = db.Select(&items, func() bool {
+		return items[len(items)-1].Age >= 18
+	}, "ID", "Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []selectItem{{ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	if !slices.Equal(items, want) {
+		t.Fatalf("got %+v, want %+v", items, want)
+	}
+
+	err = db.Select(&items, func() bool {
+		return items[len(items)-1].ID == 1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Bio != "long" {
+		t.Fatalf("got %+v, want item 1 whole", items)
+	}
+
+	err = db.Select(&items, func() bool { return true }, "Missing")
+	if err == nil {
+		t.Fatal("got nil error for an unknown field")
+	}
+}
@@ -0,0 +1,159 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// storeSize is the total size of the entities in a store, and the number of
+// entities of each type, shared by every db with the same dir.
+type storeSize struct {
+	mu     sync.Mutex
+	known  bool           // whether bytes has been measured.
+	bytes  int64          // total size of the entities in bytes.
+	counts map[string]int // number of entities of each type which has been counted.
+}
+
+// WithMaxStoreSize specifies the maximum total size in bytes of the entities in
+// the store, as reported by KeySizes. A write which would take the total over
+// the limit returns ErrQuotaExceeded and nothing is written, while deletes and
+// writes which don't grow the total are always allowed. Indexes and other
+// metadata aren't counted.
+//
+// The total is measured the first time it is needed and then kept up to date
+// as entities are written and deleted by any db with the same dir, so changes
+// made to the files by other means aren't seen.
+func WithMaxStoreSize(bytes int64) newDBOption {
+	return func(db *BurrowDB) error {
+		if bytes <= 0 {
+			return errors.New("max store size must be positive")
+		}
+
+		db.maxStoreSize = bytes
+		return nil
+	}
+}
+
+// reserveSize accounts for the entity of the named type with the passed key
+// being replaced by one of the passed size, or deleted if size is 0, returning
+// ErrQuotaExceeded if this would take the store over its maximum size. The lock
+// of the type must be held.
+func (db *BurrowDB) reserveSize(typeName, key string, size int64) error {
+	if isReservedType(typeName) || !db.tracksSize() {
+		return nil
+	}
+
+	old, err := db.entitySize(typeName, key)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrNoSuchEntity) {
+		old = 0
+	} else if err != nil {
+		return err
+	}
+
+	return db.reserveBytes(size - old)
+}
+
+// reserveBytes adds delta to the size of the store, returning ErrQuotaExceeded
+// if a positive delta would take it over the maximum size.
+func (db *BurrowDB) reserveBytes(delta int64) error {
+	s := &db.locks.size
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.known {
+		if db.maxStoreSize == 0 {
+			return nil
+		}
+
+		total, err := db.measureStoreSize()
+		if err != nil {
+			return err
+		}
+		s.bytes, s.known = total, true
+	}
+
+	if db.maxStoreSize > 0 && delta > 0 && s.bytes+delta > db.maxStoreSize {
+		return fmt.Errorf("%w: writing %d more bytes would take the store to %d of %d bytes", ErrQuotaExceeded, delta, s.bytes+delta, db.maxStoreSize)
+	}
+
+	s.bytes += delta
+	return nil
+}
+
+// tracksSize reports whether the size of the store is being kept, either
+// because this db has a maximum size or another with the same dir has measured
+// it.
+func (db *BurrowDB) tracksSize() bool {
+	if db.maxStoreSize > 0 {
+		return true
+	}
+
+	s := &db.locks.size
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.known
+}
+
+// forgetStoreSize discards the size of the store, and the number of entities of
+// each type, so that they are measured again when next needed, such as after a
+// write which may have only partly happened.
+func (db *BurrowDB) forgetStoreSize() {
+	s := &db.locks.size
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.known = false
+	s.counts = nil
+}
+
+// measureStoreSize returns the total size of the entities of every type. The
+// locks of the types aren't taken, so the result is an estimate if other types
+// are being written at the same time.
+func (db *BurrowDB) measureStoreSize() (int64, error) {
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, typeName := range typeNames {
+		keys, err := db.keys(typeName)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, key := range keys {
+			size, err := db.entitySize(typeName, key)
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrNoSuchEntity) {
+				continue
+			} else if err != nil {
+				return 0, err
+			}
+			total += size
+		}
+	}
+
+	return total, nil
+}
+
+// reserveBatch accounts for every staged write of a batch replacing its entity,
+// as reserveSize does, checking the maximum size against the batch as a whole.
+func (db *BurrowDB) reserveBatch(writes []*batchWrite) error {
+	if !db.tracksSize() {
+		return nil
+	}
+
+	var delta int64
+	for _, w := range writes {
+		old, err := db.entitySize(w.enc.typeName, w.enc.key)
+		if errors.Is(err, os.ErrNotExist) {
+			old = 0
+		} else if err != nil {
+			return err
+		}
+		delta += int64(len(w.enc.data)) - old
+	}
+
+	return db.reserveBytes(delta)
+}
@@ -0,0 +1,69 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+)
+
+type quotaItem struct {
+	Num  int64 `burrowdb:"ID"`
+	Body string
+}
+
+func TestMaxStoreSize(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithMaxStoreSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each entity is 29 bytes, so three fit.
+	body := "0123456789"
+	for i := range int64(3) {
+		err = db.Put(quotaItem{Num: i, Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.Put(quotaItem{Num: 3, Body: body})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	err = db.GetByID(&quotaItem{}, 3)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want nothing written", err)
+	}
+
+	err = db.PutBatch(quotaItem{Num: 4, Body: body})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v putting a batch, want %v", err, ErrQuotaExceeded)
+	}
+
+	// Writes which don't grow the total are allowed.
+	err = db.Put(quotaItem{Num: 0, Body: "9876543210"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting makes room, which other dbs on the dir also see.
+	err = db.Delete(quotaItem{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewDB(WithDir(db.dir), WithMaxStoreSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = other.Put(quotaItem{Num: 5, Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(quotaItem{Num: 6, Body: body})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v after the other db filled the store, want %v", err, ErrQuotaExceeded)
+	}
+}
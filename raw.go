@@ -0,0 +1,77 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// GetRawJSON returns the stored bytes of the entity of the named type with the
+// passed ID without decoding them. ErrNotJSON is returned if the entity was not
+// written with JSONCodec.
+func (db *BurrowDB) GetRawJSON(typeName string, id any) (_ json.RawMessage, err error) {
+	defer db.handleError("GetRawJSON", &err)
+
+	return db.rawJSON(typeName, id)
+}
+
+// GetAsMap returns the entity of the named type with the passed ID decoded into
+// a map keyed by JSON member name, so that it can be inspected without its Go
+// type, such as by admin tools. Numbers are decoded as json.Number rather than
+// float64, so that integers which a float64 can't hold exactly, such as large
+// int64 IDs, keep their value. Encrypted fields are left as ciphertext.
+// ErrNotJSON is returned if the entity was not written with JSONCodec.
+func (db *BurrowDB) GetAsMap(typeName string, id any) (_ map[string]any, err error) {
+	defer db.handleError("GetAsMap", &err)
+
+	data, err := db.rawJSON(typeName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: stored data is not a JSON object: %w", ErrNotJSON, err)
+	}
+
+	return m, nil
+}
+
+// rawJSON returns the stored bytes of the entity of the named type with the
+// passed ID, as described by GetRawJSON.
+func (db *BurrowDB) rawJSON(typeName string, id any) (json.RawMessage, error) {
+	key, err := db.idKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, err := db.storedCodec(typeName)
+	if err == nil {
+		codec, err = db.entityCodec(typeName, key, codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if codec.Name() != JSONCodec.Name() {
+		return nil, fmt.Errorf("%w: %s is stored with the %s codec", ErrNotJSON, typeName, codec.Name())
+	}
+
+	data, err := db.readEntity(typeName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%w: stored data is not valid JSON", ErrNotJSON)
+	}
+
+	return json.RawMessage(data), nil
+}
@@ -0,0 +1,135 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+type rawItem struct {
+	ID   int
+	Name string
+}
+
+func TestGetRawJSON(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(rawItem{ID: 1, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := db.GetRawJSON("rawItem", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ID":1,"Name":"a"}`; string(raw) != want {
+		t.Fatalf("got %s, want %s", raw, want)
+	}
+
+	_, err = db.GetRawJSON("rawItem", 2)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+
+	gob, err := NewDB(WithDir(dir), WithCodec(GobCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gob.Put(codecItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = gob.GetRawJSON("codecItem", 1)
+	if !errors.Is(err, ErrNotJSON) {
+		t.Fatalf("got %v for a gob entity, want %v", err, ErrNotJSON)
+	}
+}
+
+func TestGetAsMap(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(rawItem{ID: 1, Name: "a"})
+	if err == nil {
+		err = db.PutList("rawList", 1, []int{1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := db.GetAsMap("rawItem", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["Name"] != "a" || m["ID"] != json.Number("1") {
+		t.Fatalf("got %v", m)
+	}
+
+	_, err = db.GetAsMap("rawList", 1)
+	if err == nil {
+		t.Fatal("got no error for an entity which isn't an object")
+	}
+
+	_, err = db.GetAsMap("rawItem", 2)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v for a missing entity, want %v", err, ErrNoSuchEntity)
+	}
+
+	gob, err := NewDB(WithDir(t.TempDir()), WithCodec(GobCodec))
+	if err == nil {
+		err = gob.Put(rawItem{ID: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = gob.GetAsMap("rawItem", 1)
+	if !errors.Is(err, ErrNotJSON) {
+		t.Fatalf("got %v for a gob entity, want %v", err, ErrNotJSON)
+	}
+}
+
+type largeNumberItem struct {
+	ID     int64
+	Amount uint64
+}
+
+func TestGetAsMapLargeNumbers(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither value can be held exactly by a float64.
+	want := largeNumberItem{ID: 1<<53 + 1, Amount: math.MaxUint64}
+	err = db.Put(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := db.GetAsMap("largeNumberItem", want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, ok := m["ID"].(json.Number)
+	if !ok || id.String() != strconv.FormatInt(want.ID, 10) {
+		t.Fatalf("got ID %#v, want %d", m["ID"], want.ID)
+	}
+
+	amount, ok := m["Amount"].(json.Number)
+	if !ok || amount.String() != strconv.FormatUint(want.Amount, 10) {
+		t.Fatalf("got amount %#v, want %d", m["Amount"], want.Amount)
+	}
+}
@@ -0,0 +1,80 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RenameType moves every entity of the type named oldName to the type named
+// newName, along with its indexes, ID sequence and codec, by renaming its type
+// dir. This keeps the data of a Go type which has been renamed, so that it can
+// then be read as the new type. An error is returned if there is already data
+// stored under newName, or there is none under oldName. The change log isn't
+// rewritten, so its earlier entries still name oldName.
+func (db *BurrowDB) RenameType(oldName, newName string) (err error) {
+	defer db.handleError("RenameType", &err)
+
+	err = db.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{oldName, newName} {
+		if name == "" || isReservedType(name) || !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+	}
+
+	if db.typeDirName(oldName) == db.typeDirName(newName) {
+		return nil
+	}
+
+	// Take both locks in ascending order of name, as lockAll does.
+	mus := []*sync.RWMutex{db.typeLock(oldName), db.typeLock(newName)}
+	if db.typeDirName(newName) < db.typeDirName(oldName) {
+		mus[0], mus[1] = mus[1], mus[0]
+	}
+	for _, mu := range mus {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+
+	_, err = os.Stat(db.typeDir(oldName))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no type %s is stored: %w", oldName, err)
+	} else if err != nil {
+		return fmt.Errorf("unable to stat type dir: %w", err)
+	}
+
+	_, err = os.Lstat(db.typeDir(newName))
+	if err == nil {
+		return fmt.Errorf("type %s is already stored: %w", newName, os.ErrExist)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to stat type dir: %w", err)
+	}
+
+	// Qualified type names are nested below their package path.
+	err = db.mkdirAll(filepath.Dir(db.typeDir(newName)))
+	if err != nil {
+		return fmt.Errorf("unable to create dir for %s: %w", newName, err)
+	}
+
+	err = os.Rename(db.typeDir(oldName), db.typeDir(newName))
+	if err != nil {
+		return fmt.Errorf("unable to rename type dir: %w", err)
+	}
+
+	db.markWritten(db.typeDir(newName))
+	db.markWritten(filepath.Dir(db.typeDir(oldName)))
+
+	db.locks.forgetAppendLog(oldName)
+	db.locks.forgetAppendLog(newName)
+	db.locks.forgetKeyNames(db.typeDirName(oldName))
+	db.locks.forgetKeyNames(db.typeDirName(newName))
+	db.forgetStoreSize()
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type renameBefore struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+type renameAfter struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestRenameType(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, status := range []string{"open", "closed", "open"} {
+		err = db.Put(renameBefore{ID: i + 1, Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.RenameType("renameBefore", "renameAfter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got renameAfter
+	err = db.GetByID(&got, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (renameAfter{ID: 2, Status: "closed"}) {
+		t.Fatalf("got %+v, want the entity stored before the rename", got)
+	}
+
+	// The index moves with the entities.
+	var open []renameAfter
+	err = db.GetByField(&open, "Status", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 2 {
+		t.Fatalf("got %d open entities, want 2", len(open))
+	}
+
+	var before []renameBefore
+	err = db.GetAll(&before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 0 {
+		t.Fatalf("got %d entities under the old name, want 0", len(before))
+	}
+
+	// Renaming onto a stored type fails and leaves both alone.
+	err = db.Put(renameBefore{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.RenameType("renameBefore", "renameAfter")
+	if !errors.Is(err, os.ErrExist) {
+		t.Fatalf("got %v renaming onto a stored type, want %v", err, os.ErrExist)
+	}
+
+	err = db.RenameType("renameMissing", "renameOther")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v renaming a missing type, want %v", err, os.ErrNotExist)
+	}
+
+	err = db.RenameType("renameBefore", "../escaped")
+	if err == nil {
+		t.Fatal("got no error renaming outside the db dir")
+	}
+}
@@ -0,0 +1,125 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// WithReplicaRefresh opens the db as a read-only replica of a store which is
+// written by another process, such as a directory kept in sync with a primary
+// by replication. Entities are always read from disk, so new and removed files
+// are seen straight away, but every interval the replica polls the dir for
+// append logs which have changed and discards its indexes of them, and the
+// size of the store measured for quotas, so that they are rebuilt from the
+// replicated files when next used. The refresher is stopped by Close.
+//
+// Every write, including whole-store operations such as Reset, Compact and
+// Restore, returns ErrReadOnly. The db dir must already exist, as with
+// WithNoCreate, and the write-ahead log isn't replayed nor the seed run, which
+// is left to the primary.
+func WithReplicaRefresh(interval time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if interval <= 0 {
+			return errors.New("replica refresh interval must be positive")
+		}
+
+		db.replicaRefresh = interval
+		db.noCreate = true
+		return nil
+	}
+}
+
+// isReplica reports whether the db is a read-only replica.
+func (db *BurrowDB) isReplica() bool {
+	return db.replicaRefresh > 0
+}
+
+// checkWritable returns ErrReadOnly if the db is a read-only replica.
+func (db *BurrowDB) checkWritable() error {
+	if db.isReplica() {
+		return fmt.Errorf("%w: %q is a replica", ErrReadOnly, db.dir)
+	}
+	return nil
+}
+
+// startRefresher starts refreshing the replica every refresh interval until
+// stopRefresher is called, if the db is a replica.
+func (db *BurrowDB) startRefresher() {
+	if !db.isReplica() {
+		return
+	}
+
+	logs := db.statAppendLogs()
+	stop, done := make(chan struct{}), make(chan struct{})
+	db.refreshStop, db.refreshDone = stop, done
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(db.replicaRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				logs = db.refresh(logs)
+			}
+		}
+	}()
+}
+
+// stopRefresher stops the refresher, if it is running, and waits for it to
+// finish.
+func (db *BurrowDB) stopRefresher() {
+	if db.refreshStop == nil {
+		return
+	}
+
+	close(db.refreshStop)
+	<-db.refreshDone
+	db.refreshStop, db.refreshDone = nil, nil
+}
+
+// refresh discards the indexes of the append logs which have changed since
+// they were last statted, as recorded in logs, and the size of the store. The
+// current stats of the append logs are returned.
+func (db *BurrowDB) refresh(logs map[string]os.FileInfo) map[string]os.FileInfo {
+	db.forgetStoreSize()
+
+	current := db.statAppendLogs()
+	for _, typeName := range slices.Sorted(maps.Keys(db.appendLogs)) {
+		prev, info := logs[typeName], current[typeName]
+		if prev != nil && info != nil && os.SameFile(prev, info) && info.Size() == prev.Size() && info.ModTime().Equal(prev.ModTime()) {
+			continue
+		}
+
+		// A replicated log may have been replaced by a new file reusing the
+		// inode of the old one, so any change means indexing it again.
+
+		mu := db.typeLock(typeName)
+		mu.Lock()
+		db.locks.forgetAppendLog(db.typeDirName(typeName))
+		mu.Unlock()
+	}
+
+	return current
+}
+
+// statAppendLogs returns the stats of the append log of every type stored in
+// one, keyed by type. Types whose logs can't be statted, such as because
+// nothing of them has been written yet, are left out.
+func (db *BurrowDB) statAppendLogs() map[string]os.FileInfo {
+	logs := map[string]os.FileInfo{}
+	for typeName := range db.appendLogs {
+		info, err := os.Stat(db.appendLogPath(typeName))
+		if err == nil {
+			logs[typeName] = info
+		}
+	}
+	return logs
+}
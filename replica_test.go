@@ -0,0 +1,125 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type replicaItem struct {
+	ID   int
+	Name string
+}
+
+type replicaEvent struct {
+	ID   int
+	Name string
+}
+
+// replicate replaces the contents of the replica dir with those of the primary
+// dir, as a replication tool would.
+func replicate(t *testing.T, primary, replica string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(replica)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(replica, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = os.CopyFS(replica, os.DirFS(primary))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplicaRefresh(t *testing.T) {
+	primaryDir, replicaDir := t.TempDir(), t.TempDir()
+	primary, err := NewDB(WithDir(primaryDir), WithAppendLog("replicaEvent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = errors.Join(primary.Put(replicaItem{ID: 1, Name: "first"}), primary.Put(replicaEvent{ID: 1, Name: "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicate(t, primaryDir, replicaDir)
+
+	replica, err := NewDB(WithDir(replicaDir), WithAppendLog("replicaEvent"), WithReplicaRefresh(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	var event replicaEvent
+	err = replica.GetByID(&event, 1)
+	if err != nil || event.Name != "a" {
+		t.Fatalf("got %+v and %v from the replica, want the replicated event", event, err)
+	}
+
+	// Replace the append log with a longer one, which the replica's index of
+	// the old log can't be brought up to date with by appending.
+	err = primary.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errors.Join(primary.Put(replicaItem{ID: 2, Name: "second"}), primary.Put(replicaEvent{ID: 1, Name: "a longer name"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicate(t, primaryDir, replicaDir)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var items []replicaItem
+		err = replica.GetAll(&items)
+		if err == nil {
+			err = replica.GetByID(&event, 1)
+		}
+		if err == nil && len(items) == 1 && items[0].ID == 2 && event.Name == "a longer name" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %+v, %+v and %v from the replica, want the primary's writes", items, event, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	err = replica.Put(replicaItem{ID: 3})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v putting to the replica, want %v", err, ErrReadOnly)
+	}
+
+	err = replica.Put(replicaEvent{ID: 2})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v appending to the replica, want %v", err, ErrReadOnly)
+	}
+
+	err = replica.Delete(replicaItem{}, 2)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v deleting from the replica, want %v", err, ErrReadOnly)
+	}
+
+	err = replica.Reset()
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v resetting the replica, want %v", err, ErrReadOnly)
+	}
+
+	if cfg := replica.Config(); cfg.ReplicaRefresh != 10*time.Millisecond || !cfg.NoCreate {
+		t.Fatalf("got refresh %v and no create %t in config, want 10ms and true", cfg.ReplicaRefresh, cfg.NoCreate)
+	}
+
+	_, err = NewDB(WithDir(filepath.Join(t.TempDir(), "missing")), WithReplicaRefresh(time.Second))
+	if !errors.Is(err, ErrMissingDir) {
+		t.Fatalf("got %v opening a replica of a missing dir, want %v", err, ErrMissingDir)
+	}
+}
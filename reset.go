@@ -0,0 +1,117 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// Reset removes every entity of every type, along with their indexes, ID
+// sequences and the change log, leaving an empty store which can be used as
+// normal. The db dir itself is kept, as are the process lock and the marker
+// recording that the store has been seeded.
+func (db *BurrowDB) Reset() (err error) {
+	defer db.handleError("Reset", &err)
+
+	err = db.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return err
+	}
+
+	// Make sure every stored type has a lock for lockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	unlock := db.locks.lockAll()
+	defer unlock()
+	defer db.forgetStoreSize()
+	db.dropAllHeld("")
+
+	entries, err := os.ReadDir(db.dir)
+	if err != nil {
+		return fmt.Errorf("unable to read db dir: %w", err)
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		filename := filepath.Join(db.dir, entry.Name())
+		if entry.Name() == lockFileName || entry.Name() == seedFileName || filename == filepath.Clean(db.tempDir) {
+			continue
+		}
+
+		err = os.RemoveAll(filename)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove %s: %w", entry.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Truncate removes every entity with the type of dst, keeping its type dir. Its
+// indexes are emptied rather than removed, and its ID sequence, if Insert has
+// been used, is reset so that the next inserted entity is given ID 1. The dst
+// may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) Truncate(dst any) (err error) {
+	defer db.handleError("Truncate", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+	db.dropAllHeld(typeName)
+
+	var errs []error
+	if db.isAppendLog(typeName) && len(keys) > 0 {
+		// Empty the log rather than appending a deletion for every entity.
+		err = db.writeFileAtomic(db.appendLogPath(typeName), nil)
+		if err != nil {
+			return fmt.Errorf("unable to empty append log: %w", err)
+		}
+		db.forgetStoreSize()
+
+		for _, key := range keys {
+			errs = append(errs, db.logChange(typeName, key, OpDelete))
+		}
+	} else {
+		for _, key := range keys {
+			err = db.deleteEntity(typeName, key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to delete %q: %w", key, err))
+			}
+		}
+	}
+
+	indexes := map[string]index{}
+	for _, spec := range db.indexSpecs(_type) {
+		indexes[spec.name] = index{}
+	}
+	errs = append(errs, db.writeIndexes(_type, indexes))
+
+	_, err = os.Stat(db.seqPath(typeName))
+	if err == nil {
+		errs = append(errs, db.writeSeq(typeName, 0))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("unable to stat sequence: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
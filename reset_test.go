@@ -0,0 +1,134 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+type resetItem struct {
+	ID int
+}
+
+type resetIndexed struct {
+	ID    int
+	Email string `burrowdb:"unique"`
+}
+
+func TestReset(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithChangeLog(), WithProcessLock(), WithSeed(func(*BurrowDB) error {
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = db.Put(resetIndexed{ID: 1, Email: "a"})
+	if err == nil {
+		_, _, err = db.Insert(resetItem{})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	for _, name := range names {
+		if name != lockFileName && name != seedFileName {
+			t.Errorf("got %s left in the db dir %v", name, names)
+		}
+	}
+	if !slices.Contains(names, seedFileName) {
+		t.Errorf("got the seed marker removed")
+	}
+
+	err = db.GetByID(&resetIndexed{}, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want %v", err, ErrNoSuchEntity)
+	}
+
+	// The unique index and ID sequence are gone along with the entities.
+	err = db.Put(resetIndexed{ID: 2, Email: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, id, err := db.Insert(resetItem{})
+	if err != nil || id != 1 {
+		t.Fatalf("got ID %d, %v, want the sequence started again", id, err)
+	}
+}
+
+type resetEnum struct {
+	ID     int
+	Status string `burrowdb:"index,enum"`
+}
+
+func TestTruncate(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = db.Insert(resetIndexed{Email: "x"})
+	if err == nil {
+		_, _, err = db.Insert(resetIndexed{Email: "y"})
+	}
+	if err == nil {
+		err = db.PutAll(resetEnum{ID: 1, Status: "open"}, resetItem{ID: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Truncate(resetIndexed{})
+	if err == nil {
+		err = db.Truncate(&resetEnum{})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indexed []resetIndexed
+	err = db.GetAll(&indexed)
+	if err != nil || len(indexed) != 0 {
+		t.Fatalf("got %+v, %v, want no entities", indexed, err)
+	}
+
+	var enums []resetEnum
+	err = db.GetByField(&enums, "Status", "open")
+	if err != nil || len(enums) != 0 {
+		t.Fatalf("got %+v, %v, want the index emptied", enums, err)
+	}
+
+	// Other types are kept.
+	err = db.GetByID(&resetItem{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The unique index and ID sequence start again.
+	_, id, err := db.Insert(resetIndexed{Email: "x"})
+	if err != nil || id != 1 {
+		t.Fatalf("got ID %d, %v, want 1", id, err)
+	}
+
+	_, err = os.Stat(db.typeDir("resetIndexed"))
+	if err != nil {
+		t.Fatalf("got %v, want the type dir kept", err)
+	}
+}
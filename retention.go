@@ -0,0 +1,118 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// retention limits how many entities of a type are kept.
+type retention struct {
+	maxCount int           // number of entities with the highest IDs kept, or 0 for any number.
+	maxAge   time.Duration // age after which entities are removed, or 0 to keep them.
+}
+
+// WithRetention specifies that after each put of an entity of the named type,
+// only the maxCount entities with the highest IDs are kept, comparing integer
+// IDs numerically, and entities last written more than maxAge ago are deleted.
+// A limit of 0 disables it. This suits log-like types where only recent
+// records are useful.
+//
+// Entities are only pruned when one of their type is put, so expired entities
+// remain until the next put unless WithSweepInterval is used.
+func WithRetention(typeName string, maxCount int, maxAge time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if maxCount < 0 || maxAge < 0 {
+			return fmt.Errorf("retention limits for %s must not be negative", typeName)
+		}
+
+		if db.retention == nil {
+			db.retention = map[string]retention{}
+		}
+		db.retention[typeName] = retention{maxCount: maxCount, maxAge: maxAge}
+
+		return nil
+	}
+}
+
+// applyRetention deletes the entities of the passed type exceeding its
+// retention limits, if it has any. The type's lock must be held.
+func (db *BurrowDB) applyRetention(_type reflect.Type) error {
+	typeName := db.typeName(_type)
+	expired, err := db.expiredKeys(typeName)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, key := range expired {
+		err = db.delete(_type, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to delete expired %s %q: %w", typeName, key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// expiredKeys returns the keys of the entities of the named type exceeding its
+// retention limits, from the lowest ID up for those beyond its maximum count.
+// The type's lock must be held.
+func (db *BurrowDB) expiredKeys(typeName string) ([]string, error) {
+	r, ok := db.retention[typeName]
+	if !ok {
+		return nil, nil
+	}
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return nil, err
+	}
+	sortKeys(keys, Descending, nil, db.compareKeys(typeName))
+
+	var expired []string
+	if r.maxCount > 0 && len(keys) > r.maxCount {
+		expired = slices.Clone(keys[r.maxCount:])
+		slices.Reverse(expired)
+		keys = keys[:r.maxCount]
+	}
+
+	if r.maxAge > 0 {
+		cutoff := time.Now().Add(-r.maxAge)
+		for _, key := range slices.Backward(keys) {
+			modTime, err := db.entityModTime(typeName, key)
+			if err != nil {
+				return nil, err
+			}
+
+			if modTime.Before(cutoff) {
+				expired = append(expired, key)
+			}
+		}
+	}
+
+	return expired, nil
+}
+
+// entityModTime returns when the entity of the named type with the passed key
+// was last written.
+func (db *BurrowDB) entityModTime(typeName, key string) (time.Time, error) {
+	if db.isAppendLog(typeName) {
+		rec, ok, err := db.logRecord(typeName, key)
+		if err != nil {
+			return time.Time{}, err
+		} else if !ok {
+			return time.Time{}, ErrNoSuchEntity
+		}
+		return rec.time, nil
+	}
+
+	info, err := os.Stat(db.entityPath(typeName, key))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to stat entity: %w", err)
+	}
+	return info.ModTime(), nil
+}
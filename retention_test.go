@@ -0,0 +1,83 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type retentionItem struct {
+	Num int64 `burrowdb:"ID"`
+}
+
+func retentionNums(t *testing.T, db *BurrowDB) []int64 {
+	t.Helper()
+
+	var items []retentionItem
+	err := db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nums := make([]int64, len(items))
+	for i, item := range items {
+		nums[i] = item.Num
+	}
+	return nums
+}
+
+func TestRetentionCount(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithRetention("retentionItem", 3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range int64(12) {
+		err = db.Put(retentionItem{Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := retentionNums(t, db); !slices.Equal(got, []int64{9, 10, 11}) {
+		t.Fatalf("got %v, want the 3 highest IDs kept", got)
+	}
+}
+
+func TestRetentionAge(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithRetention("retentionItem", 0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(retentionItem{Num: 1}, retentionItem{Num: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(filepath.Join(dir, "retentionItem", "1"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired entities are pruned by the next put of their type.
+	err = db.Put(retentionItem{Num: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := retentionNums(t, db); !slices.Equal(got, []int64{2, 3}) {
+		t.Fatalf("got %v, want the expired entity pruned", got)
+	}
+}
+
+func TestRetentionNegative(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithRetention("retentionItem", -1, 0))
+	if err == nil {
+		t.Fatal("got no error for a negative count")
+	}
+}
@@ -0,0 +1,301 @@
+package burrowdb
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// WithParallelism specifies how many entities scans such as GetAll may read and
+// decode concurrently. By default entities are read one at a time.
+func WithParallelism(n int) newDBOption {
+	return func(db *BurrowDB) error {
+		if n < 1 {
+			return errors.New("parallelism must be at least 1")
+		}
+
+		db.parallelism = n
+		return nil
+	}
+}
+
+// EachAll calls fn with the stored bytes of every entity of every type, visiting
+// types in ascending order of name and entities in the db's sort order. Each
+// entity is read under the read lock of its type, which is released before fn
+// is called so that fn may write to the db. Iteration stops at the first error
+// returned by fn, which is returned.
+func (db *BurrowDB) EachAll(fn func(typeName string, raw []byte) error) (err error) {
+	defer db.handleError("EachAll", &err)
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return err
+	}
+
+	for _, typeName := range typeNames {
+		mu := db.typeLock(typeName)
+		mu.RLock()
+		keys, err := db.keys(typeName)
+		mu.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			mu.RLock()
+			data, err := db.readEntity(typeName, key)
+			mu.RUnlock()
+			if errors.Is(err, ErrNoSuchEntity) {
+				// The entity was deleted since the keys were read.
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			err = fn(typeName, data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EachRaw calls fn with the key and stored bytes of every entity of the named
+// type in the db's sort order, without decoding them. For a type stored with
+// JSONCodec, the bytes are the entity's JSON, so they can be forwarded as is.
+// Each entity is read under the read lock of the type, which is released before
+// fn is called so that fn may write to the db. Iteration stops at the first
+// error returned by fn, which is returned.
+func (db *BurrowDB) EachRaw(typeName string, fn func(key string, data []byte) error) (err error) {
+	defer db.handleError("EachRaw", &err)
+
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	keys, err := db.keys(typeName)
+	mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		mu.RLock()
+		data, err := db.readEntity(typeName, key)
+		mu.RUnlock()
+		if errors.Is(err, ErrNoSuchEntity) {
+			// The entity was deleted since the keys were read.
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = fn(key, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scan sets the slice pointed to by dst to up to limit entities of its element
+// type, visited in the db's sort order starting after the entity identified by
+// cursor, or from the first entity if cursor is empty. The returned cursor
+// resumes the scan after the last entity set and is empty once every entity has
+// been visited, so a client can page through a type across requests.
+//
+// If the entity a cursor identifies has since been deleted, the scan resumes
+// from the next entity in Ascending or Descending order, but ErrInvalidCursor is
+// returned in Insertion order.
+func (db *BurrowDB) Scan(dst any, cursor string, limit int) (_ string, err error) {
+	defer db.handleError("Scan", &err)
+
+	_type := reflect.TypeOf(dst)
+	if _type.Kind() != reflect.Pointer {
+		return "", ErrNonPointerDst
+	}
+
+	if _type.Elem().Kind() != reflect.Slice {
+		return "", ErrInvalidDstType
+	}
+
+	elemType := _type.Elem().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return "", ErrInvalidValueType
+	}
+
+	if limit < 1 {
+		return "", fmt.Errorf("invalid limit %d", limit)
+	}
+
+	typeName := db.typeName(elemType)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return "", err
+	}
+
+	if cursor != "" {
+		start, err := db.cursorStart(typeName, keys, cursor)
+		if err != nil {
+			return "", err
+		}
+		keys = keys[start:]
+	}
+
+	next := ""
+	if len(keys) > limit {
+		keys = keys[:limit]
+		next = base64.RawURLEncoding.EncodeToString([]byte(keys[limit-1]))
+	}
+
+	values, err := db.loadAll(elemType, keys)
+	if err != nil {
+		return "", err
+	}
+
+	slice := reflect.MakeSlice(_type.Elem(), 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v.Elem())
+	}
+	reflect.ValueOf(dst).Elem().Set(slice)
+
+	return next, nil
+}
+
+// cursorStart returns the index in the passed sorted keys of the first key after
+// the one identified by the passed Scan cursor.
+func (db *BurrowDB) cursorStart(typeName string, keys []string, cursor string) (int, error) {
+	last, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(last) == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+
+	if i := slices.Index(keys, string(last)); i >= 0 {
+		return i + 1, nil
+	}
+
+	var after func(key string) bool
+	switch db.sortOrder {
+	case Ascending:
+		after = func(key string) bool { return db.compareKeys(typeName)(key, string(last)) > 0 }
+	case Descending:
+		after = func(key string) bool { return db.compareKeys(typeName)(key, string(last)) < 0 }
+	default:
+		return 0, fmt.Errorf("%w: %q no longer exists", ErrInvalidCursor, last)
+	}
+
+	i := slices.IndexFunc(keys, after)
+	if i < 0 {
+		return len(keys), nil
+	}
+	return i, nil
+}
+
+// loadAll reads and decodes the entities of the named type with the passed
+// keys, returning a pointer to each in the same order as keys. Up to the db's
+// parallelism entities are loaded concurrently and the first error stops any
+// remaining work from being started.
+func (db *BurrowDB) loadAll(elemType reflect.Type, keys []string) ([]reflect.Value, error) {
+	codec, err := db.storedCodec(db.typeName(elemType))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]reflect.Value, len(keys))
+	if db.parallelism <= 1 {
+		for i, key := range keys {
+			v, err := db.load(codec, elemType, key)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, db.parallelism)
+	)
+
+loop:
+	for i, key := range keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			v, err := db.load(codec, elemType, key)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			values[i] = v
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return values, nil
+}
+
+// loadOne reads and decodes the entity of the passed type with the passed key,
+// returning a pointer to it.
+func (db *BurrowDB) loadOne(elemType reflect.Type, key string) (reflect.Value, error) {
+	codec, err := db.storedCodec(db.typeName(elemType))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return db.load(codec, elemType, key)
+}
+
+// load reads the entity of the passed type with the passed key and decodes it
+// with the passed codec, returning a pointer to it.
+func (db *BurrowDB) load(codec Codec, elemType reflect.Type, key string) (reflect.Value, error) {
+	typeName := db.typeName(elemType)
+	codec, err := db.entityCodec(typeName, key, codec)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	v := reflect.New(elemType)
+	err = db.withEntity(typeName, key, func(data []byte) error {
+		return db.decode(codec, typeName, key, data, v.Interface())
+	})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return v, nil
+}
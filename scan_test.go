@@ -0,0 +1,204 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+type scanItem struct {
+	Num int64 `burrowdb:"ID"`
+}
+
+func TestParallelism(t *testing.T) {
+	dir := t.TempDir()
+	var reads atomic.Int64
+	db, err := NewDB(WithDir(dir), WithParallelism(4), WithRawReadHook(func(string, string, []byte) {
+		reads.Add(1)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range int64(200) {
+		err = db.Put(scanItem{Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var items []scanItem
+	err = db.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 200 {
+		t.Fatalf("got %d items, want 200", len(items))
+	}
+	for i, item := range items {
+		if item.Num != int64(i) {
+			t.Fatalf("got %d at %d, want the entities in order", item.Num, i)
+		}
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "scanItem", "5"), []byte("{bad"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The corrupt entity stops the entities after it from being read, other
+	// than those already being read.
+	reads.Store(0)
+	err = db.GetAll(&items)
+	if err == nil {
+		t.Fatal("got no error scanning a corrupt entity")
+	}
+	if n := reads.Load(); n > 20 {
+		t.Fatalf("got %d entities read, want the scan stopped soon after the 6th", n)
+	}
+}
+
+func TestParallelismInvalid(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithParallelism(0))
+	if err == nil {
+		t.Fatal("got no error for a parallelism of 0")
+	}
+}
+
+type scanOther struct {
+	ID string
+}
+
+func TestEachAll(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(scanOther{ID: "a"}, scanItem{Num: 2}, scanItem{Num: 1})
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing from fn doesn't deadlock, as the lock is released first.
+	var visited []string
+	err = db.EachAll(func(typeName string, data []byte) error {
+		visited = append(visited, fmt.Sprintf("%s %s", typeName, data))
+		return db.Put(scanOther{ID: "b" + typeName})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`scanItem {"Num":1}`,
+		`scanItem {"Num":2}`,
+		`scanOther {"ID":"a"}`,
+	}
+	if !slices.Equal(visited[:3], want) {
+		t.Fatalf("got %q, want %q first", visited, want)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = db.EachAll(func(string, []byte) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+}
+
+func TestScanCursor(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range int64(10) {
+		err = db.Put(scanItem{Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []int64
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("scan didn't finish")
+		}
+
+		var items []scanItem
+		cursor, err = db.Scan(&items, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, item := range items {
+			ids = append(ids, item.Num)
+		}
+
+		if cursor == "" {
+			break
+		}
+
+		// Deleting the last entity seen shouldn't stop the scan resuming.
+		err = db.Delete(scanItem{}, items[len(items)-1].Num)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+
+	_, err = db.Scan(&[]scanItem{}, "!", 3)
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestEachRaw(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range int64(3) {
+		err = db.Put(scanItem{Num: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var keys []string
+	err = db.EachRaw("scanItem", func(key string, data []byte) error {
+		stored, err := os.ReadFile(db.entityPath("scanItem", key))
+		if err != nil {
+			return err
+		}
+
+		if string(data) != string(stored) {
+			t.Errorf("%s: got %q, want %q", key, data, stored)
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"0", "1", "2"}; !slices.Equal(keys, want) {
+		t.Fatalf("got keys %v, want %v", keys, want)
+	}
+}
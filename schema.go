@@ -0,0 +1,390 @@
+package burrowdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithSchema specifies a JSON Schema which every entity of the named type must
+// satisfy to be Put. Violations are returned as ErrSchemaViolation and nothing
+// is written.
+//
+// The following subset of JSON Schema is supported: type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum. The annotations $schema, $id, $comment, title,
+// description, default and examples are allowed but have no effect, and any
+// other keyword, such as $ref, allOf or format, is an error so that a schema is
+// never enforced only in part.
+func WithSchema(typeName string, schema []byte) newDBOption {
+	return func(db *BurrowDB) error {
+		s := &jsonSchema{}
+		err := decodeJSONNumbers(schema, s)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal schema for %s: %w", typeName, err)
+		}
+
+		err = s.compile()
+		if err != nil {
+			return fmt.Errorf("invalid schema for %s: %w", typeName, err)
+		}
+
+		if db.schemas == nil {
+			db.schemas = map[string]*jsonSchema{}
+		}
+		db.schemas[typeName] = s
+
+		return nil
+	}
+}
+
+// validateSchema checks the passed value of the named type against the type's
+// schema, if it has one. The data is the value encoded by the passed codec and
+// is validated directly when the codec is JSON.
+func (db *BurrowDB) validateSchema(typeName string, codec Codec, v any, data []byte) error {
+	s, ok := db.schemas[typeName]
+	if !ok {
+		return nil
+	}
+
+	if codec.Name() != JSONCodec.Name() {
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("unable to marshal value for validation: %w", err)
+		}
+	}
+
+	var doc any
+	err := decodeJSONNumbers(data, &doc)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal value for validation: %w", err)
+	}
+
+	return s.validate("$", doc)
+}
+
+// jsonSchema is a parsed JSON Schema.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                *any                   `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+
+	pattern     *regexp.Regexp // compiled Pattern.
+	unsupported []string       // keywords of the schema which aren't supported, sorted.
+}
+
+// schemaKeywords are the keywords a jsonSchema may have, being those it
+// validates along with annotations which don't affect validation.
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true,
+	"maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+}
+
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	var keywords map[string]json.RawMessage
+	err := json.Unmarshal(data, &keywords)
+	if err != nil {
+		return err
+	}
+
+	// The keywords are decoded as a type without this method to avoid
+	// recursing.
+	type plainSchema jsonSchema
+	err = decodeJSONNumbers(data, (*plainSchema)(s))
+	if err != nil {
+		return err
+	}
+
+	for keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			s.unsupported = append(s.unsupported, keyword)
+		}
+	}
+	slices.Sort(s.unsupported)
+
+	return nil
+}
+
+// schemaTypes is the value of the type keyword, which may be a single type name
+// or a list of them.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	err := json.Unmarshal(data, &name)
+	if err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// compile compiles the patterns of the schema and its subschemas, returning an
+// error if any of them has an unsupported keyword.
+func (s *jsonSchema) compile() error {
+	if len(s.unsupported) > 0 {
+		return fmt.Errorf("unsupported keyword %s", strings.Join(s.unsupported, ", "))
+	}
+
+	if s.Pattern != "" {
+		var err error
+		s.pattern, err = regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	for name, prop := range s.Properties {
+		err := prop.compile()
+		if err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+	}
+
+	if s.Items != nil {
+		err := s.Items.compile()
+		if err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks the passed decoded JSON value, found at the passed path,
+// against the schema.
+func (s *jsonSchema) validate(path string, v any) error {
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(name string) bool {
+		return isSchemaType(name, v)
+	}) {
+		return schemaViolation(path, "expected %s, got %s", strings.Join(s.Type, " or "), jsonTypeName(v))
+	}
+
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool {
+		return jsonEqual(e, v)
+	}) {
+		return schemaViolation(path, "value is not one of the enumerated values")
+	}
+
+	if s.Const != nil && !jsonEqual(*s.Const, v) {
+		return schemaViolation(path, "value does not equal the constant")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return s.validateObject(path, v)
+	case []any:
+		return s.validateArray(path, v)
+	case string:
+		return s.validateString(path, v)
+	case json.Number:
+		return s.validateNumber(path, v)
+	}
+
+	return nil
+}
+
+// validateObject checks the keywords which apply to objects.
+func (s *jsonSchema) validateObject(path string, obj map[string]any) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return schemaViolation(path, "missing required property %q", name)
+		}
+	}
+
+	// Visit properties in order so the reported violation is deterministic.
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return schemaViolation(path, "unexpected property %q", name)
+			}
+			continue
+		}
+
+		err := prop.validate(path+"."+name, obj[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateArray checks the keywords which apply to arrays.
+func (s *jsonSchema) validateArray(path string, arr []any) error {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		return schemaViolation(path, "expected at least %d items, got %d", *s.MinItems, len(arr))
+	}
+
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		return schemaViolation(path, "expected at most %d items, got %d", *s.MaxItems, len(arr))
+	}
+
+	if s.Items == nil {
+		return nil
+	}
+
+	for i, item := range arr {
+		err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateString checks the keywords which apply to strings.
+func (s *jsonSchema) validateString(path string, str string) error {
+	n := utf8.RuneCountInString(str)
+	if s.MinLength != nil && n < *s.MinLength {
+		return schemaViolation(path, "expected at least %d characters, got %d", *s.MinLength, n)
+	}
+
+	if s.MaxLength != nil && n > *s.MaxLength {
+		return schemaViolation(path, "expected at most %d characters, got %d", *s.MaxLength, n)
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return schemaViolation(path, "value does not match pattern %q", s.Pattern)
+	}
+
+	return nil
+}
+
+// validateNumber checks the keywords which apply to numbers.
+func (s *jsonSchema) validateNumber(path string, num json.Number) error {
+	f, err := num.Float64()
+	if err != nil {
+		return schemaViolation(path, "invalid number %s", num)
+	}
+
+	switch {
+	case s.Minimum != nil && f < *s.Minimum:
+		return schemaViolation(path, "%s is less than the minimum %v", num, *s.Minimum)
+	case s.Maximum != nil && f > *s.Maximum:
+		return schemaViolation(path, "%s is greater than the maximum %v", num, *s.Maximum)
+	case s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum:
+		return schemaViolation(path, "%s is not greater than %v", num, *s.ExclusiveMinimum)
+	case s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum:
+		return schemaViolation(path, "%s is not less than %v", num, *s.ExclusiveMaximum)
+	}
+
+	return nil
+}
+
+// schemaViolation returns an ErrSchemaViolation describing the violation at the
+// passed path.
+func schemaViolation(path, format string, args ...any) error {
+	return fmt.Errorf("%w: %s: %s", ErrSchemaViolation, path, fmt.Sprintf(format, args...))
+}
+
+// isSchemaType reports whether the decoded JSON value is of the named JSON
+// Schema type.
+func isSchemaType(name string, v any) bool {
+	if name == "integer" {
+		num, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := num.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+
+	return name == jsonTypeName(v) || (name == "number" && jsonTypeName(v) == "integer")
+}
+
+// jsonTypeName returns the JSON Schema type name of the decoded JSON value.
+func jsonTypeName(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if isSchemaType("integer", v) {
+			return "integer"
+		}
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing
+// numbers by value.
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := a.Float64()
+		fb, errB := b.Float64()
+		return errA == nil && errB == nil && fa == fb
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, jsonEqual)
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !jsonEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+// decodeJSONNumbers decodes JSON data into dst, decoding numbers held in
+// interface values as json.Number.
+func decodeJSONNumbers(data []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(dst)
+}
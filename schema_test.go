@@ -0,0 +1,85 @@
+package burrowdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type schemaItem struct {
+	ID   int
+	Name string `json:"name,omitempty"`
+	Age  int    `json:"age"`
+	Tags []string
+}
+
+const schemaItemSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+		"age": {"type": "integer", "minimum": 0, "maximum": 150},
+		"Tags": {"type": ["array", "null"], "items": {"enum": ["a", "b"]}}
+	}
+}`
+
+func TestSchema(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSchema("schemaItem", []byte(schemaItemSchema)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		item  schemaItem
+		valid bool
+	}{
+		{schemaItem{ID: 1}, false},
+		{schemaItem{ID: 2, Name: "al"}, false},
+		{schemaItem{ID: 3, Name: "Al", Age: 200}, false},
+		{schemaItem{ID: 4, Name: "Al", Tags: []string{"c"}}, false},
+		{schemaItem{ID: 5, Name: "Al", Age: 3, Tags: []string{"a"}}, true},
+		{schemaItem{ID: 6, Name: "Bo"}, true},
+	}
+
+	for _, test := range tests {
+		err = db.Put(test.item)
+		if test.valid && err != nil {
+			t.Errorf("%+v: got %v", test.item, err)
+		} else if !test.valid && !errors.Is(err, ErrSchemaViolation) {
+			t.Errorf("%+v: got %v, want %v", test.item, err, ErrSchemaViolation)
+		}
+
+		// Nothing is written for a violation.
+		err = db.GetByID(&schemaItem{}, test.item.ID)
+		if test.valid != (err == nil) {
+			t.Errorf("%+v: got %v getting it back", test.item, err)
+		}
+	}
+}
+
+func TestSchemaInvalid(t *testing.T) {
+	_, err := NewDB(WithDir(t.TempDir()), WithSchema("schemaItem", []byte(`{"pattern": "["}`)))
+	if err == nil {
+		t.Fatal("got no error for a bad pattern")
+	}
+
+	// Keywords which aren't supported are rejected rather than ignored,
+	// wherever they are.
+	for _, schema := range []string{
+		`{"$ref": "#/definitions/item"}`,
+		`{"allOf": [{"type": "object"}]}`,
+		`{"properties": {"name": {"format": "email"}}}`,
+		`{"items": {"not": {"type": "null"}}}`,
+	} {
+		_, err = NewDB(WithDir(t.TempDir()), WithSchema("schemaItem", []byte(schema)))
+		if err == nil || !strings.Contains(err.Error(), "unsupported keyword") {
+			t.Errorf("got %v for %s, want an unsupported keyword", err, schema)
+		}
+	}
+
+	// Annotations are allowed.
+	_, err = NewDB(WithDir(t.TempDir()), WithSchema("schemaItem", []byte(`{"title": "Item", "description": "An item.", "type": "object"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const seedFileName = ".seeded" // Name of the file in the db dir marking that it has been seeded.
+
+// WithSeed specifies a function which populates a new store. It is run by NewDB
+// the first time the db dir is opened and never again, as recorded by a marker
+// file written once it succeeds. If it fails, NewDB returns its error and the
+// seed is attempted again the next time the dir is opened.
+func WithSeed(seed func(*BurrowDB) error) newDBOption {
+	return func(db *BurrowDB) error {
+		if seed == nil {
+			return errors.New("seed function is nil")
+		}
+
+		db.seed = seed
+		return nil
+	}
+}
+
+// runSeed runs the db's seed function if the db dir hasn't already been seeded.
+func (db *BurrowDB) runSeed() error {
+	filename := fmt.Sprintf("%s/%s", db.dir, seedFileName)
+	_, err := os.Stat(filename)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to stat seed marker: %w", err)
+	}
+
+	err = db.seed(db)
+	if err != nil {
+		return fmt.Errorf("unable to seed db: %w", err)
+	}
+
+	err = db.writeFileAtomic(filename, nil)
+	if err != nil {
+		return fmt.Errorf("unable to write seed marker: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package burrowdb
+
+import (
+	"errors"
+	"testing"
+)
+
+type seedItem struct {
+	ID int
+}
+
+func TestSeed(t *testing.T) {
+	dir := t.TempDir()
+	runs := 0
+	seed := WithSeed(func(db *BurrowDB) error {
+		runs++
+		return db.Put(seedItem{ID: 1})
+	})
+
+	for range 2 {
+		db, err := NewDB(WithDir(dir), seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.GetByID(&seedItem{}, 1)
+		if err != nil {
+			t.Fatalf("got %v, want the seeded entity", err)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("got %d runs of the seed, want 1", runs)
+	}
+}
+
+func TestSeedError(t *testing.T) {
+	dir := t.TempDir()
+	errSeed := errors.New("seed failed")
+	_, err := NewDB(WithDir(dir), WithSeed(func(*BurrowDB) error {
+		return errSeed
+	}))
+	if !errors.Is(err, errSeed) {
+		t.Fatalf("got %v, want %v", err, errSeed)
+	}
+
+	// A failed seed is attempted again.
+	runs := 0
+	_, err = NewDB(WithDir(dir), WithSeed(func(*BurrowDB) error {
+		runs++
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Fatalf("got %d runs of the seed, want 1", runs)
+	}
+}
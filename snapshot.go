@@ -0,0 +1,92 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot is a read-only view of a store as it was when BurrowDB.Snapshot was
+// called. Writes made to the store afterwards aren't seen through it.
+type Snapshot struct {
+	db *BurrowDB // db using the dir holding the snapshot.
+}
+
+// Snapshot returns a consistent view of every type in the store, for reading
+// several entities without seeing writes made in between. Every type is read
+// locked while the snapshot is taken, by hard linking the files of the db dir
+// into a temp dir, so it is cheap even for large stores. The temp dir is
+// created in the dir set by WithTempDir, or else beside the db dir so that it
+// is on the same device, and is removed by Close. If the dir beside the db dir
+// can't be written to, the system's temp dir is used instead, where files on
+// another device have to be copied, making the snapshot as costly as a backup.
+func (db *BurrowDB) Snapshot() (_ *Snapshot, err error) {
+	defer db.handleError("Snapshot", &err)
+
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure every stored type has a lock for rLockAll to acquire.
+	for _, typeName := range typeNames {
+		db.typeLock(typeName)
+	}
+
+	dir, err := db.snapshotDir()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create snapshot dir: %w", noSpace(err))
+	}
+
+	unlock := db.locks.rLockAll()
+	err = db.copyFiles(dir, true)
+	unlock()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("unable to snapshot db: %w", err)
+	}
+
+	snap, err := db.openDetached(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("unable to open snapshot: %w", err)
+	}
+	snap.errorHandler = db.errorHandler
+
+	return &Snapshot{db: snap}, nil
+}
+
+// snapshotDir creates the temp dir for a snapshot, as described by Snapshot.
+func (db *BurrowDB) snapshotDir() (string, error) {
+	if db.tempDir != "" {
+		return os.MkdirTemp(db.tempDir, "burrowdb-snapshot-")
+	}
+
+	dbDir := filepath.Clean(db.dir)
+	pattern := fmt.Sprintf(".%s-snapshot-", filepath.Base(dbDir))
+	dir, err := os.MkdirTemp(filepath.Dir(dbDir), pattern)
+	if errors.Is(err, fs.ErrPermission) {
+		return os.MkdirTemp("", pattern)
+	}
+	return dir, err
+}
+
+// GetByID gets the entity with the type of the passed destination with the
+// passed ID as it was when the snapshot was taken, as BurrowDB.GetByID does.
+func (s *Snapshot) GetByID(dst any, id any) error {
+	return s.db.GetByID(dst, id)
+}
+
+// GetAll gets every entity with the element type of the slice pointed to by dst
+// as they were when the snapshot was taken, as BurrowDB.GetAll does.
+func (s *Snapshot) GetAll(dst any) error {
+	return s.db.GetAll(dst)
+}
+
+// Close releases the snapshot, removing its files. It should not be used
+// afterwards.
+func (s *Snapshot) Close() error {
+	return errors.Join(s.db.Close(), os.RemoveAll(s.db.dir))
+}
@@ -0,0 +1,137 @@
+package burrowdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type snapshotItem struct {
+	ID   int
+	Name string
+}
+
+func TestSnapshot(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(snapshotItem{ID: 1, Name: "a"}, snapshotItem{ID: 2, Name: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(snapshotItem{ID: 1, Name: "changed"})
+	if err == nil {
+		err = db.Delete(snapshotItem{}, 2)
+	}
+	if err == nil {
+		err = db.Put(snapshotItem{ID: 3, Name: "c"})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item snapshotItem
+	err = snap.GetByID(&item, 1)
+	if err != nil || item.Name != "a" {
+		t.Fatalf("got %+v, %v, want the entity as it was", item, err)
+	}
+
+	var items []snapshotItem
+	err = snap.GetAll(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Name != "a" || items[1].Name != "b" {
+		t.Fatalf("got %+v, want the entities as they were", items)
+	}
+
+	// The store itself sees the writes.
+	err = db.GetByID(&item, 1)
+	if err != nil || item.Name != "changed" {
+		t.Fatalf("got %+v, %v from the store", item, err)
+	}
+
+	// The snapshot dir is beside the db dir, so entity files are linked
+	// rather than copied.
+	dir := snap.db.dir
+	if filepath.Dir(dir) != filepath.Dir(db.dir) {
+		t.Fatalf("got snapshot dir %s, want it beside %s", dir, db.dir)
+	}
+
+	other, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	stored, err := os.Stat(db.entityPath("snapshotItem", "3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := os.Stat(other.db.entityPath("snapshotItem", "3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(stored, linked) {
+		t.Fatal("got the entity file copied into the snapshot, want it linked")
+	}
+
+	err = snap.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(dir)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v for the snapshot dir after closing, want it removed", err)
+	}
+}
+
+func TestSnapshotKeyNames(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithEncryption(bytes.Repeat([]byte("k"), 32)), WithEncryptedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(snapshotItem{ID: 1, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+
+	// The key names file is appended to in place, so the snapshot's copy
+	// mustn't see IDs recorded afterwards.
+	filename := snap.db.keyNamesPath("snapshotItem")
+	before, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(snapshotItem{ID: 2, Name: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("got the snapshot's key names file changed by a later put")
+	}
+}
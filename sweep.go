@@ -0,0 +1,131 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// defaultSweepBatchSize is the number of expired entities deleted from each
+// type on each sweep if WithSweepBatchSize isn't used.
+const defaultSweepBatchSize = 100
+
+// WithSweepInterval specifies that the limits set by WithRetention should also
+// be applied in the background every interval, so that expired entities are
+// deleted even if nothing more of their type is put. Each sweep deletes a
+// bounded batch of entities from each type, as set by WithSweepBatchSize,
+// releasing the type's lock between types, so a backlog is worked through over
+// several sweeps rather than all at once. The sweeper is stopped by Close.
+//
+// Errors met while sweeping are passed to the handler given to
+// WithErrorHandler, if any, with the operation "Sweep".
+func WithSweepInterval(interval time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if interval <= 0 {
+			return errors.New("sweep interval must be positive")
+		}
+
+		db.sweepInterval = interval
+		return nil
+	}
+}
+
+// WithSweepBatchSize specifies the maximum number of expired entities deleted
+// from each type on each sweep started by WithSweepInterval. It defaults to
+// 100.
+func WithSweepBatchSize(n int) newDBOption {
+	return func(db *BurrowDB) error {
+		if n < 1 {
+			return errors.New("sweep batch size must be at least 1")
+		}
+
+		db.sweepBatchSize = n
+		return nil
+	}
+}
+
+// startSweeper starts sweeping every sweep interval until stopSweeper is
+// called, if the db has a sweep interval and retention limits.
+func (db *BurrowDB) startSweeper() {
+	if db.sweepInterval == 0 || len(db.retention) == 0 {
+		return
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	db.sweepStop, db.sweepDone = stop, done
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(db.sweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := db.sweep()
+				db.handleError("Sweep", &err)
+			}
+		}
+	}()
+}
+
+// stopSweeper stops the sweeper, if it is running, and waits for it to finish.
+func (db *BurrowDB) stopSweeper() {
+	if db.sweepStop == nil {
+		return
+	}
+
+	close(db.sweepStop)
+	<-db.sweepDone
+	db.sweepStop, db.sweepDone = nil, nil
+}
+
+// sweep deletes a batch of the expired entities of each type with retention
+// limits.
+func (db *BurrowDB) sweep() error {
+	var errs []error
+	for _, typeName := range slices.Sorted(maps.Keys(db.retention)) {
+		err := db.sweepType(typeName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to sweep %s: %w", typeName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sweepType deletes up to a batch of the expired entities of the named type,
+// oldest first.
+func (db *BurrowDB) sweepType(typeName string) error {
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	expired, err := db.expiredKeys(typeName)
+	if err != nil {
+		return err
+	}
+
+	batchSize := db.sweepBatchSize
+	if batchSize == 0 {
+		batchSize = defaultSweepBatchSize
+	}
+
+	var errs []error
+	for _, key := range expired[:min(batchSize, len(expired))] {
+		// The Go type isn't known, so the indexes are updated from disk.
+		err = db.deleteEntity(typeName, key)
+		if err == nil {
+			err = db.removeFromRawIndexes(typeName, key)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to delete expired %q: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
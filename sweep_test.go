@@ -0,0 +1,96 @@
+package burrowdb
+
+import (
+	"testing"
+	"time"
+)
+
+type sweepItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+// putSweepItems puts n entities into a new db in dir without retention limits,
+// so that none are pruned as they are put.
+func putSweepItems(t *testing.T, dir string, n int) {
+	t.Helper()
+
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range n {
+		err = db.Put(sweepItem{ID: i + 1, Status: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSweepBatches(t *testing.T) {
+	dir := t.TempDir()
+	putSweepItems(t, dir, 10)
+
+	db, err := NewDB(WithDir(dir), WithRetention("sweepItem", 2, 0), WithSweepInterval(time.Hour), WithSweepBatchSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Each sweep deletes a batch of the oldest entities, updating the index.
+	for _, want := range []int{7, 4, 2, 2} {
+		err = db.sweep()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var items []sweepItem
+		err = db.GetByField(&items, "Status", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != want || items[0].ID != 11-want {
+			t.Fatalf("got %+v, want the %d highest IDs", items, want)
+		}
+	}
+}
+
+func TestSweepInterval(t *testing.T) {
+	dir := t.TempDir()
+	putSweepItems(t, dir, 5)
+
+	db, err := NewDB(WithDir(dir), WithRetention("sweepItem", 1, 0), WithSweepInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		keys, err := db.IntKeys(sweepItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v after 5s, want the sweeper to prune to 1", keys)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSweepInvalid(t *testing.T) {
+	for _, opt := range []newDBOption{WithSweepInterval(0), WithSweepBatchSize(0)} {
+		_, err := NewDB(WithDir(t.TempDir()), opt)
+		if err == nil {
+			t.Error("got no error for an invalid option")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WithNoFollowSymlinks specifies that entity files, and the directories between
+// them and the db dir, must not be symlinks. Reading or writing an entity
+// through a symlink, which could have been planted to reach files outside the
+// store, returns ErrSymlink rather than following it. The db dir itself may
+// be a symlink.
+func WithNoFollowSymlinks() newDBOption {
+	return func(db *BurrowDB) error {
+		db.noFollowSymlinks = true
+		return nil
+	}
+}
+
+// checkSymlinks returns ErrSymlink if the db doesn't follow symlinks and the
+// file at the passed path within the db dir, or any directory between it and
+// the db dir, is one. Paths which don't exist yet are skipped.
+func (db *BurrowDB) checkSymlinks(filename string) error {
+	if !db.noFollowSymlinks {
+		return nil
+	}
+
+	root := filepath.Clean(db.dir)
+	for p := filepath.Clean(filename); p != root && p != filepath.Dir(p); p = filepath.Dir(p) {
+		info, err := os.Lstat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to stat %q: %w", p, err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q", ErrSymlink, p)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type symlinkItem struct {
+	ID   int
+	Name string
+}
+
+func TestNoFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithNoFollowSymlinks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(symlinkItem{ID: 1, Name: "real"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plant an entity file linking outside the store.
+	outside := filepath.Join(t.TempDir(), "secret")
+	err = os.WriteFile(outside, []byte(`{"ID":2,"Name":"secret"}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Symlink(outside, db.entityPath("symlinkItem", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got symlinkItem
+	err = db.GetByID(&got, 2)
+	if !errors.Is(err, ErrSymlink) {
+		t.Fatalf("got %v reading through a symlink, want %v", err, ErrSymlink)
+	}
+
+	err = db.Put(symlinkItem{ID: 2, Name: "overwritten"})
+	if !errors.Is(err, ErrSymlink) {
+		t.Fatalf("got %v writing through a symlink, want %v", err, ErrSymlink)
+	}
+
+	data, err := os.ReadFile(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"ID":2,"Name":"secret"}` {
+		t.Fatalf("got %s, want the file outside the store untouched", data)
+	}
+
+	err = db.GetByID(&got, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the option the symlink is followed.
+	other, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = other.GetByID(&got, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "secret" {
+		t.Fatalf("got %+v, want the linked entity", got)
+	}
+}
+
+func TestNoFollowSymlinkedTypeDir(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithNoFollowSymlinks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := t.TempDir()
+	err = os.Symlink(target, db.typeDir("symlinkItem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(symlinkItem{ID: 1})
+	if !errors.Is(err, ErrSymlink) {
+		t.Fatalf("got %v writing into a symlinked type dir, want %v", err, ErrSymlink)
+	}
+
+	err = db.PutBatch(symlinkItem{ID: 1})
+	if !errors.Is(err, ErrSymlink) {
+		t.Fatalf("got %v writing a batch into a symlinked type dir, want %v", err, ErrSymlink)
+	}
+
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d files written through the symlink, want none", len(entries))
+	}
+}
@@ -0,0 +1,283 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithSyncEvery specifies that files written by the db should be synced to disk
+// once every n entities are written, rather than being left for the operating
+// system to flush, so that at most the last n writes can be lost by a crash.
+// Files such as indexes written alongside an entity are synced with it, as are
+// the directories holding new or renamed files and any directories created for
+// them. Files replaced atomically are synced before being renamed into place,
+// so a crash never leaves one partially written, and only their directories
+// wait for the sync. Flush and Close sync any writes made since the last sync.
+func WithSyncEvery(n int) newDBOption {
+	return func(db *BurrowDB) error {
+		if n < 1 {
+			return errors.New("sync count must be at least 1")
+		}
+
+		db.syncEvery = n
+		db.syncer = &syncer{}
+		return nil
+	}
+}
+
+// WithSyncInterval specifies that files written by the db should be synced to
+// disk at most interval after they're written, batching the syncs of writes
+// made in quick succession. It may be combined with WithSyncEvery, in which
+// case files are synced when either limit is reached. Files replaced atomically
+// are synced before being renamed into place, as with WithSyncEvery. Flush and
+// Close sync any writes made since the last sync.
+//
+// Errors met by syncs started by the interval are passed to the handler given
+// to WithErrorHandler, if any, with the operation "Sync", and returned by the
+// next Flush or Close.
+func WithSyncInterval(interval time.Duration) newDBOption {
+	return func(db *BurrowDB) error {
+		if interval <= 0 {
+			return errors.New("sync interval must be positive")
+		}
+
+		db.syncInterval = interval
+		db.syncer = &syncer{}
+		return nil
+	}
+}
+
+// Durability is how much of the data written by the db survives a crash, as
+// set by WithDurability. Each level is safer, and slower, than the one before.
+type Durability int
+
+const (
+	// DurabilityNone leaves written files for the operating system to flush,
+	// so writes made shortly before a crash may be lost or left incomplete,
+	// although atomic writes mean a file is never seen partially written
+	// while the system is running. This is the default and the fastest.
+	DurabilityNone Durability = iota
+
+	// DurabilityFlush syncs the contents of each replaced file before it is
+	// renamed into place, and the rest of the written files in batches, at
+	// most a second after they're written and by Flush and Close, as
+	// WithSyncInterval does. Up to a second of writes may be lost, and new
+	// files whose directory entries haven't been flushed may vanish, but a
+	// file is never left partially written.
+	DurabilityFlush
+
+	// DurabilitySync syncs the files of each write before it returns, so a
+	// file is never left partially written. The directories holding them
+	// aren't synced, so after a crash a newly created entity may vanish and a
+	// replaced entity may have its previous contents back.
+	DurabilitySync
+
+	// DurabilitySyncDir syncs the files of each write and the directories
+	// holding them before it returns, so no write is lost once it returns.
+	DurabilitySyncDir
+
+	// DurabilityWAL syncs as DurabilitySyncDir does and also records each
+	// write in a write-ahead log, as WithWAL does, so that a write interrupted
+	// by a crash is completed the next time the dir is opened. Each write
+	// costs an extra file and sync.
+	DurabilityWAL
+)
+
+// defaultFlushInterval is the time after a write by which its files are synced
+// with DurabilityFlush.
+const defaultFlushInterval = time.Second
+
+// WithDurability sets how written files are synced to disk to the passed
+// level, replacing the settings of WithSyncEvery, WithSyncInterval and WithWAL
+// given before it. Options given after it adjust the level's settings.
+func WithDurability(level Durability) newDBOption {
+	return func(db *BurrowDB) error {
+		if level < DurabilityNone || level > DurabilityWAL {
+			return fmt.Errorf("unknown durability level %d", level)
+		}
+
+		db.syncer, db.syncEvery, db.syncInterval = nil, 0, 0
+		db.noDirSync = level < DurabilitySyncDir
+		db.wal = level == DurabilityWAL
+
+		switch level {
+		case DurabilityFlush:
+			db.syncInterval = defaultFlushInterval
+			db.syncer = &syncer{}
+		case DurabilitySync, DurabilitySyncDir, DurabilityWAL:
+			db.syncEvery = 1
+			db.syncer = &syncer{}
+		}
+
+		return nil
+	}
+}
+
+// syncer tracks the files written since they were last synced.
+type syncer struct {
+	mu     sync.Mutex
+	files  map[string]bool // paths of the files written since the last sync.
+	moved  map[string]bool // paths of the synced files renamed into place since the last sync.
+	writes int             // number of entities written since the last sync.
+	timer  *time.Timer     // syncs the files once the sync interval ends, or nil.
+	syncs  int             // number of syncs made.
+	synced int             // number of files synced.
+	dirs   int             // number of directories synced.
+	errs   []error         // errors met by syncs started by the timer.
+}
+
+// markWritten records that the file at the passed path has been written,
+// starting the sync interval if it isn't already running.
+func (db *BurrowDB) markWritten(filename string) {
+	db.markPending(filename, false)
+}
+
+// markRenamed records that a file whose contents have already been synced has
+// been renamed into place at the passed path, so that only its directory needs
+// syncing, starting the sync interval if it isn't already running.
+func (db *BurrowDB) markRenamed(filename string) {
+	db.markPending(filename, true)
+}
+
+// markPending records the passed path as described by markWritten, or by
+// markRenamed if renamed is true.
+func (db *BurrowDB) markPending(filename string, renamed bool) {
+	s := db.syncer
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if renamed {
+		if s.moved == nil {
+			s.moved = map[string]bool{}
+		}
+		s.moved[filename] = true
+	} else {
+		if s.files == nil {
+			s.files = map[string]bool{}
+		}
+		s.files[filename] = true
+	}
+
+	if db.syncInterval > 0 && s.timer == nil {
+		s.timer = time.AfterFunc(db.syncInterval, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			err := s.syncLocked(!db.noDirSync)
+			db.handleError("Sync", &err)
+			if err != nil {
+				s.errs = append(s.errs, err)
+			}
+		})
+	}
+}
+
+// countWrites records that the passed number of entities have been written,
+// syncing every written file if the db's sync count is reached.
+func (db *BurrowDB) countWrites(n int) error {
+	s := db.syncer
+	if s == nil || db.syncEvery == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes += n
+	if s.writes < db.syncEvery {
+		return nil
+	}
+
+	return s.syncLocked(!db.noDirSync)
+}
+
+// syncWritten syncs every file written since the last sync, returning the
+// errors met by it and by syncs started by the sync interval since the last
+// call.
+func (db *BurrowDB) syncWritten() error {
+	s := db.syncer
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errs := append(s.errs, s.syncLocked(!db.noDirSync))
+	s.errs = nil
+
+	return errors.Join(errs...)
+}
+
+// syncLocked syncs every file written since the last sync, along with the
+// directories holding them and the files renamed into place if dirs is true so
+// that new files survive a crash. The syncer's lock must be held.
+func (s *syncer) syncLocked(dirs bool) error {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	if len(s.files) == 0 && len(s.moved) == 0 {
+		return nil
+	}
+
+	var errs []error
+	parents := map[string]bool{}
+	for filename := range s.moved {
+		parents[filepath.Dir(filename)] = true
+	}
+	for _, filename := range slices.Sorted(maps.Keys(s.files)) {
+		parents[filepath.Dir(filename)] = true
+
+		err := syncFile(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("unable to sync %s: %w", filename, err))
+		}
+		s.synced++
+	}
+
+	// Not every platform can sync a directory, so failures are ignored.
+	if dirs {
+		for dir := range parents {
+			syncFile(dir)
+			s.dirs++
+		}
+	}
+
+	s.files, s.moved = nil, nil
+	s.writes = 0
+	s.syncs++
+
+	return errors.Join(errs...)
+}
+
+// countSynced records that a file has been synced outside of a sync of the
+// written files, such as a temp file before it is renamed into place.
+func (s *syncer) countSynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.synced++
+}
+
+// syncFile syncs the file or directory at the passed path to disk.
+func syncFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
@@ -0,0 +1,210 @@
+package burrowdb
+
+import (
+	"testing"
+	"time"
+)
+
+type syncItem struct {
+	ID int
+}
+
+// syncs returns the number of syncs made by the db.
+func syncs(db *BurrowDB) int {
+	db.syncer.mu.Lock()
+	defer db.syncer.mu.Unlock()
+	return db.syncer.syncs
+}
+
+func TestSyncEvery(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSyncEvery(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 4 {
+		err = db.Put(syncItem{ID: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := syncs(db); n != 1 {
+		t.Fatalf("got %d syncs after 4 puts, want 1", n)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := syncs(db); n != 2 {
+		t.Fatalf("got %d syncs after Close, want 2", n)
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSyncInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(syncItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for syncs(db) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no sync after the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	err = db.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := syncs(db); n != 1 {
+		t.Fatalf("got %d syncs, want no sync by Flush without new writes", n)
+	}
+}
+
+func TestSyncCreatedDirs(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSyncEvery(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(syncItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new type dir is synced, along with the db dir holding its entry.
+	db.syncer.mu.Lock()
+	dirSynced := db.syncer.files[db.typeDir("syncItem")]
+	db.syncer.mu.Unlock()
+	if !dirSynced {
+		t.Fatal("got the created type dir left out of the next sync, want it synced")
+	}
+
+	err = db.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := syncs(db); n != 1 {
+		t.Fatalf("got %d syncs after Flush, want 1", n)
+	}
+}
+
+func TestDurability(t *testing.T) {
+	tests := []struct {
+		level   Durability
+		syncs   int  // syncs made by the puts.
+		flushed bool // whether the files are synced by Flush rather than the puts.
+		dirs    bool // whether directories are synced.
+		wal     bool
+	}{
+		{level: DurabilityNone},
+		{level: DurabilityFlush, flushed: true},
+		{level: DurabilitySync, syncs: 2},
+		{level: DurabilitySyncDir, syncs: 2, dirs: true},
+		{level: DurabilityWAL, syncs: 2, dirs: true, wal: true},
+	}
+
+	for _, test := range tests {
+		db, err := NewDB(WithDir(t.TempDir()), WithWAL(), WithDurability(test.level))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range 2 {
+			err = db.Put(syncItem{ID: i})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if db.syncer == nil {
+			if test.syncs > 0 || test.flushed {
+				t.Fatalf("level %d: got no syncer, want files synced", test.level)
+			}
+		} else {
+			// The interval of DurabilityFlush may pass on a slow machine.
+			if n := syncs(db); n != test.syncs && !test.flushed {
+				t.Fatalf("level %d: got %d syncs after 2 puts, want %d", test.level, n, test.syncs)
+			}
+
+			err = db.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			db.syncer.mu.Lock()
+			synced, dirs := db.syncer.synced, db.syncer.dirs
+			db.syncer.mu.Unlock()
+			if synced < 2 {
+				t.Fatalf("level %d: got %d files synced, want both entities", test.level, synced)
+			}
+			if (dirs > 0) != test.dirs {
+				t.Fatalf("level %d: got %d directories synced, want them synced %t", test.level, dirs, test.dirs)
+			}
+		}
+
+		if db.wal != test.wal {
+			t.Fatalf("level %d: got write-ahead log %t, want %t", test.level, db.wal, test.wal)
+		}
+
+		if cfg := db.Config(); cfg.NoDirSync == test.dirs {
+			t.Fatalf("level %d: got NoDirSync %t in config, want %t", test.level, cfg.NoDirSync, !test.dirs)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := NewDB(WithDir(t.TempDir()), WithDurability(DurabilityWAL+1))
+	if err == nil {
+		t.Fatal("got no error for an unknown durability level")
+	}
+}
+
+func TestSyncBeforeRename(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()), WithSyncEvery(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Put(syncItem{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The entity is synced before it replaces the previous file, leaving only
+	// its directory for the next sync.
+	db.syncer.mu.Lock()
+	synced, moved, pending := db.syncer.synced, db.syncer.moved, db.syncer.files
+	db.syncer.mu.Unlock()
+	filename := db.entityPath("syncItem", db.entityKey(1))
+	if synced == 0 || !moved[filename] || pending[filename] {
+		t.Fatalf("got %d files synced with %v renamed and %v pending, want the entity synced before its rename", synced, moved, pending)
+	}
+
+	err = db.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.syncer.mu.Lock()
+	synced, dirs := db.syncer.synced, db.syncer.dirs
+	db.syncer.mu.Unlock()
+	if dirs == 0 {
+		t.Fatalf("got %d files and no directories synced by Flush, want the entity's directory synced", synced)
+	}
+}
@@ -0,0 +1,90 @@
+package burrowdb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// tagOptions holds the options set on a field by its burrowdb struct tag, a
+// comma separated list of keywords in any order such as `burrowdb:"index,enum"`,
+// along with options taking a value such as `burrowdb:"name=created"`.
+type tagOptions struct {
+	id      bool // whether the field is the ID field.
+	index   bool // whether the field has a secondary index.
+	unique  bool // whether the field has a unique index.
+	enum    bool // whether the field's index is stored with one file per value.
+	encrypt bool // whether the field is stored encrypted.
+	binary  bool // whether the field is stored as raw bytes rather than base64.
+
+	omitEmpty bool // whether zero values of the field are left out of its index.
+
+	name string // name of the JSON member the field is stored under, or "" for its JSON name.
+}
+
+// parseTag returns the options set by the burrowdb struct tag of the passed
+// field. Unknown keywords are ignored, but are reported by the returned error
+// wrapping ErrInvalidTag so they can be rejected by WithStrictTags.
+func parseTag(field reflect.StructField) (tagOptions, error) {
+	var opts tagOptions
+	var unknown []string
+	for keyword := range strings.SplitSeq(field.Tag.Get(structTagName), ",") {
+		keyword = strings.TrimSpace(keyword)
+		switch keyword {
+		case "":
+		case idFieldName:
+			opts.id = true
+		case indexTagValue:
+			opts.index = true
+		case uniqueTagValue:
+			opts.unique = true
+		case enumTagOption:
+			opts.enum = true
+		case encryptTagValue:
+			opts.encrypt = true
+		case binaryTagValue:
+			opts.binary = true
+		case omitEmptyTagOption:
+			opts.omitEmpty = true
+		default:
+			if name, ok := strings.CutPrefix(keyword, nameTagOption); ok && name != "" {
+				opts.name = name
+				continue
+			}
+			unknown = append(unknown, fmt.Sprintf("%q", keyword))
+		}
+	}
+
+	if len(unknown) > 0 {
+		return opts, fmt.Errorf("%w: field %s has unknown options %s", ErrInvalidTag, field.Name, strings.Join(unknown, ", "))
+	}
+
+	return opts, nil
+}
+
+// WithStrictTags specifies that Put should return ErrInvalidTag for values
+// with a burrowdb struct tag containing an unknown keyword, rather than
+// ignoring it, so that misspelled options are caught.
+func WithStrictTags() newDBOption {
+	return func(db *BurrowDB) error {
+		db.strictTags = true
+		return nil
+	}
+}
+
+// checkTags returns the error of the first field of the passed struct type with
+// an invalid burrowdb struct tag, if tags are strict.
+func (db *BurrowDB) checkTags(_type reflect.Type) error {
+	if !db.strictTags {
+		return nil
+	}
+
+	for _, field := range reflect.VisibleFields(_type) {
+		_, err := parseTag(field)
+		if err != nil {
+			return fmt.Errorf("invalid tag on %s: %w", _type, err)
+		}
+	}
+
+	return nil
+}
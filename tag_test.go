@@ -0,0 +1,106 @@
+package burrowdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type tagItem struct {
+	ID     int
+	Status string `burrowdb:" enum , index"`
+	Email  string `burrowdb:"unique"`
+}
+
+type tagKeyed struct {
+	Key  string `burrowdb:"ID,omitempty"`
+	Code string `burrowdb:"unique,omitempty"`
+}
+
+type tagTypo struct {
+	ID     int
+	Status string `burrowdb:"idnex"`
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		field string
+		want  tagOptions
+	}{
+		{"ID", tagOptions{}},
+		{"Status", tagOptions{index: true, enum: true}},
+		{"Email", tagOptions{unique: true}},
+		{"Key", tagOptions{id: true, omitEmpty: true}},
+		{"Code", tagOptions{unique: true, omitEmpty: true}},
+	}
+
+	for _, test := range tests {
+		field, ok := reflect.TypeFor[tagItem]().FieldByName(test.field)
+		if !ok {
+			field, _ = reflect.TypeFor[tagKeyed]().FieldByName(test.field)
+		}
+		got, err := parseTag(field)
+		if err != nil {
+			t.Errorf("%s: got %v", test.field, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.field, got, test.want)
+		}
+	}
+
+	field, _ := reflect.TypeFor[tagTypo]().FieldByName("Status")
+	_, err := parseTag(field)
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("got %v for an unknown option, want %v", err, ErrInvalidTag)
+	}
+}
+
+func TestStrictTags(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown options are ignored by default.
+	err = db.Put(tagTypo{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strict, err := NewDB(WithDir(t.TempDir()), WithStrictTags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = strict.Put(tagTypo{ID: 1})
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidTag)
+	}
+
+	err = strict.Put(tagItem{ID: 1, Status: "a", Email: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID option may be combined with others, and omitempty leaves zero
+	// values out of a unique index so that they don't conflict.
+	err = strict.PutAll(tagKeyed{Key: "a"}, tagKeyed{Key: "b"}, tagKeyed{Key: "c", Code: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keyed []tagKeyed
+	err = strict.GetByField(&keyed, "Code", "")
+	if err != nil || len(keyed) != 0 {
+		t.Fatalf("got %+v, %v, want no entities indexed by an empty code", keyed, err)
+	}
+
+	err = strict.GetByField(&keyed, "Code", "x")
+	if err != nil || len(keyed) != 1 || keyed[0].Key != "c" {
+		t.Fatalf("got %+v, %v, want c", keyed, err)
+	}
+
+	err = strict.Put(tagKeyed{Key: "d", Code: "x"})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("got %v, want %v", err, ErrUniqueViolation)
+	}
+}
@@ -0,0 +1,120 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+const typeSchemaFileName = ".schema" // Name of the file in each type dir describing its fields.
+
+// TypeSchema describes the Go type of the entities of a type, as recorded the
+// first time the type was Put, so that tools without the type can understand
+// the stored data.
+type TypeSchema struct {
+	Name    string        `json:"name"`              // Name of the type.
+	IDField string        `json:"idField,omitempty"` // Name of the field used as the ID, if any.
+	Fields  []FieldSchema `json:"fields"`            // Exported fields in declaration order.
+}
+
+// FieldSchema describes a field of a TypeSchema.
+type FieldSchema struct {
+	Name string `json:"name"`          // Name of the field.
+	Kind string `json:"kind"`          // Kind of the field's type, such as int64 or struct.
+	Type string `json:"type"`          // Go type of the field, such as time.Time.
+	Tag  string `json:"tag,omitempty"` // Value of the field's burrowdb struct tag.
+}
+
+// Schema returns the descriptor recorded for the type of dst, which may be a
+// struct or a pointer to one and is only used for its type. The descriptor is
+// written as JSON to the .schema file of the type dir the first time the type
+// is Put and isn't updated if the type changes. ErrNoSuchEntity is returned if
+// no entity of the type has been Put.
+func (db *BurrowDB) Schema(dst any) (_ TypeSchema, err error) {
+	defer db.handleError("Schema", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return TypeSchema{}, ErrInvalidDstType
+	}
+
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	data, err := os.ReadFile(db.typeSchemaPath(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return TypeSchema{}, fmt.Errorf("%w: no schema has been recorded for %s", ErrNoSuchEntity, typeName)
+	} else if err != nil {
+		return TypeSchema{}, fmt.Errorf("unable to read schema: %w", err)
+	}
+
+	var schema TypeSchema
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		return TypeSchema{}, fmt.Errorf("unable to unmarshal schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// recordTypeSchema writes the descriptor of the passed struct type to its type
+// dir if one hasn't already been written. The lock of the type must be held.
+func (db *BurrowDB) recordTypeSchema(_type reflect.Type) error {
+	typeName := db.typeName(_type)
+	filename := db.typeSchemaPath(typeName)
+	_, err := os.Stat(filename)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to stat schema: %w", err)
+	}
+
+	// Types put with PutWithID or as a BurrowMarshaler may have no ID field.
+	schema := TypeSchema{Name: typeName, Fields: []FieldSchema{}}
+	idField, err := db.findIDField(_type)
+	if err == nil {
+		schema.IDField = idField.Name
+	} else if !errors.Is(err, ErrNoIDField) {
+		return err
+	}
+
+	for _, field := range reflect.VisibleFields(_type) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		schema.Fields = append(schema.Fields, FieldSchema{
+			Name: field.Name,
+			Kind: field.Type.Kind().String(),
+			Type: field.Type.String(),
+			Tag:  field.Tag.Get(structTagName),
+		})
+	}
+
+	data, err := json.MarshalIndent(schema, "", "\t")
+	if err != nil {
+		return fmt.Errorf("unable to marshal schema: %w", err)
+	}
+
+	err = db.mkdirAll(db.typeDir(typeName))
+	if err != nil {
+		return fmt.Errorf("unable to create type dir: %w", err)
+	}
+
+	err = db.writeFileAtomic(filename, data)
+	if err != nil {
+		return fmt.Errorf("unable to write schema: %w", err)
+	}
+
+	return nil
+}
+
+// typeSchemaPath returns the path of the file holding the descriptor of the
+// named type.
+func (db *BurrowDB) typeSchemaPath(typeName string) string {
+	return fmt.Sprintf("%s/%s", db.typeDir(typeName), typeSchemaFileName)
+}
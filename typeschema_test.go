@@ -0,0 +1,50 @@
+package burrowdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaDescribed struct {
+	Num     int64  `burrowdb:"ID"`
+	Status  string `burrowdb:"index"`
+	Created time.Time
+	hidden  int
+}
+
+func TestTypeSchema(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Schema(schemaDescribed{})
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v before a put, want %v", err, ErrNoSuchEntity)
+	}
+
+	err = db.Put(schemaDescribed{Num: 1, hidden: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.Schema(&schemaDescribed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := TypeSchema{
+		Name:    "schemaDescribed",
+		IDField: "Num",
+		Fields: []FieldSchema{
+			{Name: "Num", Kind: "int64", Type: "int64", Tag: "ID"},
+			{Name: "Status", Kind: "string", Type: "string", Tag: "index"},
+			{Name: "Created", Kind: "struct", Type: "time.Time"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
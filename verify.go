@@ -0,0 +1,168 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// VerifyIssue describes a problem found by Verify.
+type VerifyIssue struct {
+	Type   string // Type of the entity.
+	Key    string // Key of the entity.
+	Field  string // Indexed field, for problems with an index.
+	Detail string // Description of the problem.
+}
+
+// VerifyReport lists the problems found by Verify.
+type VerifyReport struct {
+	Entities  int           // Number of entities checked.
+	Corrupt   []VerifyIssue // Entities which can't be read or decoded.
+	Orphaned  []VerifyIssue // Index entries of entities which don't exist.
+	Unindexed []VerifyIssue // Entities missing from an index of their type.
+}
+
+// OK reports whether no problems were found.
+func (r VerifyReport) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Orphaned) == 0 && len(r.Unindexed) == 0
+}
+
+// Verify checks the consistency of every type in the store without modifying
+// anything. Each entity is checked to be decodable by the codec it was stored
+// with, and each index to refer only to existing entities and to include every
+// entity of its type. Entities whose indexed field is a nil pointer are
+// expected to be reported as unindexed.
+//
+// Problems with the data are listed in the report, and an error is only
+// returned if the check can't be completed. Problems with indexes can be
+// repaired with RebuildIndexes.
+func (db *BurrowDB) Verify() (_ VerifyReport, err error) {
+	defer db.handleError("Verify", &err)
+
+	var report VerifyReport
+	typeNames, err := db.typeNames()
+	if err != nil {
+		return report, err
+	}
+
+	for _, typeName := range typeNames {
+		err = db.verifyType(typeName, &report)
+		if err != nil {
+			return report, fmt.Errorf("unable to verify %s: %w", typeName, err)
+		}
+	}
+
+	return report, nil
+}
+
+// verifyType adds the problems with the named type to the report.
+func (db *BurrowDB) verifyType(typeName string, report *VerifyReport) error {
+	mu := db.typeLock(typeName)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	keys, err := db.keys(typeName)
+	if err != nil {
+		return err
+	}
+	report.Entities += len(keys)
+
+	codec, err := db.storedCodec(typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		codec, err := db.entityCodec(typeName, key, codec)
+		var data []byte
+		if err == nil {
+			data, err = db.readEntity(typeName, key)
+		}
+		// Decoding discards the value as its type isn't known. Values encoded
+		// by their BurrowMarshaler can only be decoded into their own type, so
+		// they aren't decoded.
+		if err == nil && codec.Name() != MarshalerCodec.Name() {
+			var dst any = new(any)
+			if codec.Name() == GobCodec.Name() {
+				dst = nil
+			}
+			err = codec.Unmarshal(data, dst)
+		}
+
+		if err != nil {
+			report.Corrupt = append(report.Corrupt, VerifyIssue{Type: typeName, Key: key, Detail: err.Error()})
+		}
+	}
+
+	indexes, err := db.readRawIndexes(typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(indexes)) {
+		idx := indexes[field]
+		indexed := map[string]bool{}
+		for _, value := range slices.Sorted(maps.Keys(idx)) {
+			for _, key := range idx[value] {
+				indexed[key] = true
+				if !slices.Contains(keys, key) {
+					report.Orphaned = append(report.Orphaned, VerifyIssue{
+						Type:   typeName,
+						Key:    key,
+						Field:  field,
+						Detail: fmt.Sprintf("indexed with value %q but doesn't exist", value),
+					})
+				}
+			}
+		}
+
+		for _, key := range keys {
+			if !indexed[key] {
+				report.Unindexed = append(report.Unindexed, VerifyIssue{Type: typeName, Key: key, Field: field, Detail: "missing from index"})
+			}
+		}
+	}
+
+	return nil
+}
+
+// readRawIndexes returns every index stored for the named type keyed by field,
+// reading them from disk without knowing the type's fields.
+func (db *BurrowDB) readRawIndexes(typeName string) (map[string]index, error) {
+	entries, err := os.ReadDir(db.indexDir(typeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read index dir: %w", err)
+	}
+
+	indexes := map[string]index{}
+	for _, entry := range entries {
+		field := entry.Name()
+		if !entry.IsDir() {
+			if strings.HasPrefix(field, ".") {
+				// Skip temp files.
+				continue
+			}
+
+			idx := index{}
+			err = readIndexFile(db.indexPath(typeName, field), &idx)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read index %s: %w", field, err)
+			}
+			indexes[field] = idx
+			continue
+		}
+
+		idx, err := db.readIndex(typeName, indexSpec{name: field, enum: true})
+		if err != nil {
+			return nil, err
+		}
+		indexes[field] = idx
+	}
+
+	return indexes, nil
+}
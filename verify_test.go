@@ -0,0 +1,84 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type verifyItem struct {
+	ID     int
+	Status string `burrowdb:"index"`
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(verifyItem{ID: 1, Status: "a"}, verifyItem{ID: 2, Status: "b"}, verifyItem{ID: 3, Status: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Entities != 3 {
+		t.Fatalf("got %+v for a consistent store", report)
+	}
+
+	// Remove one entity behind the index's back, corrupt another and add one
+	// which isn't indexed.
+	err = os.Remove(filepath.Join(dir, "verifyItem", "1"))
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "verifyItem", "2"), []byte("{bad"), 0666)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "verifyItem", "4"), []byte(`{"ID":4,"Status":"d"}`), 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = db.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("got an inconsistent store reported OK")
+	}
+
+	if len(report.Corrupt) != 1 || report.Corrupt[0].Key != "2" {
+		t.Errorf("got corrupt %+v, want entity 2", report.Corrupt)
+	}
+	if len(report.Orphaned) != 1 || report.Orphaned[0].Key != "1" || report.Orphaned[0].Field != "Status" {
+		t.Errorf("got orphaned %+v, want entity 1 in Status", report.Orphaned)
+	}
+	if len(report.Unindexed) != 1 || report.Unindexed[0].Key != "4" {
+		t.Errorf("got unindexed %+v, want entity 4", report.Unindexed)
+	}
+}
+
+func TestVerifyMarshaler(t *testing.T) {
+	db, err := NewDB(WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(codecPair{A: "a", B: "1"}, codecPair{A: "b", B: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Entities != 2 {
+		t.Fatalf("got %+v, want entities stored by their BurrowMarshaler reported OK", report)
+	}
+}
@@ -0,0 +1,185 @@
+package burrowdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const walDirName = ".wal" // Name of the directory in the db dir holding the write-ahead log.
+
+// walEntry is a mutation recorded in the write-ahead log before it is made.
+type walEntry struct {
+	Op   ChangeOp `json:"op"`             // Kind of mutation.
+	Path string   `json:"path"`           // Slash separated path of the mutated file relative to the db dir.
+	Data []byte   `json:"data,omitempty"` // Contents the file is given by a put.
+}
+
+// WithWAL specifies that every write and delete should be recorded in a
+// write-ahead log, and synced to disk, before it is made. Mutations which were
+// interrupted by a crash are completed by NewDB the next time the dir is
+// opened, so a mutation is never lost once the log has been written.
+func WithWAL() newDBOption {
+	return func(db *BurrowDB) error {
+		db.wal = true
+		return nil
+	}
+}
+
+// logIntent records in the write-ahead log that the file at the passed path is
+// about to be mutated, returning the path of the entry to pass to clearIntent
+// once the mutation is complete.
+func (db *BurrowDB) logIntent(op ChangeOp, filename string, data []byte) (string, error) {
+	err := db.checkWritable()
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(db.dir, filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to get path relative to db dir: %w", err)
+	}
+
+	entry, err := json.Marshal(walEntry{Op: op, Path: filepath.ToSlash(rel), Data: data})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal log entry: %w", err)
+	}
+
+	err = db.mkdirAll(db.walDir())
+	if err != nil {
+		return "", fmt.Errorf("unable to create log dir: %w", err)
+	}
+
+	// Entries are named by the time they were written so they can be replayed
+	// in order. The temp file suffix keeps the names of concurrent entries
+	// distinct.
+	release, err := db.acquireFile()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	f, err := createTemp(db.walDir(), db.filePerm())
+	if err != nil {
+		return "", fmt.Errorf("unable to create log entry: %w", noSpace(err))
+	}
+
+	name := filepath.Join(db.walDir(), fmt.Sprintf("%020d%s", time.Now().UnixNano(), filepath.Base(f.Name())))
+	_, err = f.Write(entry)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to write log entry: %w", noSpace(err))
+	}
+
+	// Sync the entry's name too, so that it survives a crash along with its
+	// contents. Not every platform can sync a directory, so failures are
+	// ignored.
+	syncFile(db.walDir())
+
+	return name, nil
+}
+
+// clearIntent removes the passed entry from the write-ahead log once its
+// mutation is complete.
+func (db *BurrowDB) clearIntent(entry string) error {
+	err := os.Remove(entry)
+	if err != nil {
+		return fmt.Errorf("unable to clear log entry: %w", err)
+	}
+	return nil
+}
+
+// replayWAL makes every mutation left in the write-ahead log by a crash, in the
+// order they were logged, and clears them from the log.
+func (db *BurrowDB) replayWAL() error {
+	entries, err := os.ReadDir(db.walDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read log dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		filename := filepath.Join(db.walDir(), name)
+		if strings.HasPrefix(name, tempFilePrefix) {
+			// The entry wasn't completely written, so neither was its mutation.
+			os.Remove(filename)
+			continue
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("unable to read log entry: %w", err)
+		}
+
+		var entry walEntry
+		err = json.Unmarshal(data, &entry)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal log entry %s: %w", name, err)
+		}
+
+		err = db.replay(entry)
+		if err != nil {
+			return fmt.Errorf("unable to replay log entry %s: %w", name, err)
+		}
+
+		err = db.clearIntent(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replay makes the mutation recorded by the passed log entry.
+func (db *BurrowDB) replay(entry walEntry) error {
+	if !filepath.IsLocal(entry.Path) {
+		return fmt.Errorf("invalid path %q", entry.Path)
+	}
+
+	filename := filepath.Join(db.dir, filepath.FromSlash(entry.Path))
+	switch entry.Op {
+	case OpPut:
+		err := db.mkdirAll(filepath.Dir(filename))
+		if err != nil {
+			return fmt.Errorf("unable to create dir: %w", err)
+		}
+		return db.replaceFile(filename, entry.Data)
+	case OpDelete:
+		err := os.Remove(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to delete file: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown op %q", entry.Op)
+}
+
+// walDir returns the directory holding the write-ahead log.
+func (db *BurrowDB) walDir() string {
+	return fmt.Sprintf("%s/%s", db.dir, walDirName)
+}
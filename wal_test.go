@@ -0,0 +1,71 @@
+package burrowdb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type walItem struct {
+	ID   int
+	Name string
+}
+
+// walEntries returns the number of entries in the write-ahead log of the db.
+func walEntries(t *testing.T, db *BurrowDB) int {
+	t.Helper()
+
+	entries, err := os.ReadDir(db.walDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestWAL(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir), WithWAL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(walItem{ID: 1, Name: "a"}, walItem{ID: 2, Name: "b"})
+	if err == nil {
+		err = db.Delete(walItem{}, 2)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := walEntries(t, db); n != 0 {
+		t.Fatalf("got %d entries left after the writes completed", n)
+	}
+
+	// Log a put and a delete as if the process crashed before making them.
+	_, err = db.logIntent(OpPut, db.entityPath("walItem", "3"), []byte(`{"ID":3,"Name":"c"}`))
+	if err == nil {
+		_, err = db.logIntent(OpDelete, db.entityPath("walItem", "1"), nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDB(WithDir(dir), WithWAL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item walItem
+	err = db.GetByID(&item, 3)
+	if err != nil || item.Name != "c" {
+		t.Fatalf("got %+v, %v, want the logged put replayed", item, err)
+	}
+
+	err = db.GetByID(&item, 1)
+	if !errors.Is(err, ErrNoSuchEntity) {
+		t.Fatalf("got %v, want the logged delete replayed", err)
+	}
+
+	if n := walEntries(t, db); n != 0 {
+		t.Fatalf("got %d entries left after replaying", n)
+	}
+}
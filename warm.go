@@ -0,0 +1,71 @@
+package burrowdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// WithWarmIndexes specifies that NewDB should call WarmIndexes for the types of
+// the passed values, so that their indexes are built before the first query
+// rather than that query finding them missing. Each value may be a struct or a
+// pointer to one.
+func WithWarmIndexes(vs ...any) newDBOption {
+	return func(db *BurrowDB) error {
+		for _, v := range vs {
+			_type := indirectType(reflect.TypeOf(v))
+			if _type.Kind() != reflect.Struct {
+				return fmt.Errorf("%w: %s is not a struct", ErrInvalidDstType, _type)
+			}
+			db.warmTypes = append(db.warmTypes, _type)
+		}
+		return nil
+	}
+}
+
+// WarmIndexes makes sure every index of the type of dst, given by its struct
+// tags and WithCompositeIndex, has been written, rebuilding them all from the
+// stored entities as RebuildIndexes does if any is missing, such as after an
+// index is added to a type which already has entities. Indexes which exist are
+// kept up to date by writes, so are left as they are, but their files are read
+// so that the first query doesn't wait for the disk.
+//
+// The dst may be a struct or a pointer to one and is only used for its type.
+func (db *BurrowDB) WarmIndexes(dst any) (err error) {
+	defer db.handleError("WarmIndexes", &err)
+
+	_type := indirectType(reflect.TypeOf(dst))
+	if _type.Kind() != reflect.Struct {
+		return ErrInvalidDstType
+	}
+
+	return db.warmIndexes(_type)
+}
+
+// warmIndexes warms the indexes of the passed struct type as WarmIndexes does.
+func (db *BurrowDB) warmIndexes(_type reflect.Type) error {
+	typeName := db.typeName(_type)
+	mu := db.typeLock(typeName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	specs := db.indexSpecs(_type)
+	for _, spec := range specs {
+		_, err := os.Stat(db.indexPath(typeName, spec.name))
+		if errors.Is(err, os.ErrNotExist) {
+			return db.rebuildIndexes(_type)
+		} else if err != nil {
+			return fmt.Errorf("unable to stat index %s: %w", spec.name, err)
+		}
+	}
+
+	for _, spec := range specs {
+		_, err := db.readIndex(typeName, spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
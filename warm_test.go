@@ -0,0 +1,82 @@
+package burrowdb
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWarmIndexes(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB(WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.PutAll(indexItem{ID: 1, Status: "open", Email: "a"}, indexItem{ID: 2, Status: "done", Email: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the indexes, as if the fields had been tagged after the entities
+	// were stored.
+	err = os.RemoveAll(filepath.Join(dir, "indexItem", indexDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []indexItem
+	err = db.GetByField(&items, "Status", "open")
+	if err != nil || len(items) != 0 {
+		t.Fatalf("got %v and %v from a missing index, want nothing", indexItemIDs(items), err)
+	}
+
+	err = db.WarmIndexes(&indexItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"Status", "Email"} {
+		_, err = os.Stat(filepath.Join(dir, "indexItem", indexDirName, field))
+		if err != nil {
+			t.Fatalf("got %v for the %s index after warming, want it written", err, field)
+		}
+	}
+
+	err = db.GetByField(&items, "Status", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{1}) {
+		t.Fatalf("got %v open, want [1]", got)
+	}
+
+	// NewDB warms the indexes of the types it is given.
+	err = os.RemoveAll(filepath.Join(dir, "indexItem", indexDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDB(WithDir(dir), WithWarmIndexes(indexItem{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.GetByField(&items, "Email", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := indexItemIDs(items); !slices.Equal(got, []int{2}) {
+		t.Fatalf("got %v with email b, want [2]", got)
+	}
+
+	if cfg := db.Config(); !slices.Equal(cfg.WarmIndexes, []string{"indexItem"}) {
+		t.Fatalf("got %v warmed in config, want [indexItem]", cfg.WarmIndexes)
+	}
+
+	_, err = NewDB(WithDir(dir), WithWarmIndexes(1))
+	if err == nil {
+		t.Fatal("got no error warming a non-struct type")
+	}
+}